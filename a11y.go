@@ -0,0 +1,74 @@
+package gore
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// In the accessibility mode, the outputs are labeled to be read by screen
+// readers, without colors and escape sequences, and the long lines are
+// wrapped at a11yLineWidth.
+const (
+	a11yLineWidth      = 80
+	a11yResultLabel    = "result: "
+	a11yErrorLabel     = "error: "
+	promptA11y         = "gore> "
+	promptA11yContinue = "continuation line> "
+)
+
+// a11yWriter labels the lines, removes the escape sequences and wraps the
+// long lines written to the underlying writer.
+type a11yWriter struct {
+	w      io.Writer
+	label  string
+	col    int
+	escape bool
+	rest   []byte // incomplete rune
+}
+
+func (w *a11yWriter) Write(p []byte) (int, error) {
+	var buf bytes.Buffer
+	b := append(w.rest, p...)
+	w.rest = nil
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && !utf8.FullRune(b) {
+			w.rest = append([]byte(nil), b...)
+			break
+		}
+		b = b[size:]
+
+		// skip the escape sequences (e.g. colors) of ESC [ ... final byte
+		if w.escape {
+			if r != '[' && r >= 0x40 && r <= 0x7e {
+				w.escape = false
+			}
+			continue
+		}
+		if r == 0x1b {
+			w.escape = true
+			continue
+		}
+
+		if r == '\n' {
+			buf.WriteRune(r)
+			w.col = 0
+			continue
+		}
+		if w.col == 0 {
+			buf.WriteString(w.label)
+			w.col = len(w.label)
+		} else if w.col >= a11yLineWidth {
+			buf.WriteString("\n" + strings.Repeat(" ", len(w.label)))
+			w.col = len(w.label)
+		}
+		buf.WriteRune(r)
+		w.col++
+	}
+	if _, err := w.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
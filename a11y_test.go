@@ -0,0 +1,63 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestA11yWriter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		label  string
+		writes []string
+		want   string
+	}{
+		{"label", "error: ", []string{"foo\n\nbar\n"}, "error: foo\n\nerror: bar\n"},
+		{"split", "error: ", []string{"fo", "o\nb", "ar\n"}, "error: foo\nerror: bar\n"},
+		{"escape", "", []string{"\x1b[34m\x1b[1m3\x1b[0m\n"}, "3\n"},
+		{"rune", "", []string{"\xe3\x81", "\x82\n"}, "あ\n"},
+		{
+			"wrap", "error: ", []string{strings.Repeat("x", 100) + "\n"},
+			"error: " + strings.Repeat("x", 73) + "\n       " + strings.Repeat("x", 27) + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		var sb strings.Builder
+		w := &a11yWriter{w: &sb, label: tc.label}
+		for _, s := range tc.writes {
+			n, err := w.Write([]byte(s))
+			require.NoError(t, err)
+			assert.Equal(t, len(s), n)
+		}
+		assert.Equal(t, tc.want, sb.String(), tc.name)
+	}
+}
+
+func TestSessionEval_A11y(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := New(A11y(true)).newSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`1 + 2`,
+		`x := "foo"`,
+		`y`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, "result: 3\nresult: \"foo\"\n", stdout.String())
+	assert.Equal(t, "error: undefined: y\n", stderr.String())
+}
+
+func TestContLiner_A11y(t *testing.T) {
+	cl := &contLiner{a11y: true}
+	assert.Equal(t, promptA11y, cl.promptString())
+	cl.buffer, cl.depth = "func f() {", 1
+	assert.Equal(t, promptA11yContinue, cl.promptString())
+}
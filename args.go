@@ -0,0 +1,81 @@
+package gore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func actionArgs(s *Session, arg string) error {
+	if arg == "" {
+		fmt.Fprintln(s.stdout, quoteArgs(s.args))
+		return nil
+	}
+
+	args, err := splitArgs(arg)
+	if err != nil {
+		return err
+	}
+	// a leading -- separates the arguments, so that :args -- clears them
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	s.args = args
+	return nil
+}
+
+// splitArgs splits the arguments like shells, by spaces except in quotes.
+// Backslashes escape the following characters except in single quotes.
+func splitArgs(in string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	var inArg bool
+	var quote rune
+	var escaped bool
+	for _, c := range in {
+		switch {
+		case escaped:
+			arg.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inArg = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// quoteArgs joins the arguments quoting the ones which need it.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
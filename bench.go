@@ -0,0 +1,132 @@
+package gore
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+)
+
+// The expression of :bench is run in the loop of a benchmark function by
+// testing.Benchmark, which is called at the end of main as the statements
+// before are the setup of the benchmark. The function is declared in a file
+// added to the session only while benchmarking, so that the imports of the
+// session are not changed.
+const (
+	benchFileName = "gore_bench.go"
+	benchFuncName = "__gore_bench"
+	benchTypeName = "__gore_B"
+)
+
+const benchSource = `package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+type ` + benchTypeName + ` = testing.B
+
+func ` + benchFuncName + `(f func(*testing.B)) {
+	r := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		f(b)
+	})
+	fmt.Printf("%s\t%s\n", r, r.MemString())
+}
+`
+
+// actionBench benchmarks the expression, and prints the number of the runs,
+// ns/op, B/op and allocs/op.
+func actionBench(s *Session, in string) error {
+	if in == "" {
+		return s.errorf("argument is required")
+	}
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return err
+	}
+	expandLastResult(expr, s.results.last)
+
+	s.storeCode()
+	defer s.restoreCode()
+	restore, err := s.addSessionFile(benchFileName, benchSource)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	stmt, loop := benchStmt(expr)
+	s.appendStatements(stmt)
+	s.addUsedResults()
+
+	// the value is assigned to _ not to be removed, unless the expression
+	// is a call of no value or multiple values
+	if call, ok := expr.(*ast.CallExpr); ok {
+		conf := *s.types
+		conf.Error = func(error) {}
+		info := types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+		_, _ = conf.Check("_tmp", s.fset, append(s.extraFiles, s.file), &info)
+		if sig, ok := info.Types[call.Fun].Type.(*types.Signature); ok && sig.Results().Len() != 1 {
+			loop.Body.List[0] = &ast.ExprStmt{X: expr}
+		}
+	}
+	s.doQuickFix()
+
+	if err := s.Run(); err != nil {
+		debugf("bench :: err = %s", err)
+		return s.errorf("could not run the benchmark")
+	}
+	return nil
+}
+
+// addSessionFile adds the file of the source to the files of the session
+// (e.g. declaring the benchmark function), and returns the function to
+// remove it.
+func (s *Session) addSessionFile(name, source string) (func(), error) {
+	path := filepath.Join(s.tempDir, name)
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseFile(s.fset, path, source, parser.Mode(0))
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	files, paths := s.extraFiles, s.extraFilePaths
+	s.extraFiles = append(files[:len(files):len(files)], f)
+	s.extraFilePaths = append(paths[:len(paths):len(paths)], path)
+	return func() {
+		s.extraFiles, s.extraFilePaths = files, paths
+		os.Remove(path)
+	}, nil
+}
+
+// benchStmt returns the statement benchmarking the expression, and the loop
+// running the expression:
+//
+//	__gore_bench(func(b *__gore_B) {
+//		for i := 0; i < b.N; i++ {
+//			_ = expr
+//		}
+//	})
+func benchStmt(expr ast.Expr) (ast.Stmt, *ast.ForStmt) {
+	b, i := ast.NewIdent("b"), ast.NewIdent("i")
+	loop := &ast.ForStmt{
+		Init: &ast.AssignStmt{Lhs: []ast.Expr{i}, Tok: token.DEFINE, Rhs: []ast.Expr{&ast.BasicLit{Kind: token.INT, Value: "0"}}},
+		Cond: &ast.BinaryExpr{X: ast.NewIdent("i"), Op: token.LSS, Y: &ast.SelectorExpr{X: ast.NewIdent("b"), Sel: ast.NewIdent("N")}},
+		Post: &ast.IncDecStmt{X: ast.NewIdent("i"), Tok: token.INC},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.AssignStmt{Lhs: []ast.Expr{ast.NewIdent("_")}, Tok: token.ASSIGN, Rhs: []ast.Expr{expr}},
+		}},
+	}
+	fn := &ast.FuncLit{
+		Type: &ast.FuncType{Params: &ast.FieldList{List: []*ast.Field{
+			{Names: []*ast.Ident{b}, Type: &ast.StarExpr{X: ast.NewIdent(benchTypeName)}},
+		}}},
+		Body: &ast.BlockStmt{List: []ast.Stmt{loop}},
+	}
+	return &ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent(benchFuncName), Args: []ast.Expr{fn}}}, loop
+}
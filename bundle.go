@@ -0,0 +1,426 @@
+package gore
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/modfile"
+)
+
+// A bundle is a zip archive of the session to restore it on another machine,
+// with the source, the module requirements, the files given by -context and
+// the metadata of the environment and the settings.
+const (
+	bundleExt        = ".gorebundle"
+	bundleMetaName   = "gore.json"
+	bundleSourceName = "main.go"
+	bundleGoModName  = "go.mod"
+	bundleGoSumName  = "go.sum"
+	bundleContextDir = "context/"
+)
+
+// bundleMeta is the metadata of a bundle.
+type bundleMeta struct {
+	Version    string            `json:"version"`
+	GoVersion  string            `json:"goVersion,omitempty"`
+	GOOS       string            `json:"goos"`
+	GOARCH     string            `json:"goarch"`
+	Created    time.Time         `json:"created"`
+	AutoImport bool              `json:"autoImport,omitempty"`
+	Imports    []string          `json:"imports,omitempty"`
+	Settings   map[string]string `json:"settings,omitempty"`
+	Env        []string          `json:"env,omitempty"`
+	Args       []string          `json:"args,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	WorkDir    string            `json:"workDir,omitempty"`
+}
+
+// The settings depending on the machine or the user are not restored.
+var bundleSkipSettings = map[string]bool{
+	"gocache": true, "lang": true, "goos": true, "goarch": true,
+	"goproxy": true, "gosumdb": true, "goprivate": true, "goflags": true,
+}
+
+// goVersion returns the version of the go command running the code.
+func (s *Session) goVersion() string {
+	cmd := exec.Command("go", "env", "GOVERSION")
+	cmd.Env = s.goEnviron()
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (s *Session) bundleMeta() *bundleMeta {
+	meta := &bundleMeta{
+		Version:    Version,
+		GoVersion:  s.goVersion(),
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		Created:    time.Now(),
+		AutoImport: s.autoImport,
+		Settings:   map[string]string{},
+		Args:       s.args,
+		Tags:       s.buildContext.BuildTags,
+		WorkDir:    s.workDir,
+	}
+	// the unused imports are not in the source but kept in the session
+	_, info := s.typeCheck()
+	for _, imp := range s.sessionImports(info) {
+		meta.Imports = append(meta.Imports, strings.Trim(imp.spec.Path.Value, `"`))
+	}
+	for _, st := range settings {
+		if !bundleSkipSettings[st.name] {
+			meta.Settings[st.name] = st.get(s)
+		}
+	}
+	for key, o := range s.env {
+		if o.unset {
+			meta.Env = append(meta.Env, "-u "+key)
+		} else {
+			meta.Env = append(meta.Env, key+"="+o.value)
+		}
+	}
+	return meta
+}
+
+// writeBundle writes the session to the bundle file.
+func (s *Session) writeBundle(filename string) error {
+	data, err := s.bundle()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
+
+// bundle returns the zip archive of the session.
+func (s *Session) bundle() ([]byte, error) {
+	source, err := s.userSource(false)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := json.MarshalIndent(s.bundleMeta(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name string, data []byte) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	if err := add(bundleMetaName, append(meta, '\n')); err != nil {
+		return nil, err
+	}
+	if err := add(bundleSourceName, []byte(source)); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{bundleGoModName, bundleGoSumName} {
+		data, err := os.ReadFile(filepath.Join(s.tempDir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if err := add(name, data); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range s.extraFilePaths {
+		// the declarations of the files of :file are in the source, and
+		// the helpers of the deterministic mode are restored by the setting
+		if s.isDeclFile(file) || filepath.Base(file) == deterministicFileName {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(bundleContextDir+filepath.Base(file), data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// openBundle restores the session from the bundle file. The parts which
+// cannot be restored on this machine, e.g. a package not available, are
+// reported as warnings.
+func (s *Session) openBundle(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return s.restoreBundle(data, true)
+}
+
+// restoreBundle restores the session from the zip archive of the bundle,
+// confirming the changes of the environment if confirm is set.
+func (s *Session) restoreBundle(data []byte, confirm bool) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{}
+	var contextFiles []string
+	for _, f := range zr.File {
+		data, err := readZipFile(f)
+		if err != nil {
+			return err
+		}
+		files[f.Name] = data
+		if strings.HasPrefix(f.Name, bundleContextDir) {
+			contextFiles = append(contextFiles, f.Name)
+		}
+	}
+
+	var meta bundleMeta
+	if err := json.Unmarshal(files[bundleMetaName], &meta); err != nil {
+		return s.errorf("invalid bundle: %s", err)
+	}
+	source, ok := files[bundleSourceName]
+	if !ok {
+		return s.errorf("invalid bundle: %s", "no "+bundleSourceName)
+	}
+	f, err := parser.ParseFile(s.fset, bundleSourceName, source, parser.Mode(0))
+	if err != nil {
+		return s.errorf("invalid bundle: %s", err)
+	}
+
+	if goVersion := s.goVersion(); meta.GoVersion != goVersion || meta.GOOS != runtime.GOOS || meta.GOARCH != runtime.GOARCH {
+		fmt.Fprintf(s.stderr, s.tr("warning: the bundle was written with %s on %s/%s")+"\n", meta.GoVersion, meta.GOOS, meta.GOARCH)
+	}
+	warn := func(part string, err error) {
+		fmt.Fprintf(s.stderr, s.tr("warning: could not restore %s: %s")+"\n", part, err)
+	}
+
+	// restore the environment first, as the packages are loaded with it
+	s.autoImport = s.autoImport || meta.AutoImport
+	for _, st := range settings {
+		if value, ok := meta.Settings[st.name]; ok && !bundleSkipSettings[st.name] {
+			if err := st.set(s, value); err != nil {
+				warn(st.name, err)
+			}
+		}
+	}
+	var goMod *modfile.File
+	if data, ok := files[bundleGoModName]; ok {
+		if goMod, err = modfile.Parse(bundleGoModName, data, nil); err != nil {
+			warn(bundleGoModName, err)
+		}
+	}
+	environ := !confirm || s.confirmBundleEnv(&meta, goMod)
+	if environ {
+		for _, kv := range meta.Env {
+			if err := actionEnv(s, kv); err != nil {
+				warn("env", err)
+			}
+		}
+		s.args = meta.Args
+		if meta.WorkDir != "" {
+			if err := s.setWorkDir(meta.WorkDir); err != nil {
+				warn("workdir", err)
+			}
+		}
+	}
+	s.buildContext.BuildTags = meta.Tags
+	if goMod != nil {
+		if err := s.mergeGoMod(goMod, files[bundleGoSumName], environ); err != nil {
+			warn(bundleGoModName, err)
+		}
+	}
+	for _, name := range contextFiles {
+		if err := s.importPackages(files[name]); err != nil {
+			warn(name, err)
+		} else if err := s.importFile(files[name]); err != nil {
+			warn(name, err)
+		}
+	}
+
+	imports := meta.Imports
+	for _, imp := range f.Imports {
+		if imp.Name != nil && imp.Name.Name != "_" {
+			imports = append(imports, imp.Name.Name+" "+imp.Path.Value)
+			continue
+		}
+		imports = append(imports, strings.Trim(imp.Path.Value, `"`))
+	}
+	for _, path := range imports {
+		if err := actionImport(s, path); err != nil {
+			warn(path, err)
+		}
+	}
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT {
+				continue
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.Name == "main" {
+				s.mainBody.List = append(s.mainBody.List, decl.Body.List...)
+				continue
+			}
+		}
+		s.file.Decls = append(s.file.Decls, decl)
+	}
+	s.doQuickFix()
+
+	return nil
+}
+
+// confirmBundleEnv shows the changes of the environment by the bundle, i.e.
+// the environment variables, the arguments, the working directory and the
+// module replacements, which may make the code run differently from what the
+// source reads. They are confirmed if the session can ask, returning whether
+// to apply them.
+func (s *Session) confirmBundleEnv(meta *bundleMeta, goMod *modfile.File) bool {
+	var changes []string
+	for _, kv := range meta.Env {
+		changes = append(changes, ":env "+kv)
+	}
+	if len(meta.Args) > 0 {
+		changes = append(changes, ":args "+quoteArgs(meta.Args))
+	}
+	if meta.WorkDir != "" {
+		changes = append(changes, ":cd "+meta.WorkDir)
+	}
+	if goMod != nil {
+		// the replacements the session already has are not changes
+		replaced := map[string]bool{}
+		if data, err := os.ReadFile(filepath.Join(s.tempDir, bundleGoModName)); err == nil {
+			if mod, err := modfile.Parse(bundleGoModName, data, nil); err == nil {
+				for _, r := range mod.Replace {
+					replaced[r.Old.String()+" => "+r.New.String()] = true
+				}
+			}
+		}
+		for _, r := range goMod.Replace {
+			if replace := r.Old.String() + " => " + r.New.String(); !replaced[replace] {
+				changes = append(changes, "replace "+replace)
+			}
+		}
+	}
+	if len(changes) == 0 {
+		return true
+	}
+	fmt.Fprintln(s.stderr, s.tr("the bundle changes the environment:"))
+	for _, change := range changes {
+		fmt.Fprintf(s.stderr, "    %s\n", change)
+	}
+	return s.confirm == nil || s.confirm(s.tr("apply the changes?"))
+}
+
+// maxBundleFileSize is the limit of the size of a file in a bundle, not to
+// exhaust the memory by a crafted archive.
+const maxBundleFileSize = 64 << 20
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxBundleFileSize {
+		return nil, fmt.Errorf("%s: file too large: %d bytes", f.Name, f.UncompressedSize64)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	// the size in the header may be forged
+	data, err := io.ReadAll(io.LimitReader(rc, maxBundleFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBundleFileSize {
+		return nil, fmt.Errorf("%s: file too large", f.Name)
+	}
+	return data, nil
+}
+
+// mergeGoMod adds the requirements of the module file of a bundle to the
+// session module. The replacements are added if replace is set and the
+// directories exist.
+func (s *Session) mergeGoMod(bundle *modfile.File, goSum []byte, replace bool) error {
+	goModPath := filepath.Join(s.tempDir, bundleGoModName)
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return err
+	}
+	mod, err := modfile.Parse(goModPath, data, nil)
+	if err != nil {
+		return err
+	}
+
+	required := map[string]bool{}
+	for _, r := range mod.Require {
+		required[r.Mod.Path] = true
+	}
+	for _, r := range bundle.Require {
+		if !required[r.Mod.Path] {
+			if err := mod.AddRequire(r.Mod.Path, r.Mod.Version); err != nil {
+				return err
+			}
+		}
+	}
+	for _, r := range bundle.Replace {
+		if !replace {
+			break
+		}
+		if r.New.Version == "" {
+			if fi, err := os.Stat(r.New.Path); err != nil || !fi.IsDir() {
+				continue
+			}
+		}
+		if err := mod.AddReplace(r.Old.Path, r.Old.Version, r.New.Path, r.New.Version); err != nil {
+			return err
+		}
+	}
+	if data, err = mod.Format(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(goModPath, data, 0o644); err != nil {
+		return err
+	}
+
+	if goSum == nil {
+		return nil
+	}
+	goSumPath := filepath.Join(s.tempDir, bundleGoSumName)
+	sum, err := os.ReadFile(goSumPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lines := map[string]bool{}
+	for _, line := range strings.Split(string(sum), "\n") {
+		lines[line] = true
+	}
+	for _, line := range strings.Split(string(goSum), "\n") {
+		if !lines[line] {
+			sum = append(sum, line+"\n"...)
+			lines[line] = true
+		}
+	}
+	return os.WriteFile(goSumPath, sum, 0o644)
+}
@@ -0,0 +1,132 @@
+package gore
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_Bundle(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	bundle := filepath.Join(t.TempDir(), "session"+bundleExt)
+	codes := []string{
+		`:import strings`,
+		`:set floatfmt %.2f`,
+		`:env GORE_TEST_BUNDLE=yes`,
+		`:args -v`,
+		`:tags gore_test`,
+		`func double(n int) int { return n * 2 }`,
+		`x := double(21)`,
+		`:write --bundle ` + bundle,
+	}
+	for _, code := range codes {
+		_, err = s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	stdout.Reset()
+	restored, err := New(Open(bundle)).newSession(&stdout, &stderr)
+	t.Cleanup(func() { restored.Clear() })
+	require.NoError(t, err)
+
+	codes = []string{
+		`:import os`,
+		`x`,
+		`strings.Repeat("a", 2)`,
+		`1.0 / 3`,
+		`os.Getenv("GORE_TEST_BUNDLE")`,
+		`os.Args[1:]`,
+		`:tags`,
+	}
+	for _, code := range codes {
+		_, _ = restored.Eval(code)
+	}
+
+	assert.Equal(t, `42
+"aa"
+0.33
+"yes"
+[]string{"-v"}
+gore_test
+`, stdout.String())
+	assert.Equal(t, `the bundle changes the environment:
+    :env GORE_TEST_BUNDLE=yes
+    :args -v
+`, stderr.String())
+
+	// the changes of the environment are not applied if declined
+	stdout.Reset()
+	stderr.Reset()
+	g := New(Open(bundle))
+	var prompts []string
+	g.confirm = func(prompt string) bool {
+		prompts = append(prompts, prompt)
+		return false
+	}
+	declined, err := g.newSession(&stdout, &stderr)
+	t.Cleanup(func() { declined.Clear() })
+	require.NoError(t, err)
+	for _, code := range []string{`:import os`, `x`, `os.Getenv("GORE_TEST_BUNDLE")`, `len(os.Args)`} {
+		_, _ = declined.Eval(code)
+	}
+	assert.Equal(t, `42
+""
+1
+`, stdout.String())
+	assert.Equal(t, []string{"apply the changes?"}, prompts)
+}
+
+func TestAction_SaveSession(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.store, _ = NewStore("memory")
+
+	codes := []string{
+		`:import strings`,
+		`func double(n int) int { return n * 2 }`,
+		`x := double(21)`,
+		`:save-session work`,
+		`:clear`,
+		`:restore-session work`,
+		`strings.Repeat("a", x/21)`,
+		`:restore-session nothing`,
+		`:save-session ../work`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `42
+"aa"
+`, stdout.String())
+	assert.Equal(t, `restore-session: no saved session: nothing
+save-session: invalid session name: "../work"
+`, stderr.String())
+}
+
+func TestSession_restoreBundle_tooLarge(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	_, err = zw.CreateRaw(&zip.FileHeader{Name: bundleSourceName, UncompressedSize64: maxBundleFileSize + 1})
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	err = s.restoreBundle(buf.Bytes(), true)
+	assert.EqualError(t, err, "main.go: file too large: 67108865 bytes")
+}
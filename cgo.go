@@ -0,0 +1,131 @@
+package gore
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// The C code of cgo is written as the comment (the preamble) before
+// import "C". The import is kept as a separate declaration in the session,
+// and the preamble is inserted before it on writing the source.
+const cgoImport = `import "C"`
+
+func actionCgo(s *Session, arg string) error {
+	if arg == "" {
+		if s.cgoPreamble != "" {
+			fmt.Fprintln(s.stdout, s.cgoPreamble)
+		}
+		return nil
+	}
+
+	if arg == "--" {
+		s.cgoPreamble = ""
+		s.removeCgoImport()
+		return nil
+	}
+
+	preamble, added := s.cgoPreamble, s.addCgoImport()
+	if s.cgoPreamble == "" {
+		s.cgoPreamble = arg
+	} else {
+		s.cgoPreamble += "\n" + arg
+	}
+	if err := s.checkBuild(); err != nil {
+		s.cgoPreamble = preamble
+		if added {
+			s.removeCgoImport()
+		}
+		return err
+	}
+	return nil
+}
+
+// cgoImportDecl returns the index of the declaration of import "C".
+func (s *Session) cgoImportDecl() int {
+	for i, decl := range s.file.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT && len(decl.Specs) == 1 {
+			if spec := decl.Specs[0].(*ast.ImportSpec); spec.Path.Value == strconv.Quote("C") {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// addCgoImport adds import "C" to the session, and reports whether added.
+func (s *Session) addCgoImport() bool {
+	if s.cgoImportDecl() >= 0 {
+		return false
+	}
+	spec := &ast.ImportSpec{Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("C")}}
+	decl := &ast.GenDecl{Tok: token.IMPORT, Specs: []ast.Spec{spec}}
+	s.file.Decls = append([]ast.Decl{decl}, s.file.Decls...)
+	s.file.Imports = append(s.file.Imports, spec)
+	return true
+}
+
+func (s *Session) removeCgoImport() {
+	i := s.cgoImportDecl()
+	if i < 0 {
+		return
+	}
+	spec := s.file.Decls[i].(*ast.GenDecl).Specs[0]
+	s.file.Decls = append(s.file.Decls[:i], s.file.Decls[i+1:]...)
+	for j, imp := range s.file.Imports {
+		if imp == spec {
+			s.file.Imports = append(s.file.Imports[:j], s.file.Imports[j+1:]...)
+			break
+		}
+	}
+}
+
+// cgoComment returns the preamble as the comment before import "C".
+func (s *Session) cgoComment() string {
+	if s.cgoPreamble == "" {
+		return ""
+	}
+	var sb strings.Builder
+	for _, line := range strings.Split(s.cgoPreamble, "\n") {
+		sb.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+	}
+	return sb.String()
+}
+
+// withCgoPreamble inserts the preamble before import "C" of the source.
+func (s *Session) withCgoPreamble(src []byte) []byte {
+	if s.cgoPreamble == "" {
+		return src
+	}
+	i := bytes.Index(src, []byte("\n"+cgoImport+"\n"))
+	if i < 0 {
+		return src
+	}
+	i++
+	return append(append(src[:i:i], s.cgoComment()...), src[i:]...)
+}
+
+// checkBuild builds the session without running, to check the C code.
+func (s *Session) checkBuild() error {
+	if err := s.writeSource(); err != nil {
+		return err
+	}
+	args := append(append([]string{"build"}, s.buildFlags()...), "-o", os.DevNull)
+	args = append(args, s.runFiles()...)
+	cmd := exec.Command("go", args...)
+	cmd.Env = s.goEnviron()
+	cmd.Dir = s.tempDir
+	ef := newErrFilter(s.stderr)
+	defer ef.Close()
+	cmd.Stderr = ef
+	if err := cmd.Run(); err != nil {
+		debugf("%s", err)
+		return s.errorf("could not build the C code")
+	}
+	return nil
+}
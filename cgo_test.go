@@ -0,0 +1,57 @@
+package gore
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Cgo(t *testing.T) {
+	if out, err := exec.Command("go", "env", "CGO_ENABLED").Output(); err != nil || strings.TrimSpace(string(out)) != "1" {
+		t.Skip("cgo is not enabled")
+	}
+
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:cgo #include <stdlib.h>`,
+		`:cgo static int add(int a, int b) {`,
+		":cgo static int add(int a, int b) {\n  return a + b;\n}",
+		`:cgo`,
+		`x := int(C.add(3, 4))`,
+		`:cgo int broken() { return undeclared; }`,
+		`C.abs(-2)`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `#include <stdlib.h>
+static int add(int a, int b) {
+  return a + b;
+}
+7
+2
+`, stdout.String())
+	assert.Contains(t, stderr.String(), "cgo: could not build the C code\n")
+
+	source, err := s.userSource(false)
+	require.NoError(t, err)
+	assert.Contains(t, source, `// #include <stdlib.h>
+// static int add(int a, int b) {
+//   return a + b;
+// }
+import "C"
+`)
+
+	stdout.Reset()
+	_, _ = s.Eval(`:cgo --`)
+	_, _ = s.Eval(`:cgo`)
+	assert.Equal(t, "", stdout.String())
+}
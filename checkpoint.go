@@ -0,0 +1,174 @@
+package gore
+
+import (
+	"fmt"
+	"go/parser"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Checkpoints snapshot the code of the session (the statements, the
+// declarations and the imports) by name, to roll back to after trying
+// something. The checkpoints are kept as a stack, and rolling back to one
+// drops the ones taken after it.
+
+// checkpoint is a snapshot of the code of the session.
+type checkpoint struct {
+	name         string
+	source       string            // the source of the main file
+	declSources  map[string]string // the sources of the files of :file by the paths
+	declFile     string            // the path of the file of :file, or "" for the main file
+	stmts        int
+	results      []result
+	resultNumber int
+	lastResult   string
+	marks        map[string]int
+	importNames  map[string]string
+}
+
+// takeCheckpoint returns the snapshot of the code of the session.
+func (s *Session) takeCheckpoint(name string) (*checkpoint, error) {
+	source, err := s.source(false)
+	if err != nil {
+		return nil, err
+	}
+	cp := &checkpoint{
+		name:         name,
+		source:       source,
+		declSources:  make(map[string]string),
+		stmts:        len(s.mainBody.List),
+		results:      append([]result(nil), s.results.pending...),
+		resultNumber: s.results.next,
+		lastResult:   s.results.last,
+		marks:        make(map[string]int, len(s.marks)),
+		importNames:  make(map[string]string, len(s.imports.names)),
+	}
+	if err := s.writeDeclFiles(); err != nil {
+		return nil, err
+	}
+	for _, path := range s.declFilePaths() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		cp.declSources[path] = string(data)
+	}
+	if s.declFile != nil {
+		cp.declFile = s.fset.File(s.declFile.Pos()).Name()
+	}
+	for name, n := range s.marks {
+		cp.marks[name] = n
+	}
+	for path, name := range s.imports.names {
+		cp.importNames[path] = name
+	}
+	return cp, nil
+}
+
+// rollback restores the code of the session from the checkpoint. The files
+// of :file added after the checkpoint are left without the declarations.
+func (s *Session) rollback(cp *checkpoint) error {
+	file, err := parser.ParseFile(s.fset, "gore_session.go", cp.source, parser.Mode(0))
+	if err != nil {
+		return err
+	}
+	s.file = file
+	s.mainBody = s.mainFunc().Body
+
+	s.declFile = nil
+	for i, path := range s.extraFilePaths {
+		if !s.isDeclFile(path) {
+			continue
+		}
+		source, ok := cp.declSources[path]
+		if !ok {
+			source = "package main\n"
+		}
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			return err
+		}
+		f, err := parser.ParseFile(s.fset, path, source, parser.Mode(0))
+		if err != nil {
+			return err
+		}
+		s.extraFiles[i] = f
+		if path == cp.declFile {
+			s.declFile = f
+		}
+	}
+
+	s.results.pending = append([]result(nil), cp.results...)
+	s.results.next, s.results.last = cp.resultNumber, cp.lastResult
+	s.marks = make(map[string]int, len(cp.marks))
+	for name, n := range cp.marks {
+		s.marks[name] = n
+	}
+	s.imports.names = make(map[string]string, len(cp.importNames))
+	for path, name := range cp.importNames {
+		s.imports.names[path] = name
+	}
+	s.storeCode()
+	return nil
+}
+
+// checkpointIndex returns the index of the checkpoint of the name, or -1.
+func (s *Session) checkpointIndex(name string) int {
+	for i, cp := range s.checkpoints {
+		if cp.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func actionCheckpoint(s *Session, name string) error {
+	if name == "" {
+		w := tabwriter.NewWriter(s.stdout, 0, 8, 4, ' ', 0)
+		for _, cp := range s.checkpoints {
+			fmt.Fprintf(w, "    %s\t%d statements\n", cp.name, cp.stmts)
+		}
+		return w.Flush()
+	}
+
+	if strings.ContainsAny(name, " \t") {
+		return s.errorf("invalid checkpoint name: %s", name)
+	}
+	cp, err := s.takeCheckpoint(name)
+	if err != nil {
+		return err
+	}
+	// taking the checkpoint of the same name again moves it to the top
+	if i := s.checkpointIndex(name); i >= 0 {
+		s.checkpoints = append(s.checkpoints[:i:i], s.checkpoints[i+1:]...)
+	}
+	s.checkpoints = append(s.checkpoints, cp)
+	return nil
+}
+
+func actionRollback(s *Session, name string) error {
+	if len(s.checkpoints) == 0 {
+		return s.errorf("no checkpoint to roll back to")
+	}
+	i := len(s.checkpoints) - 1
+	if name != "" {
+		if i = s.checkpointIndex(name); i < 0 {
+			return s.errorf("no such checkpoint: %s", name)
+		}
+	}
+	if err := s.rollback(s.checkpoints[i]); err != nil {
+		return err
+	}
+	s.checkpoints = s.checkpoints[:i+1]
+	return nil
+}
+
+func completeCheckpoint(s *Session, prefix string) []string {
+	var result []string
+	for i := len(s.checkpoints) - 1; i >= 0; i-- {
+		if name := s.checkpoints[i].name; strings.HasPrefix(name, prefix) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
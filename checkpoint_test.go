@@ -0,0 +1,59 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Checkpoint(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:rollback`,
+		`x := 1`,
+		`:checkpoint before-refactor`,
+		`:import strings`,
+		`func double(n int) int { return n * 2 }`,
+		`y := double(x)`,
+		`:checkpoint doubled`,
+		`z := strings.Repeat("a", y)`,
+		`:checkpoint`,
+		`:rollback doubled`,
+		`z`,
+		`y`,
+		`:rollback before-refactor`,
+		`y`,
+		`double(x)`,
+		`strings.ToUpper("a")`,
+		`x`,
+		`:checkpoint`,
+		`:rollback doubled`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `1
+2
+"aa"
+    before-refactor    1 statements
+    doubled            2 statements
+2
+1
+    before-refactor    1 statements
+`, stdout.String())
+	assert.Equal(t, `rollback: no checkpoint to roll back to
+undefined: z
+undefined: y
+undefined: double
+undefined: strings
+rollback: no such checkpoint: doubled
+`, stderr.String())
+}
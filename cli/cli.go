@@ -1,10 +1,16 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"go/build"
 	"io"
+	"os"
 	"runtime"
+	"runtime/debug"
+	"strings"
 
 	"github.com/x-motemen/gore"
 )
@@ -20,22 +26,226 @@ type cli struct {
 	outWriter, errWriter io.Writer
 }
 
+// subcommand is run by gore <name> [options] [arguments].
+type subcommand struct {
+	name  string
+	args  string
+	usage string
+	// session tells whether the subcommand takes the options of the session
+	session bool
+	// flags defines the flags of the subcommand, if any
+	flags func(*flag.FlagSet, *options)
+	run   func(*cli, *options, []string) int
+	// words are completed as the arguments
+	words []string
+}
+
+var subcommands []subcommand
+
+func init() {
+	subcommands = []subcommand{
+		{
+			name:    "eval",
+			args:    "[<code>...]",
+			usage:   "evaluate the code of the arguments, or the standard input, and exit",
+			session: true,
+			run:     runEval,
+		},
+		{
+			name:    "run",
+			args:    "<file>",
+			usage:   "evaluate the inputs in the file (- for the standard input) and exit",
+			session: true,
+			run:     runScript,
+		},
+		{
+			name:    "serve",
+			usage:   "speak JSON-RPC over stdio for editor integration, or serve a web playground",
+			session: true,
+			flags: func(fs *flag.FlagSet, opts *options) {
+				fs.StringVar(&opts.httpAddr, "http", "", "serve a web playground on the address (e.g. :8080 on 127.0.0.1)")
+			},
+			run: runServe,
+		},
+		{
+			name:    "kernel",
+			args:    "<connection file>",
+			usage:   "run as a Jupyter kernel",
+			session: true,
+			run:     runKernel,
+		},
+		{
+			name:  "doctor",
+			usage: "check the environment gore depends on",
+			run:   runDoctor,
+		},
+		{
+			name:  "self-update",
+			usage: "replace gore with the latest release",
+			flags: func(fs *flag.FlagSet, opts *options) {
+				fs.BoolVar(&opts.checkOnly, "check", false, "only check the latest release")
+			},
+			run: runSelfUpdate,
+		},
+		{
+			name:  "version",
+			usage: "print gore version",
+			run:   runVersion,
+		},
+		{
+			name:  "completions",
+			args:  strings.Join(completionShells, "|"),
+			usage: "print the completion script for the shell",
+			run:   runCompletions,
+			words: completionShells,
+		},
+	}
+}
+
 func (c *cli) run(args []string) int {
-	g, err := c.parseArgs(args)
+	if len(args) > 0 {
+		for _, sub := range subcommands {
+			if args[0] == sub.name {
+				return c.runSubcommand(sub, args[1:])
+			}
+		}
+	}
+
+	var opts options
+	g, err := c.parseArgs(args, &opts)
 	if err != nil {
 		if err != flag.ErrHelp {
 			return exitCodeErr
 		}
 		return exitCodeOK
 	}
-	if err := g.Run(); err != nil {
+	if len(opts.evals) > 0 {
+		return c.exit(g.Script(strings.NewReader(strings.Join(opts.evals, "\n"))))
+	}
+	return c.exit(g.Run())
+}
+
+func (c *cli) runSubcommand(sub subcommand, args []string) int {
+	var opts options
+	fs := c.subcommandFlagSet(sub, &opts)
+	if err := fs.Parse(args); err != nil {
+		if err != flag.ErrHelp {
+			return exitCodeErr
+		}
+		return exitCodeOK
+	}
+	return sub.run(c, &opts, fs.Args())
+}
+
+func (c *cli) exit(err error) int {
+	if err != nil {
 		fmt.Fprintf(c.errWriter, "gore: %s\n", err)
+		var exitErr *gore.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.Code
+		}
 		return exitCodeErr
 	}
 	return exitCodeOK
 }
 
-func (c *cli) parseArgs(args []string) (*gore.Gore, error) {
+// options are the values of the flags.
+type options struct {
+	autoImport    bool
+	extFiles      string
+	packageName   string
+	bundle        string
+	maxOutput     string
+	race          bool
+	deterministic bool
+	timeout       string
+	sandbox       string
+	sandboxImage  string
+	tempDir       string
+	noColor       bool
+	debug         bool
+	storeKind     string
+	a11y          bool
+	gopath        string
+	goroot        string
+	goos          string
+	goarch        string
+	server        bool
+	plain         bool
+	kernel        string
+	httpAddr      string
+	checkUpdate   bool
+	checkOnly     bool
+	showVersion   bool
+	evals         []string
+	envErr        error
+}
+
+// sessionFlags defines the flags shared by the commands running a session.
+// The defaults are taken from the environment variables GORE_<FLAG> (e.g.
+// GORE_AUTOIMPORT=1 for -autoimport, GORE_MAX_OUTPUT=64KB for -max-output),
+// and an invalid value is kept in envErr to be reported on parsing.
+func (opts *options) sessionFlags(fs *flag.FlagSet) {
+	defined := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) { defined[f.Name] = true })
+
+	fs.BoolVar(&opts.autoImport, "autoimport", false, "formats and adjusts imports automatically")
+	fs.StringVar(&opts.extFiles, "context", "", "import packages, functions, variables and constants from external golang source files")
+	fs.StringVar(&opts.packageName, "pkg", "", "the package where the session will be run inside")
+	fs.StringVar(&opts.bundle, "open", "", "restore the session from the bundle written by :write --bundle")
+	fs.StringVar(&opts.maxOutput, "max-output", "", "truncate the outputs of each run over the size (e.g. 64KB)")
+	fs.BoolVar(&opts.race, "race", false, "run the code with the race detector")
+	fs.BoolVar(&opts.deterministic, "deterministic", false, "run the code reproducibly, with now() of a fixed time and math/rand seeded")
+	fs.StringVar(&opts.timeout, "timeout", "", "stop the program running over the duration (e.g. 10s)")
+	fs.StringVar(&opts.sandbox, "sandbox", "", "run the programs in the containers (docker), not to touch the files and the network of the host")
+	fs.StringVar(&opts.sandboxImage, "sandbox-image", gore.DefaultSandboxImage, "the image of the containers of -sandbox")
+	fs.StringVar(&opts.tempDir, "tempdir", "", "create the temporary directories of the sessions under the directory")
+	fs.BoolVar(&opts.noColor, "no-color", os.Getenv("NO_COLOR") != "", "print without colors (default if NO_COLOR is set)")
+	fs.BoolVar(&opts.debug, "debug", false, "print the debug messages")
+	fs.BoolVar(&opts.a11y, "a11y", false, "label the outputs for screen readers, without colors and long lines")
+	fs.StringVar(&opts.gopath, "gopath", "", "GOPATH of the session, for example a project-specific one")
+	fs.StringVar(&opts.goroot, "goroot", "", "GOROOT of the session")
+	fs.StringVar(&opts.goos, "goos", "", "GOOS to type check and complete the code for")
+	fs.StringVar(&opts.goarch, "goarch", "", "GOARCH to type check and complete the code for")
+	fs.StringVar(&opts.storeKind, "store", "home", "where to save the history, the config and the sessions (home: $GORE_HOME or ~/.gore, xdg: XDG base directories, memory: nowhere)")
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if defined[f.Name] || opts.envErr != nil {
+			return
+		}
+		name := "GORE_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := os.LookupEnv(name); ok {
+			if err := f.Value.Set(value); err != nil {
+				opts.envErr = fmt.Errorf("invalid value %q for %s: %s", value, name, err)
+			}
+		}
+	})
+}
+
+// buildContext returns the build context overridden by the flags,
+// or nil to use the default one.
+func (opts *options) buildContext() *build.Context {
+	if opts.gopath == "" && opts.goroot == "" && opts.goos == "" && opts.goarch == "" {
+		return nil
+	}
+	ctxt := build.Default
+	if opts.gopath != "" {
+		ctxt.GOPATH = opts.gopath
+	}
+	if opts.goroot != "" {
+		ctxt.GOROOT = opts.goroot
+	}
+	if opts.goos != "" {
+		ctxt.GOOS = opts.goos
+	}
+	if opts.goarch != "" {
+		ctxt.GOARCH = opts.goarch
+	}
+	return &ctxt
+}
+
+// flagSet defines the flags of gore to set the options.
+func (c *cli) flagSet(opts *options) *flag.FlagSet {
 	fs := flag.NewFlagSet("gore", flag.ContinueOnError)
 	fs.SetOutput(c.errWriter)
 	fs.Usage = func() {
@@ -46,40 +256,248 @@ func (c *cli) parseArgs(args []string) (*gore.Gore, error) {
 Version: %s (rev: %s/%s)
 
 Synopsis:
-    %% gore
+    %% gore [options]
+    %% gore [options] -e <code> [-e <code>...]
+    %% gore <command> [options] [arguments]
 
-Options:
-`, gore.Version, revision, runtime.Version())
+Commands:
+`, gore.Version, buildRevision(), runtime.Version())
+		for _, sub := range subcommands {
+			fmt.Fprintf(c.outWriter, "    %-12s %s\n", sub.name, sub.usage)
+		}
+		fmt.Fprintf(c.outWriter, "\nOptions:\n")
 		fs.PrintDefaults()
+		fmt.Fprintf(c.outWriter, "\n%s\n", envUsage)
 	}
 
-	var autoImport bool
-	fs.BoolVar(&autoImport, "autoimport", false, "formats and adjusts imports automatically")
-
-	var extFiles string
-	fs.StringVar(&extFiles, "context", "", "import packages, functions, variables and constants from external golang source files")
+	opts.sessionFlags(fs)
+	fs.Func("e", "evaluate the code and exit, repeated for more inputs (same as gore eval)", func(code string) error {
+		opts.evals = append(opts.evals, code)
+		return nil
+	})
+	fs.BoolVar(&opts.server, "server", false, "speak JSON-RPC over stdio for editor integration (same as gore serve)")
+	fs.BoolVar(&opts.plain, "plain", false, "read the chunks ending by a blank line or ;; without the line editor, prefixing the outputs")
+	fs.StringVar(&opts.kernel, "kernel", "", "run as a Jupyter kernel with the connection file (same as gore kernel)")
+	fs.StringVar(&opts.httpAddr, "http", "", "serve a web playground on the address (same as gore serve -http)")
+	fs.BoolVar(&opts.checkUpdate, "checkupdate", false, "notify a newer release of gore, checked at most once a day")
+	fs.BoolVar(&opts.showVersion, "version", false, "print gore version")
+	return fs
+}
 
-	var packageName string
-	fs.StringVar(&packageName, "pkg", "", "the package where the session will be run inside")
+// subcommandFlagSet defines the flags of the subcommand to set the options.
+func (c *cli) subcommandFlagSet(sub subcommand, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet("gore "+sub.name, flag.ContinueOnError)
+	fs.SetOutput(c.errWriter)
+	fs.Usage = func() {
+		fs.SetOutput(c.outWriter)
+		defer fs.SetOutput(c.errWriter)
+		synopsis := "gore " + sub.name
+		if sub.session || sub.flags != nil {
+			synopsis += " [options]"
+		}
+		if sub.args != "" {
+			synopsis += " " + sub.args
+		}
+		fmt.Fprintf(c.outWriter, "Usage:\n    %% %s\n\n%s\n", synopsis, sub.usage)
+		if sub.session || sub.flags != nil {
+			fmt.Fprintf(c.outWriter, "\nOptions:\n")
+			fs.PrintDefaults()
+		}
+		if sub.session {
+			fmt.Fprintf(c.outWriter, "\n%s\n", envUsage)
+		}
+	}
 
-	var showVersion bool
-	fs.BoolVar(&showVersion, "version", false, "print gore version")
+	if sub.session {
+		opts.sessionFlags(fs)
+	}
+	if sub.flags != nil {
+		sub.flags(fs, opts)
+	}
+	return fs
+}
 
+func (c *cli) parseArgs(args []string, opts *options) (*gore.Gore, error) {
+	fs := c.flagSet(opts)
 	err := fs.Parse(args)
 	if err != nil {
 		return nil, err
 	}
 
-	if showVersion {
-		fmt.Fprintf(c.outWriter, "gore %s (rev: %s/%s)\n", gore.Version, revision, runtime.Version())
+	if opts.showVersion {
+		c.printVersion()
 		return nil, flag.ErrHelp
 	}
 
+	return c.newGore(opts)
+}
+
+// newGore creates a Gore configured by the options.
+func (c *cli) newGore(opts *options) (*gore.Gore, error) {
+	if opts.envErr != nil {
+		fmt.Fprintf(c.errWriter, "gore: %s\n", opts.envErr)
+		return nil, opts.envErr
+	}
+
+	var store gore.Store
+	if opts.storeKind != "home" && opts.storeKind != "" {
+		// the default store is created on running, not to fail without home
+		var err error
+		if store, err = gore.NewStore(opts.storeKind); err != nil {
+			fmt.Fprintf(c.errWriter, "gore: %s\n", err)
+			return nil, err
+		}
+	}
+
 	return gore.New(
-		gore.AutoImport(autoImport),
-		gore.ExtFiles(extFiles),
-		gore.PackageName(packageName),
+		gore.AutoImport(opts.autoImport),
+		gore.ExtFiles(opts.extFiles),
+		gore.PackageName(opts.packageName),
+		gore.Open(opts.bundle),
+		gore.MaxOutput(opts.maxOutput),
+		gore.Race(opts.race),
+		gore.Deterministic(opts.deterministic),
+		gore.Timeout(opts.timeout),
+		gore.Sandbox(opts.sandbox, opts.sandboxImage),
+		gore.TempDir(opts.tempDir),
+		gore.NoColor(opts.noColor),
+		gore.Debug(opts.debug),
+		gore.Server(opts.server),
+		gore.Plain(opts.plain),
+		gore.Kernel(opts.kernel),
+		gore.HTTP(opts.httpAddr),
+		gore.CheckUpdate(opts.checkUpdate),
+		gore.A11y(opts.a11y),
+		gore.BuildContext(opts.buildContext()),
+		gore.Storage(store),
 		gore.OutWriter(c.outWriter),
 		gore.ErrWriter(c.errWriter),
 	), nil
 }
+
+// envUsage is shown in the usage of the options of the session.
+const envUsage = `The options of the session default to the environment variables GORE_<OPTION>
+(e.g. GORE_AUTOIMPORT=1, GORE_MAX_OUTPUT=64KB).`
+
+func (c *cli) printVersion() {
+	fmt.Fprintf(c.outWriter, "gore %s (rev: %s/%s)\n", gore.Version, buildRevision(), runtime.Version())
+}
+
+// buildRevision returns the revision given by -ldflags on release builds,
+// or the one embedded by go build (e.g. go install from a clone) if any.
+func buildRevision() string {
+	if revision != "HEAD" {
+		return revision
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return revision
+	}
+	var rev string
+	var modified bool
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if rev == "" {
+		if v := info.Main.Version; v != "" && v != "(devel)" {
+			return v
+		}
+		return revision
+	}
+	if len(rev) > 7 {
+		rev = rev[:7]
+	}
+	if modified {
+		rev += "-dirty"
+	}
+	return rev
+}
+
+func (c *cli) usageError(sub string) int {
+	fmt.Fprintf(c.errWriter, "gore: invalid arguments (see gore %s -help)\n", sub)
+	return exitCodeErr
+}
+
+func runEval(c *cli, opts *options, args []string) int {
+	g, err := c.newGore(opts)
+	if err != nil {
+		return exitCodeErr
+	}
+	if len(args) == 0 {
+		return c.exit(g.Script(os.Stdin))
+	}
+	return c.exit(g.Script(strings.NewReader(strings.Join(args, "\n"))))
+}
+
+func runScript(c *cli, opts *options, args []string) int {
+	if len(args) != 1 {
+		return c.usageError("run")
+	}
+	g, err := c.newGore(opts)
+	if err != nil {
+		return exitCodeErr
+	}
+	if args[0] == "-" {
+		return c.exit(g.Script(os.Stdin))
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return c.exit(err)
+	}
+	defer f.Close()
+	return c.exit(g.Script(f))
+}
+
+func runServe(c *cli, opts *options, args []string) int {
+	if len(args) != 0 {
+		return c.usageError("serve")
+	}
+	opts.server = opts.httpAddr == ""
+	g, err := c.newGore(opts)
+	if err != nil {
+		return exitCodeErr
+	}
+	return c.exit(g.Run())
+}
+
+func runKernel(c *cli, opts *options, args []string) int {
+	if len(args) != 1 {
+		return c.usageError("kernel")
+	}
+	opts.kernel = args[0]
+	g, err := c.newGore(opts)
+	if err != nil {
+		return exitCodeErr
+	}
+	return c.exit(g.Run())
+}
+
+func runDoctor(c *cli, _ *options, args []string) int {
+	if len(args) != 0 {
+		return c.usageError("doctor")
+	}
+	if !gore.Doctor(c.outWriter) {
+		return exitCodeErr
+	}
+	return exitCodeOK
+}
+
+func runSelfUpdate(c *cli, opts *options, args []string) int {
+	if len(args) != 0 {
+		return c.usageError("self-update")
+	}
+	return c.exit(gore.SelfUpdate(context.Background(), c.outWriter, opts.checkOnly))
+}
+
+func runVersion(c *cli, _ *options, args []string) int {
+	if len(args) != 0 {
+		return c.usageError("version")
+	}
+	c.printVersion()
+	return exitCodeOK
+}
@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -20,6 +22,16 @@ func TestCliRun_Version(t *testing.T) {
 	assert.Equal(t, "", stderr.String())
 }
 
+func TestCliRun_VersionCommand(t *testing.T) {
+	var stdout, stderr strings.Builder
+	c := &cli{&stdout, &stderr}
+	code := c.run([]string{"version"})
+	require.Equal(t, exitCodeOK, code)
+
+	assert.Contains(t, stdout.String(), "gore "+gore.Version)
+	assert.Equal(t, "", stderr.String())
+}
+
 func TestCliRun_Help(t *testing.T) {
 	var stdout, stderr strings.Builder
 	c := &cli{&stdout, &stderr}
@@ -40,3 +52,124 @@ func TestCliRun_Unknown(t *testing.T) {
 	assert.Contains(t, stdout.String(), "gore -")
 	assert.Contains(t, stderr.String(), "flag provided but not defined: -foobar")
 }
+
+func TestCliRun_UnknownStore(t *testing.T) {
+	var stdout, stderr strings.Builder
+	c := &cli{&stdout, &stderr}
+	code := c.run([]string{"-store", "foo"})
+	require.Equal(t, exitCodeErr, code)
+
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, "gore: unknown store: foo\n", stderr.String())
+}
+
+func TestCliRun_Completions(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var stdout, stderr strings.Builder
+		c := &cli{&stdout, &stderr}
+		code := c.run([]string{"completions", shell})
+		require.Equal(t, exitCodeOK, code)
+
+		assert.Contains(t, stdout.String(), "autoimport", shell)
+		assert.Contains(t, stdout.String(), "store", shell)
+		assert.Contains(t, stdout.String(), "completions", shell)
+		assert.Equal(t, "", stderr.String())
+	}
+
+	var stdout, stderr strings.Builder
+	c := &cli{&stdout, &stderr}
+	code := c.run([]string{"completions", "csh"})
+	require.Equal(t, exitCodeErr, code)
+	assert.Equal(t, "gore: unknown shell: csh\n", stderr.String())
+}
+
+func TestCliRun_CommandHelp(t *testing.T) {
+	var stdout, stderr strings.Builder
+	c := &cli{&stdout, &stderr}
+	code := c.run([]string{"eval", "-help"})
+	require.Equal(t, exitCodeOK, code)
+
+	assert.Contains(t, stdout.String(), "% gore eval [options] [<code>...]")
+	assert.Contains(t, stdout.String(), "-autoimport")
+	assert.Equal(t, "", stderr.String())
+
+	stdout.Reset()
+	code = c.run([]string{"eval", "-server"})
+	require.Equal(t, exitCodeErr, code)
+	assert.Contains(t, stderr.String(), "flag provided but not defined: -server")
+}
+
+func TestCliRun_Eval(t *testing.T) {
+	var stdout, stderr strings.Builder
+	c := &cli{&stdout, &stderr}
+	code := c.run([]string{"eval", "-autoimport", "-store", "memory", "x := 3", "func f(n int) int {\nreturn n * 2\n}", "fmt.Println(f(x))"})
+	require.Equal(t, exitCodeOK, code)
+
+	assert.Contains(t, stdout.String(), "6\n")
+	assert.Equal(t, "", stderr.String())
+
+	stdout.Reset()
+	code = c.run([]string{"eval", "-autoimport", "-store", "memory", `fmt.Println("ok")`, "y", "2"})
+	require.Equal(t, gore.ExitCodeCompile, code)
+
+	assert.Contains(t, stdout.String(), "ok\n")
+	assert.Contains(t, stderr.String(), "undefined: y")
+	assert.Contains(t, stderr.String(), "gore: evaluation failed at line 2\n")
+}
+
+func TestCliRun_EvalExitStatus(t *testing.T) {
+	var stdout, stderr strings.Builder
+	c := &cli{&stdout, &stderr}
+	code := c.run([]string{"eval", "-store", "memory", ":import os", "x := 1", "os.Exit(3)", "x"})
+	require.Equal(t, 3, code)
+	assert.Contains(t, stderr.String(), "gore: evaluation failed at line 3\n")
+
+	stderr.Reset()
+	code = c.run([]string{"eval", "-store", "memory", `panic("boom")`})
+	require.Equal(t, 2, code)
+	assert.Contains(t, stderr.String(), "panic: boom")
+
+	stderr.Reset()
+	code = c.run([]string{"-store", "memory", "-e", ":import os", "-e", "os.Exit(4)"})
+	require.Equal(t, 4, code)
+	assert.Contains(t, stderr.String(), "gore: evaluation failed at line 2\n")
+}
+
+func TestCliRun_Env(t *testing.T) {
+	t.Setenv("GORE_AUTOIMPORT", "1")
+	t.Setenv("GORE_STORE", "memory")
+
+	var stdout, stderr strings.Builder
+	c := &cli{&stdout, &stderr}
+	code := c.run([]string{"eval", `fmt.Println("ok")`})
+	require.Equal(t, exitCodeOK, code)
+
+	assert.Contains(t, stdout.String(), "ok\n")
+	assert.Equal(t, "", stderr.String())
+
+	t.Setenv("GORE_RACE", "foo")
+	stdout.Reset()
+	code = c.run([]string{"eval", "1"})
+	require.Equal(t, exitCodeErr, code)
+
+	assert.Contains(t, stderr.String(), `gore: invalid value "foo" for GORE_RACE`)
+}
+
+func TestCliRun_Run(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "inputs.txt")
+	require.NoError(t, os.WriteFile(file, []byte(":import fmt\ns := []int{\n\t1,\n\t2,\n}\n\nfmt.Println(len(s))\n"), 0o644))
+
+	var stdout, stderr strings.Builder
+	c := &cli{&stdout, &stderr}
+	code := c.run([]string{"run", file})
+	require.Equal(t, exitCodeOK, code)
+
+	assert.Contains(t, stdout.String(), "\n2\n")
+	assert.Equal(t, "", stderr.String())
+
+	require.NoError(t, os.WriteFile(file, []byte("func f() {\n"), 0o644))
+	stdout.Reset()
+	code = c.run([]string{"run", file})
+	require.Equal(t, exitCodeErr, code)
+	assert.Equal(t, "gore: unexpected end of input at line 1\n", stderr.String())
+}
@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+func runCompletions(c *cli, _ *options, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(c.errWriter, "usage: gore completions %s\n", strings.Join(completionShells, "|"))
+		return exitCodeErr
+	}
+
+	var fn func(io.Writer, []completionCommand)
+	switch args[0] {
+	case "bash":
+		fn = writeBashCompletion
+	case "zsh":
+		fn = writeZshCompletion
+	case "fish":
+		fn = writeFishCompletion
+	default:
+		fmt.Fprintf(c.errWriter, "gore: unknown shell: %s\n", args[0])
+		return exitCodeErr
+	}
+	fn(c.outWriter, c.completionCommands())
+	return exitCodeOK
+}
+
+// completionCommand is gore itself (with the empty name) or a subcommand
+// to be completed.
+type completionCommand struct {
+	name, usage string
+	flags       []completionFlag
+	words       []string
+}
+
+// completionFlag is a flag to be completed.
+type completionFlag struct {
+	name, usage string
+	hasArg      bool
+}
+
+// completionCommands returns gore and the subcommands with the flags
+// defined by the flag sets, so that the completion follows the flags.
+func (c *cli) completionCommands() []completionCommand {
+	cmds := []completionCommand{{flags: completionFlags(c.flagSet(&options{}))}}
+	for _, sub := range subcommands {
+		cmds = append(cmds, completionCommand{
+			name:  sub.name,
+			usage: sub.usage,
+			flags: completionFlags(c.subcommandFlagSet(sub, &options{})),
+			words: sub.words,
+		})
+	}
+	return cmds
+}
+
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:   f.Name,
+			usage:  f.Usage,
+			hasArg: !ok || !b.IsBoolFlag(),
+		})
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].name < flags[j].name })
+	return flags
+}
+
+// completionWords returns the flags and the argument words of the command.
+func (cmd *completionCommand) completionWords() string {
+	var words []string
+	for _, f := range cmd.flags {
+		words = append(words, "-"+f.name)
+	}
+	return strings.Join(append(words, cmd.words...), " ")
+}
+
+func writeBashCompletion(w io.Writer, cmds []completionCommand) {
+	var names []string
+	argNames := map[string]bool{}
+	for _, cmd := range cmds[1:] {
+		names = append(names, cmd.name)
+	}
+	for _, cmd := range cmds {
+		for _, f := range cmd.flags {
+			if f.hasArg {
+				argNames["-"+f.name] = true
+			}
+		}
+	}
+	var args []string
+	for name := range argNames {
+		args = append(args, name)
+	}
+	sort.Strings(args)
+
+	fmt.Fprintf(w, `# bash completion for gore
+_gore() {
+    local cur prev words
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+        %s)
+            COMPREPLY=($(compgen -f -- "$cur"))
+            return
+            ;;
+    esac
+    if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then
+        COMPREPLY=($(compgen -W %q -- "$cur"))
+        return
+    fi
+    case "${COMP_WORDS[1]}" in
+`, strings.Join(args, "|"), strings.Join(names, " "))
+	for _, cmd := range cmds[1:] {
+		fmt.Fprintf(w, "        %s) words=%q ;;\n", cmd.name, cmd.completionWords())
+	}
+	fmt.Fprintf(w, `        *) words=%q ;;
+    esac
+    COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+complete -F _gore gore
+`, cmds[0].completionWords())
+}
+
+func writeZshCompletion(w io.Writer, cmds []completionCommand) {
+	escape := strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`).Replace
+	writeArguments := func(cmd completionCommand, indent string) {
+		fmt.Fprintf(w, "%s_arguments", indent)
+		for _, f := range cmd.flags {
+			if f.hasArg {
+				fmt.Fprintf(w, " \\\n%s    '-%s[%s]:value:_files'", indent, f.name, escape(f.usage))
+			} else {
+				fmt.Fprintf(w, " \\\n%s    '-%s[%s]'", indent, f.name, escape(f.usage))
+			}
+		}
+		if len(cmd.words) > 0 {
+			fmt.Fprintf(w, " \\\n%s    '1:argument:(%s)'", indent, strings.Join(cmd.words, " "))
+		} else if cmd.name != "" {
+			fmt.Fprintf(w, " \\\n%s    '*:file:_files'", indent)
+		}
+		fmt.Fprint(w, "\n")
+	}
+
+	fmt.Fprint(w, "#compdef gore\n\n_gore() {\n    local -a subcommands\n    subcommands=(\n")
+	for _, cmd := range cmds[1:] {
+		fmt.Fprintf(w, "        '%s:%s'\n", cmd.name, escape(cmd.usage))
+	}
+	fmt.Fprint(w, `    )
+    if (( CURRENT == 2 )) && [[ $words[CURRENT] != -* ]]; then
+        _describe 'command' subcommands
+        return
+    fi
+    case $words[2] in
+`)
+	for _, cmd := range cmds[1:] {
+		fmt.Fprintf(w, "    %s)\n        shift words\n        (( CURRENT-- ))\n", cmd.name)
+		writeArguments(cmd, "        ")
+		fmt.Fprint(w, "        ;;\n")
+	}
+	fmt.Fprint(w, "    *)\n")
+	writeArguments(cmds[0], "        ")
+	fmt.Fprint(w, "        ;;\n    esac\n}\n\n_gore \"$@\"\n")
+}
+
+func writeFishCompletion(w io.Writer, cmds []completionCommand) {
+	escape := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace
+	fmt.Fprint(w, "# fish completion for gore\ncomplete -c gore -f\n")
+	for _, cmd := range cmds[1:] {
+		fmt.Fprintf(w, "complete -c gore -n __fish_use_subcommand -a %s -d '%s'\n", cmd.name, escape(cmd.usage))
+	}
+	for _, cmd := range cmds {
+		cond := "__fish_use_subcommand"
+		if cmd.name != "" {
+			cond = "'__fish_seen_subcommand_from " + cmd.name + "'"
+		}
+		if len(cmd.words) > 0 {
+			fmt.Fprintf(w, "complete -c gore -n %s -a '%s'\n", cond, strings.Join(cmd.words, " "))
+		}
+		for _, f := range cmd.flags {
+			if f.hasArg {
+				fmt.Fprintf(w, "complete -c gore -n %s -o %s -r -F -d '%s'\n", cond, f.name, escape(f.usage))
+			} else {
+				fmt.Fprintf(w, "complete -c gore -n %s -o %s -d '%s'\n", cond, f.name, escape(f.usage))
+			}
+		}
+	}
+}
@@ -3,13 +3,13 @@ package gore
 import (
 	"fmt"
 	"go/ast"
-	"go/build"
+	"go/parser"
+	"go/token"
 	"go/types"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -25,6 +25,7 @@ type command struct {
 	complete func(*Session, string) []string
 	arg      string
 	document string
+	block    bool // whether the argument continues while the braces are not closed
 }
 
 var commands []command
@@ -45,17 +46,82 @@ func init() {
 			complete: completeDoc,
 			document: "print the type of expression",
 		},
+		{
+			name:     commandName("ast"),
+			action:   actionAST,
+			arg:      "<code>",
+			document: "print the syntax tree of the code",
+		},
+		{
+			name:     commandName("bench"),
+			action:   actionBench,
+			arg:      "<expr>",
+			complete: completeDoc,
+			document: "benchmark the expression, printing ns/op and allocs/op",
+		},
+		{
+			name:     commandName("test"),
+			action:   actionTest,
+			arg:      "[<pattern>]",
+			document: "run the test functions declared, or the ones matching the pattern",
+		},
+		{
+			name:     commandName("prof"),
+			action:   actionProf,
+			arg:      "cpu|mem",
+			document: "profile the last statement for the CPU or the allocations, printing the top entries",
+		},
+		{
+			name:     commandName("debug"),
+			action:   actionDebug,
+			document: "debug the last statement by dlv, stopping at the breakpoint set at it",
+		},
 		{
 			name:     commandName("print"),
 			action:   actionPrint,
 			document: "print current source",
 		},
+		{
+			name:     commandName("file"),
+			action:   actionFile,
+			arg:      "[<name>.go|main]",
+			document: "add the declarations to another file of the package, or to the main file",
+		},
 		{
 			name:     commandName("w[rite]"),
 			action:   actionWrite,
-			complete: nil, // TODO implement
-			arg:      "[<file>]",
-			document: "write out current source",
+			complete: completeFile,
+			arg:      "[<n>..<m> | --bundle] [<file>]",
+			document: "write out current source, or the statements with their dependencies",
+		},
+		{
+			name:     commandName("save-session"),
+			action:   actionSaveSession,
+			arg:      "<name>",
+			document: "save the session by the name, to be restored after restarting gore",
+		},
+		{
+			name:     commandName("restore-session"),
+			action:   actionRestoreSession,
+			arg:      "<name>",
+			document: "clear the session and restore the one saved by the name",
+		},
+		{
+			name:     commandName("session"),
+			action:   actionSession,
+			complete: completeSession,
+			arg:      "[list | new <name> | switch <name>]",
+			document: "list the sessions, create a session by the name, or switch to it",
+		},
+		{
+			name:     commandName("share"),
+			action:   actionShare,
+			document: "share the source to the Go Playground (or the gist endpoint of :set share), printing the URL",
+		},
+		{
+			name:     commandName("copy"),
+			action:   actionCopy,
+			document: "copy the source without the scaffolding to the clipboard",
 		},
 		{
 			name:     commandName("clear"),
@@ -69,9 +135,188 @@ func init() {
 			arg:      "<expr or pkg>",
 			document: "show documentation",
 		},
+		{
+			name:     commandName("vars"),
+			action:   actionVars,
+			document: "list the variables with the types and the statements declaring them",
+		},
+		{
+			name:     commandName("funcs"),
+			action:   actionFuncs,
+			document: "list the functions with the signatures",
+		},
+		{
+			name:     commandName("types"),
+			action:   actionTypes,
+			document: "list the types with the underlying types and the methods",
+		},
+		{
+			name:     commandName("methods"),
+			action:   actionMethods,
+			arg:      "[<type>]",
+			document: "list the methods of the type, or of the types declared",
+		},
+		{
+			name:     commandName("check-impl"),
+			action:   actionCheckImpl,
+			arg:      "<type> <interface>",
+			document: "report whether the type implements the interface, or the methods lacked",
+		},
+		{
+			name:     commandName("impl"),
+			action:   actionImpl,
+			arg:      "<type> <interface>",
+			document: "add the stubs of the methods lacked to implement the interface",
+		},
+		{
+			name:     commandName("imports"),
+			action:   actionImports,
+			document: "list the imports, marking the unused ones",
+		},
+		{
+			name:     commandName("vet"),
+			action:   actionVet,
+			document: "report the findings of go vet by the statements",
+		},
+		{
+			name:     commandName("deps"),
+			action:   actionDeps,
+			document: "list the modules of the imports with the versions resolved",
+		},
+		{
+			name:     commandName("mark"),
+			action:   actionMark,
+			complete: completeMark,
+			arg:      "[<name>]",
+			document: "mark the last statement, or list the marks",
+		},
+		{
+			name:     commandName("goto"),
+			action:   actionGoto,
+			complete: completeMark,
+			arg:      "<mark or n>",
+			document: "drop the statements after the statement",
+		},
+		{
+			name:     commandName("drop"),
+			action:   actionDrop,
+			complete: completeMark,
+			arg:      "<n>[..<m>] | since <mark or n>",
+			document: "drop the statements",
+		},
+		{
+			name:     commandName("checkpoint"),
+			action:   actionCheckpoint,
+			complete: completeCheckpoint,
+			arg:      "[<name>]",
+			document: "snapshot the code by the name, or list the checkpoints",
+		},
+		{
+			name:     commandName("rollback"),
+			action:   actionRollback,
+			complete: completeCheckpoint,
+			arg:      "[<name>]",
+			document: "restore the code of the checkpoint (the last one if omitted), dropping the later ones",
+		},
+		{
+			name:     commandName("cd"),
+			action:   actionCd,
+			complete: completeCd,
+			arg:      "[<dir>]",
+			document: "change the working directory of the program (the session directory if omitted)",
+		},
+		{
+			name:     commandName("pwd"),
+			action:   actionPwd,
+			document: "print the working directory of the program",
+		},
+		{
+			name:     commandName("!"),
+			action:   actionShell,
+			arg:      "<command>",
+			document: "run the command by the shell in the working directory of the program",
+		},
+		{
+			name:     commandName("job"),
+			action:   actionJob,
+			arg:      "<code>",
+			document: "run the code in the background, reporting the output when it ends",
+		},
+		{
+			name:     commandName("jobs"),
+			action:   actionJobs,
+			document: "list the jobs running",
+		},
+		{
+			name:     commandName("kill"),
+			action:   actionKill,
+			arg:      "<n>",
+			document: "kill the job",
+		},
+		{
+			name:     commandName("env"),
+			action:   actionEnv,
+			complete: completeEnv,
+			arg:      "[<key>=<value> | -u <key>]",
+			document: "set or unset an environment variable, or list them",
+		},
+		{
+			name:     commandName("args"),
+			action:   actionArgs,
+			arg:      "[<arg>...]",
+			document: "set the arguments of the program, or show them (:args -- to clear)",
+		},
+		{
+			name:     commandName("cgo"),
+			action:   actionCgo,
+			arg:      "[<C code>]",
+			document: "add C code to import \"C\" by cgo, or show it (:cgo -- to clear)",
+			block:    true,
+		},
+		{
+			name:     commandName("stdin"),
+			action:   actionStdin,
+			arg:      "[<<EOF]",
+			document: "give the lines until EOF to the program as the input, or show them (:stdin -- to clear)",
+		},
+		{
+			name:     commandName("tags"),
+			action:   actionTags,
+			arg:      "[<tag>...]",
+			document: "set the build tags, or show them (:tags -- to clear)",
+		},
+		{
+			name:     commandName("history"),
+			action:   actionHistory,
+			arg:      "[search [-failed|-ok] [-session] [-since <duration or date>] [<word>...]]",
+			document: "list the recent inputs, or search the inputs of all the sessions",
+		},
+		{
+			name:     commandName("record"),
+			action:   actionRecord,
+			complete: completeFile,
+			arg:      "[--log-format text|jsonl] [<file>]",
+			document: "record inputs and outputs to file, or stop recording",
+		},
+		{
+			name:     commandName("replay"),
+			action:   actionReplay,
+			complete: completeFile,
+			arg:      "<file>",
+			document: "evaluate inputs recorded in file",
+		},
+		{
+			name:     commandName("set"),
+			action:   actionSet,
+			complete: completeSet,
+			arg:      "[<name> [<value>]]",
+			document: "show or change the settings",
+		},
 		{
 			name:     commandName("h[elp]"),
 			action:   actionHelp,
+			complete: completeCommand,
+			arg:      "[<command>]",
 			document: "show this help",
 		},
 		{
@@ -84,31 +329,48 @@ func init() {
 
 func actionImport(s *Session, arg string) error {
 	if arg == "" {
-		return fmt.Errorf("argument is required")
+		return s.errorf("argument is required")
 	}
 
-	if strings.Contains(arg, " ") {
-		for _, v := range strings.Fields(arg) {
-			if v == "" {
-				continue
-			}
-			if err := actionImport(s, v); err != nil {
-				return err
-			}
+	// the name of the package is given before the quoted path
+	// (e.g. :import rd "math/rand", :import . "fmt")
+	fields := strings.Fields(arg)
+	for i := 0; i < len(fields); i++ {
+		var name string
+		path := fields[i]
+		if i+1 < len(fields) && strings.HasPrefix(fields[i+1], `"`) && !strings.HasPrefix(path, `"`) {
+			name, path = path, fields[i+1]
+			i++
+		}
+		if err := s.importPackage(name, strings.Trim(path, `"`)); err != nil {
+			return err
 		}
+	}
 
-		return nil
+	return nil
+}
+
+// importPackage adds the import of the package by the name (or "" for the
+// package name), which is blank until used.
+func (s *Session) importPackage(name, path string) error {
+	if name != "" && name != "." && name != "_" && !token.IsIdentifier(name) {
+		return s.errorf("invalid import name: %q", name)
 	}
 
-	arg = strings.Trim(arg, `"`)
+	// C is not a package but the C code given by :cgo
+	if path == "C" {
+		s.addCgoImport()
+		return nil
+	}
 
 	// check if the package specified by path is importable
 	_, err := packages.Load(
 		&packages.Config{
 			Dir:        s.tempDir,
-			BuildFlags: []string{"-mod=mod"},
+			Env:        s.loadEnviron(),
+			BuildFlags: s.buildFlags(),
 		},
-		arg,
+		path,
 	)
 	if err != nil {
 		return err
@@ -116,26 +378,41 @@ func actionImport(s *Session, arg string) error {
 
 	var found bool
 	for _, i := range s.file.Imports {
-		if strings.Trim(i.Path.Value, `"`) == arg {
+		if strings.Trim(i.Path.Value, `"`) == path {
 			found = true
 			break
 		}
 	}
 	if !found {
-		astutil.AddNamedImport(s.fset, s.file, "_", arg)
+		astutil.AddNamedImport(s.fset, s.file, "_", path)
 		_, err = s.types.Check("_tmp", s.fset, append(s.extraFiles, s.file), nil)
-		if err != nil && strings.Contains(err.Error(), "could not import "+arg) {
-			astutil.DeleteNamedImport(s.fset, s.file, "_", arg)
-			return fmt.Errorf("could not import %q", arg)
+		if err != nil && strings.Contains(err.Error(), "could not import "+path) {
+			astutil.DeleteNamedImport(s.fset, s.file, "_", path)
+			return s.errorf("could not import %q", path)
 		}
 	}
 
+	if name == "" {
+		delete(s.imports.names, path)
+	} else {
+		if s.imports.names == nil {
+			s.imports.names = make(map[string]string)
+		}
+		s.imports.names[path] = name
+	}
 	return nil
 }
 
-var gorootSrc = filepath.Join(filepath.Clean(runtime.GOROOT()), "src")
+// importName returns the name of the import given by :import, or nil for the
+// package name.
+func (s *Session) importName(imp *ast.ImportSpec) *ast.Ident {
+	if name, ok := s.imports.names[strings.Trim(imp.Path.Value, `"`)]; ok {
+		return ast.NewIdent(name)
+	}
+	return nil
+}
 
-func completeImport(_ *Session, prefix string) []string {
+func completeImport(s *Session, prefix string) []string {
 	result := []string{}
 	seen := map[string]bool{}
 
@@ -144,58 +421,55 @@ func completeImport(_ *Session, prefix string) []string {
 	d, fn := path.Split(prefix[p:])
 
 	// complete candidates from the current module
-	if modules, err := goListAll(); err == nil {
-		for _, m := range modules {
-
-			matchPath := func(fn string) bool {
-				if len(fn) < 2 {
-					return false
-				}
-				for _, s := range strings.Split(m.Path, "/") {
-					if strings.HasPrefix(s, fn) || strings.HasPrefix(strings.TrimPrefix(s, "go-"), fn) {
-						return true
-					}
-				}
+	for _, m := range s.goModules() {
+		matchPath := func(fn string) bool {
+			if len(fn) < 2 {
 				return false
 			}
-			if strings.HasPrefix(m.Path, prefix[p:]) || d == "" && matchPath(fn) {
-				result = append(result, prefix[:p]+m.Path)
-				seen[m.Path] = true
-				continue
+			for _, s := range strings.Split(m.Path, "/") {
+				if strings.HasPrefix(s, fn) || strings.HasPrefix(strings.TrimPrefix(s, "go-"), fn) {
+					return true
+				}
 			}
+			return false
+		}
+		if strings.HasPrefix(m.Path, prefix[p:]) || d == "" && matchPath(fn) {
+			result = append(result, prefix[:p]+m.Path)
+			seen[m.Path] = true
+			continue
+		}
 
-			if strings.HasPrefix(d, m.Path) {
-				dir := filepath.Join(m.Dir, strings.Replace(d, m.Path, "", 1))
-				if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		if strings.HasPrefix(d, m.Path) {
+			dir := filepath.Join(m.Dir, strings.Replace(d, m.Path, "", 1))
+			if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+				continue
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, fi := range entries {
+				if !fi.IsDir() {
 					continue
 				}
-				entries, err := os.ReadDir(dir)
-				if err != nil {
+				name := fi.Name()
+				if skipCompleteDir(name) {
 					continue
 				}
-				for _, fi := range entries {
-					if !fi.IsDir() {
-						continue
-					}
-					name := fi.Name()
-					if skipCompleteDir(name) {
-						continue
-					}
-					if strings.HasPrefix(name, fn) {
-						r := path.Join(d, name)
-						if !seen[r] {
-							result = append(result, prefix[:p]+r)
-							seen[r] = true
-						}
+				if strings.HasPrefix(name, fn) {
+					r := path.Join(d, name)
+					if !seen[r] {
+						result = append(result, prefix[:p]+r)
+						seen[r] = true
 					}
 				}
 			}
-
 		}
 	}
 
 	// complete candidates from GOPATH/src/
-	for _, srcDir := range build.Default.SrcDirs() {
+	gorootSrc := filepath.Join(filepath.Clean(s.buildContext.GOROOT), "src")
+	for _, srcDir := range s.buildContext.SrcDirs() {
 		dir := filepath.Join(srcDir, d)
 
 		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
@@ -270,19 +544,23 @@ func completeDoc(s *Session, prefix string) []string {
 }
 
 func actionPrint(s *Session, _ string) error {
-	source, err := s.source(true)
+	source, err := s.userSource(true)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(source)
+	if _, height, ok := s.stdoutTerminal(); ok && strings.Count(source, "\n") >= height {
+		return s.pageSource(source, os.Stdin, height-1)
+	}
+
+	fmt.Fprintln(s.stdout, source)
 
 	return nil
 }
 
 func actionType(s *Session, in string) error {
 	if in == "" {
-		return fmt.Errorf("argument is required")
+		return s.errorf("argument is required")
 	}
 
 	s.clearQuickFix()
@@ -294,6 +572,7 @@ func actionType(s *Session, in string) error {
 	if err != nil {
 		return err
 	}
+	s.addUsedResults()
 
 	s.typeInfo = types.Info{
 		Types:  make(map[ast.Expr]types.TypeAndValue),
@@ -317,8 +596,64 @@ func actionType(s *Session, in string) error {
 	return nil
 }
 
-func actionWrite(s *Session, filename string) error {
-	source, err := s.source(false)
+func actionAST(s *Session, in string) error {
+	if in == "" {
+		return s.errorf("argument is required")
+	}
+
+	// try as an expression, statements and declarations in this order,
+	// and keep the positions relative to the input by line directives
+	fset := token.NewFileSet()
+	var node any
+	expr, err := parser.ParseExprFrom(fset, "", in, 0)
+	if err == nil {
+		node = expr
+	} else if f, serr := parser.ParseFile(fset, "", "package p; func _() {\n//line :1:1\n"+in+"\n}", 0); serr == nil {
+		node = f.Decls[0].(*ast.FuncDecl).Body.List
+	} else if f, derr := parser.ParseFile(fset, "", "package p\n//line :1:1\n"+in, 0); derr == nil {
+		node = f.Decls
+	} else {
+		return err
+	}
+
+	return ast.Fprint(s.stdout, fset, node, ast.NotNilFilter)
+}
+
+func actionWrite(s *Session, arg string) error {
+	if fields := strings.Fields(arg); len(fields) > 0 && (fields[0] == "--bundle" || fields[0] == "-bundle") {
+		filename := expandHome(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(arg), fields[0])))
+		if filename == "" {
+			filename = fmt.Sprintf("gore_session_%s%s", time.Now().Format("20060102_150405"), bundleExt)
+		}
+		if err := s.writeBundle(filename); err != nil {
+			return err
+		}
+		s.unsaved, s.savedBy = false, ":write --bundle "+filename
+		infof("Session bundled to %s", filename)
+		return nil
+	}
+
+	filename := arg
+	var from, to int
+	var err error
+	if fields := strings.Fields(arg); len(fields) > 1 {
+		filename = fields[len(fields)-1]
+		from, to, err = s.parseStmtRange(strings.Join(fields[:len(fields)-1], " "))
+		if err != nil {
+			return err
+		}
+	} else if arg != "" && !strings.HasSuffix(arg, ".go") {
+		if from, to, err = s.parseStmtRange(arg); err == nil {
+			filename = ""
+		}
+	}
+
+	var source string
+	if from > 0 {
+		source, err = s.exportSource(from, to)
+	} else {
+		source, err = s.userSource(false)
+	}
 	if err != nil {
 		return err
 	}
@@ -326,12 +661,14 @@ func actionWrite(s *Session, filename string) error {
 	if filename == "" {
 		filename = fmt.Sprintf("gore_session_%s.go", time.Now().Format("20060102_150405"))
 	}
+	filename = expandHome(filename)
 
 	err = os.WriteFile(filename, []byte(source), 0o644)
 	if err != nil {
 		return err
 	}
 
+	s.unsaved, s.savedBy = false, ":write "+filename
 	infof("Source wrote to %s", filename)
 
 	return nil
@@ -343,7 +680,7 @@ func actionClear(s *Session, _ string) error {
 
 func actionDoc(s *Session, in string) error {
 	if in == "" {
-		return fmt.Errorf("argument is required")
+		return s.errorf("argument is required")
 	}
 
 	s.clearQuickFix()
@@ -397,7 +734,7 @@ func actionDoc(s *Session, in string) error {
 	}
 
 	if docObj == nil {
-		return fmt.Errorf("cannot determine the document location")
+		return s.errorf("cannot determine the document location")
 	}
 
 	debugf("doc :: obj=%#v", docObj)
@@ -456,17 +793,38 @@ func actionDoc(s *Session, in string) error {
 	return godoc.Run()
 }
 
-func actionHelp(s *Session, _ string) error {
+func completeCommand(_ *Session, prefix string) []string {
+	name := strings.TrimLeft(prefix, ":")
+	var result []string
+	for _, command := range commands {
+		if command.name.matchesPrefix(name) {
+			result = append(result, fmt.Sprint(command.name))
+		}
+	}
+	return result
+}
+
+func actionHelp(s *Session, arg string) error {
+	name := strings.TrimLeft(arg, ":")
 	w := tabwriter.NewWriter(s.stdout, 0, 8, 4, ' ', 0)
+	var found bool
 	for _, command := range commands {
+		if name != "" && !command.name.matches(name) {
+			continue
+		}
+		found = true
 		cmd := fmt.Sprintf(":%s", command.name)
 		if command.arg != "" {
 			cmd = cmd + " " + command.arg
 		}
-		w.Write([]byte("    " + cmd + "\t" + command.document + "\n"))
+		w.Write([]byte("    " + cmd + "\t" + s.tr(command.document) + "\n"))
 	}
 	w.Flush()
 
+	if !found {
+		return s.errorf("command not found: %s", name)
+	}
+
 	return nil
 }
 
@@ -1,8 +1,14 @@
 package gore
 
 import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,7 +36,7 @@ func TestAction_Type(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Regexp(t, `string
@@ -53,19 +59,19 @@ func TestAction_Doc(t *testing.T) {
 	t.Cleanup(func() { s.Clear() })
 	require.NoError(t, err)
 
-	err = s.Eval(":import encoding/json")
+	_, err = s.Eval(":import encoding/json")
 	require.NoError(t, err)
-	err = s.Eval(":i fmt")
+	_, err = s.Eval(":i fmt")
 	require.NoError(t, err)
 
 	test := func() {
-		err = s.Eval(":doc fmt")
+		_, err = s.Eval(":doc fmt")
 		require.NoError(t, err)
 
-		err = s.Eval(":doc fmt.Print")
+		_, err = s.Eval(":doc fmt.Print")
 		require.NoError(t, err)
 
-		err = s.Eval(":d json.NewEncoder(nil).Encode")
+		_, err = s.Eval(":d json.NewEncoder(nil).Encode")
 		require.NoError(t, err)
 	}
 
@@ -73,10 +79,10 @@ func TestAction_Doc(t *testing.T) {
 
 	// test :doc works after some code
 
-	err = s.Eval("a := 1")
+	_, err = s.Eval("a := 1")
 	require.NoError(t, err)
 
-	err = s.Eval("fmt.Print()")
+	_, err = s.Eval("fmt.Print()")
 	require.NoError(t, err)
 
 	test()
@@ -92,27 +98,56 @@ func TestAction_Import(t *testing.T) {
 	t.Cleanup(func() { s.Clear() })
 	require.NoError(t, err)
 
-	err = s.Eval(":import encoding/json fmt")
+	_, err = s.Eval(":import encoding/json fmt")
 	require.NoError(t, err)
 
-	err = s.Eval("fmt.Print")
+	_, err = s.Eval("fmt.Print")
 	require.NoError(t, err)
 
-	err = s.Eval("json.Encoder{}")
+	_, err = s.Eval("json.Encoder{}")
 	require.NoError(t, err)
 
 	assert.Contains(t, stdout.String(), "(func(...interface {}) (int, error))")
 	assert.Contains(t, stdout.String(), "json.Encoder")
 	assert.Equal(t, "", stderr.String())
 
-	err = s.Eval(":import invalid")
+	_, err = s.Eval(":import invalid")
 	require.Error(t, err)
 
-	err = s.Eval("fmt.Sprint")
+	_, err = s.Eval("fmt.Sprint")
 	require.NoError(t, err)
 	assert.Equal(t, "import: could not import \"invalid\"\n", stderr.String())
 }
 
+func TestAction_Import_Named(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import rd "math/rand" strings . "strconv"`,
+		`rd.New(rd.NewSource(1)) != nil`,
+		`strings.ToUpper("a")`,
+		`Itoa(42)`,
+		`:imports`,
+		`:import 1x "errors"`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `true
+"A"
+"42"
+    . "strconv"
+    "strings"
+    rd "math/rand"
+`, stdout.String())
+	assert.Equal(t, "import: invalid import name: \"1x\"\n", stderr.String())
+}
+
 func TestAction_Clear(t *testing.T) {
 	var stdout, stderr strings.Builder
 	s, err := NewSession(&stdout, &stderr)
@@ -130,7 +165,7 @@ func TestAction_Clear(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Equal(t, `10
@@ -141,23 +176,405 @@ func TestAction_Clear(t *testing.T) {
 	assert.Equal(t, "undefined: x\n", stderr.String())
 }
 
+func TestAction_AST(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:ast x + 1`,
+		`:ast x := 1`,
+		`:ast func f() {}`,
+		`:ast x +`,
+		`:ast`,
+		`x`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `     0  *ast.BinaryExpr {
+     1  .  X: *ast.Ident {
+     2  .  .  NamePos: 1:1
+     3  .  .  Name: "x"
+     4  .  }
+     5  .  OpPos: 1:3
+     6  .  Op: +
+     7  .  Y: *ast.BasicLit {
+     8  .  .  ValuePos: 1:5
+     9  .  .  Kind: INT
+    10  .  .  Value: "1"
+    11  .  }
+    12  }
+     0  []ast.Stmt (len = 1) {
+     1  .  0: *ast.AssignStmt {
+     2  .  .  Lhs: []ast.Expr (len = 1) {
+     3  .  .  .  0: *ast.Ident {
+     4  .  .  .  .  NamePos: 1:1
+     5  .  .  .  .  Name: "x"
+     6  .  .  .  .  Obj: *ast.Object {
+     7  .  .  .  .  .  Kind: var
+     8  .  .  .  .  .  Name: "x"
+     9  .  .  .  .  .  Decl: *(obj @ 1)
+    10  .  .  .  .  }
+    11  .  .  .  }
+    12  .  .  }
+    13  .  .  TokPos: 1:3
+    14  .  .  Tok: :=
+    15  .  .  Rhs: []ast.Expr (len = 1) {
+    16  .  .  .  0: *ast.BasicLit {
+    17  .  .  .  .  ValuePos: 1:6
+    18  .  .  .  .  Kind: INT
+    19  .  .  .  .  Value: "1"
+    20  .  .  .  }
+    21  .  .  }
+    22  .  }
+    23  }
+`, stdout.String()[:strings.Index(stdout.String(), "     0  []ast.Decl")])
+	assert.Contains(t, stdout.String(), `     0  []ast.Decl (len = 1) {
+     1  .  0: *ast.FuncDecl {
+     2  .  .  Name: *ast.Ident {
+     3  .  .  .  NamePos: 1:6`)
+	assert.Equal(t, `ast: 1:4: expected operand, found 'EOF'
+ast: argument is required
+undefined: x
+`, stderr.String())
+}
+
+func TestAction_Env(t *testing.T) {
+	t.Setenv("GORE_TEST_HOME", "/home/gore")
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import os`,
+		`:env GORE_TEST_FOO=foo bar`,
+		`:env -u GORE_TEST_HOME`,
+		`:env`,
+		`os.Getenv("GORE_TEST_FOO")`,
+		`os.LookupEnv("GORE_TEST_HOME")`,
+		`:env GORE_TEST_HOME=/tmp`,
+		`os.Getenv("GORE_TEST_HOME")`,
+		`:env GORE_TEST_FOO`,
+		`:env =foo`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `GORE_TEST_FOO=foo bar
+-u GORE_TEST_HOME
+"foo bar"
+""
+false
+"/tmp"
+`, stdout.String())
+	assert.Equal(t, `env: invalid argument: GORE_TEST_FOO (KEY=VALUE or -u KEY)
+env: invalid name: ""
+`, stderr.String())
+	assert.Equal(t, []string{"-u GORE_TEST_FOO", "-u GORE_TEST_HOME"}, completeEnv(s, "-u GORE_TEST_"))
+	assert.Equal(t, []string{"GORE_TEST_FOO=", "GORE_TEST_HOME="}, completeEnv(s, "GORE_TEST_"))
+}
+
+func TestAction_Bench(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`func f(n int) []int { return make([]int, n) }`,
+		`func g() {}`,
+		`func h() (int, error) { return 0, nil }`,
+		`n := 100`,
+		`:bench f(n)`,
+		`:bench g()`,
+		`:bench h()`,
+		`:bench len("foo")`,
+		`:bench`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	require.Len(t, lines, 5, stdout.String()+stderr.String())
+	assert.Equal(t, "100", lines[0])
+	lines = lines[1:]
+	for _, line := range lines {
+		assert.Regexp(t, `^ *\d+\t *[\d.]+ ns/op\t *\d+ B/op\t *\d+ allocs/op$`, line)
+	}
+	assert.Regexp(t, `\t *1 allocs/op$`, lines[0])
+	assert.Regexp(t, `\t *0 allocs/op$`, lines[1])
+	assert.Equal(t, "bench: argument is required\n", stderr.String())
+
+	_, _ = s.Eval(`:bench x`)
+	assert.Contains(t, stderr.String(), "bench: could not run the benchmark\n")
+	_, err = os.Stat(filepath.Join(s.tempDir, benchFileName))
+	assert.True(t, os.IsNotExist(err))
+	src, err := s.source(false)
+	require.NoError(t, err)
+	assert.NotContains(t, src, benchFuncName)
+}
+
+func TestAction_Prof(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:prof cpu`,
+		`func work(n int) (m int) { for i := 0; i < n; i++ { m ^= i * i % 7 }; return }`,
+		`func alloc(n int) [][]byte { var bs [][]byte; for i := 0; i < n; i++ { bs = append(bs, make([]byte, 1024)) }; return bs }`,
+		`m := work(1e9)`,
+		`:prof cpu`,
+		`bs := alloc(1000)`,
+		`:prof mem`,
+		`:prof`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	out := stdout.String()
+	assert.Contains(t, out, "Type: cpu")
+	assert.Contains(t, out, "main.work")
+	assert.Contains(t, out, "Type: alloc_space")
+	assert.Contains(t, out, "main.alloc")
+	assert.Regexp(t, `^prof: no statement to profile
+profile written to .+cpu.+\.pprof
+profile written to .+mem.+\.pprof
+prof: invalid profile: "" \(cpu or mem\)
+$`, stderr.String())
+	for _, path := range regexp.MustCompile(`\S+\.pprof`).FindAllString(stderr.String(), -1) {
+		os.Remove(path)
+	}
+}
+
+func TestAction_Test(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:test`,
+		`:import testing`,
+		`func add(a, b int) int { return a + b }`,
+		`x := 1`,
+		`func TestAdd(t *testing.T) { if add(x(), 2) != 3 { t.Error("wrong") } }`,
+		`func TestAdd(t *testing.T) { if add(1, 2) != 3 { t.Error("wrong") } }`,
+		`func TestSub(t *testing.T) { t.Errorf("got %d", add(1, -1)) }`,
+		`:test Add`,
+		`:test`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `1
+=== RUN   TestAdd
+--- PASS: TestAdd (0.00s)
+PASS
+=== RUN   TestAdd
+--- PASS: TestAdd (0.00s)
+=== RUN   TestSub
+    got 0
+--- FAIL: TestSub (0.00s)
+FAIL
+`, regexp.MustCompile(`\(\d+\.\d+s\)`).ReplaceAllString(stdout.String(), "(0.00s)"))
+	assert.Equal(t, `test: no test functions
+undefined: x
+test: tests failed
+`, stderr.String())
+	_, err = os.Stat(filepath.Join(s.tempDir, testFileName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAction_Args(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import os`,
+		`:args -v --input 'foo bar.txt' "a\"b" c\ d`,
+		`:args`,
+		`os.Args[1:]`,
+		`len(os.Args)`,
+		`:args main.go`,
+		`:args 'foo`,
+		`:args --`,
+		`:args`,
+		`len(os.Args)`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `-v --input "foo bar.txt" "a\"b" "c d"
+[]string{"-v", "--input", "foo bar.txt", "a\"b", "c d"}
+6
+
+1
+`, stdout.String())
+	assert.Equal(t, `args: unterminated quote or escape
+`, stderr.String())
+}
+
+func TestAction_Stdin(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	assert.True(t, commandContinues(":stdin <<EOF\nfoo"))
+	assert.True(t, commandContinues(":stdin <<'EOF'\n}"))
+	assert.False(t, commandContinues(":stdin <<EOF\nfoo\nEOF"))
+
+	codes := []string{
+		`:import bufio`,
+		`:import os`,
+		":stdin <<EOF\nfoo\n  bar }\nEOF",
+		`:stdin`,
+		`func readLines() (lines []string) {
+			sc := bufio.NewScanner(os.Stdin)
+			for sc.Scan() {
+				lines = append(lines, sc.Text())
+			}
+			return
+		}`,
+		`readLines()`,
+		`:stdin foo`,
+		`:stdin --`,
+		`:stdin`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `foo
+  bar }
+[]string{"foo", "  bar }"}
+`, stdout.String())
+	assert.Equal(t, "stdin: invalid argument: foo (<<MARKER or --)\n", stderr.String())
+}
+
+func TestAction_Tags(t *testing.T) {
+	var stdout, stderr strings.Builder
+	_ = newTempDir(t)
+	require.NoError(t, os.WriteFile("tagged.go", []byte(`//go:build gore_test
+
+package tagged
+
+// V is a value
+var V = 42
+`), 0o644))
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	s.includeFiles([]string{"tagged.go"})
+	codes := []string{
+		`V`,
+		`:tags gore_test,foo bar`,
+		`:tags`,
+		`V`,
+		`:tags foo-bar`,
+		`:tags --`,
+		`:tags`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `gore_test foo bar
+42
+
+`, stdout.String())
+	assert.Equal(t, `undefined: V
+tags: invalid build tag: "foo-bar"
+`, stderr.String())
+	assert.Equal(t, []string{"-mod=mod"}, s.buildFlags())
+}
+
+func TestAction_Cd(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "hello.txt"), []byte("hello"), 0o644))
+
+	codes := []string{
+		`:import os`,
+		`:cd ` + dir,
+		`:cd sub`,
+		`:pwd`,
+		`b, _ := os.ReadFile("hello.txt")`,
+		`string(b)`,
+		`var h, _ = os.ReadFile("hello.txt")`,
+		`string(h)`,
+		`:cd foo`,
+		`:cd`,
+		`wd, _ := os.Getwd()`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	sub := filepath.Join(dir, "sub")
+	assert.Equal(t, dir+"\n"+sub+"\n"+sub+"\n"+`[]byte{0x68, 0x65, 0x6c, 0x6c, 0x6f}
+"hello"
+[]byte{0x68, 0x65, 0x6c, 0x6c, 0x6f}
+"hello"
+`+s.tempDir+"\n"+strconv.Quote(s.tempDir)+"\n", stdout.String())
+	assert.Equal(t, "cd: stat "+filepath.Join(sub, "foo")+": no such file or directory\n", stderr.String())
+	assert.Equal(t, []string{"sub" + string(filepath.Separator)}, completeCd(&Session{workDir: dir}, "s"))
+}
+
 func TestAction_Help(t *testing.T) {
 	var stdout, stderr strings.Builder
 	s, err := NewSession(&stdout, &stderr)
 	t.Cleanup(func() { s.Clear() })
 	require.NoError(t, err)
 
-	err = s.Eval(": :  :   help  ")
+	_, err = s.Eval(": :  :   help  ")
 	require.NoError(t, err)
 
 	assert.Contains(t, stdout.String(), ":import <package>")
-	assert.Contains(t, stdout.String(), ":write [<file>]")
+	assert.Contains(t, stdout.String(), ":write [<n>..<m> | --bundle] [<file>]")
 	assert.Contains(t, stdout.String(), "show this help")
 	assert.Contains(t, stdout.String(), "quit the session")
 	assert.Equal(t, "", stderr.String())
 
-	err = s.Eval(":h")
+	_, err = s.Eval(":h")
+	require.NoError(t, err)
+
+	stdout.Reset()
+	_, err = s.Eval(":help :i")
 	require.NoError(t, err)
+	assert.Equal(t, "    :import <package>    import a package\n", stdout.String())
+
+	_, err = s.Eval(":help foo")
+	require.Error(t, err)
+	assert.Equal(t, "help: command not found: foo\n", stderr.String())
 }
 
 func TestAction_Quit(t *testing.T) {
@@ -166,13 +583,13 @@ func TestAction_Quit(t *testing.T) {
 	t.Cleanup(func() { s.Clear() })
 	require.NoError(t, err)
 
-	err = s.Eval(" :\t: quit")
+	_, err = s.Eval(" :\t: quit")
 	require.Equal(t, ErrQuit, err)
 
 	assert.Equal(t, "", stdout.String())
 	assert.Equal(t, "", stderr.String())
 
-	err = s.Eval(":q")
+	_, err = s.Eval(":q")
 	require.Equal(t, ErrQuit, err)
 }
 
@@ -182,19 +599,19 @@ func TestAction_CommandNotFound(t *testing.T) {
 	t.Cleanup(func() { s.Clear() })
 	require.NoError(t, err)
 
-	err = s.Eval(":::")
+	_, err = s.Eval(":::")
 	require.NoError(t, err)
 
-	err = s.Eval(":foo")
+	_, err = s.Eval(":foo")
 	require.Error(t, err)
 
-	err = s.Eval(":ii")
+	_, err = s.Eval(":ii")
 	require.Error(t, err)
 
-	err = s.Eval(":docc")
+	_, err = s.Eval(":docc")
 	require.Error(t, err)
 
-	err = s.Eval(":help]")
+	_, err = s.Eval(":help]")
 	require.Error(t, err)
 
 	assert.Equal(t, "", stdout.String())
@@ -211,13 +628,13 @@ func TestAction_ArgumentRequired(t *testing.T) {
 	t.Cleanup(func() { s.Clear() })
 	require.NoError(t, err)
 
-	err = s.Eval(":import")
+	_, err = s.Eval(":import")
 	require.Error(t, err)
 
-	err = s.Eval(":type")
+	_, err = s.Eval(":type")
 	require.Error(t, err)
 
-	err = s.Eval(":doc")
+	_, err = s.Eval(":doc")
 	require.Error(t, err)
 
 	assert.Equal(t, "", stdout.String())
@@ -226,3 +643,499 @@ type: argument is required
 doc: argument is required
 `, stderr.String())
 }
+
+func TestAction_Set(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`x := 3.14159265`,
+		`:set floatfmt %.4g`,
+		`:set floatfmt`,
+		`x`,
+		`x * 2`,
+		`:set floatfmt ""`,
+		`x`,
+		`:set floatfmt 4`,
+		`:set foo`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `3.14159265
+floatfmt = "%.4g"
+3.142
+6.283
+3.14159265
+`, stdout.String())
+	assert.Equal(t, `set: invalid format: "4"
+set: unknown setting: foo
+`, stderr.String())
+}
+
+func TestAction_Set_rerunDecls(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import fmt`,
+		`func init() { fmt.Println("init") }`,
+		`1`,
+		`:set rerun-decls off`,
+		`2`,
+		`3`,
+		`func init() { fmt.Println("init again") }`,
+		`4`,
+		`:set rerun-decls`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `init
+init
+1
+init
+2
+3
+init again
+4
+rerun-decls = "off"
+`, stdout.String())
+	assert.Equal(t, `warning: init runs on every evaluation (:set rerun-decls off to run it once)
+`, stderr.String())
+}
+
+func TestAction_Set_printverb(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`type T struct{ A int }`,
+		`:set printverb %v`,
+		`T{1}`,
+		`:set printverb %+v`,
+		`:set printverb`,
+		`T{2}`,
+		`:set printverb %#v`,
+		`T{3}`,
+		`:set printverb %d`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `{1}
+printverb = "%+v"
+{A:2}
+main.T{A:3}
+`, stdout.String())
+	assert.Equal(t, "set: invalid verb: \"%d\" (%v, %+v or %#v)\n", stderr.String())
+}
+
+func TestAction_Set_stringer(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import errors strings`,
+		`type D int`,
+		`func (d D) String() string { return strings.Repeat("*", int(d)) }`,
+		`type N struct{ s string }`,
+		`func (n *N) String() string { return n.s }`,
+		`:set stringer on`,
+		`D(3)`,
+		`errors.New("boom")`,
+		`var n *N`,
+		`n`,
+		`&N{"x"}`,
+		`:set printverb %#v`,
+		`&N{"x"}`,
+		`:set stringer`,
+	}
+
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err, code)
+	}
+
+	assert.Equal(t, `***
+boom
+<nil>
+x
+&main.N{s:"x"}
+stringer = "on"
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestAction_Set_maxoutput(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:set maxoutput 8B`,
+		`:set maxoutput`,
+		`"0123456789abcdef"`,
+		`:set maxoutput 1KB`,
+		`:set maxoutput`,
+		`"0123456789abcdef"`,
+		`:set maxoutput 1x`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `maxoutput = "8B"
+"0123456maxoutput = "1KB"
+"0123456789abcdef"
+`, stdout.String())
+	assert.Equal(t, "\n… 11 bytes omitted\nset: invalid size: \"1x\"\n", stderr.String())
+}
+
+func TestAction_Set_memlimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("limits are not supported on Windows")
+	}
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:set memlimit 512MB`,
+		`:set cpulimit 1s`,
+		`:set memlimit`,
+		`len(make([]byte, 1<<20))`,
+		`len(make([]byte, 1<<32))`,
+		`for {}`,
+		`:set memlimit ""`,
+		`:set cpulimit ""`,
+		`len(make([]byte, 1<<32))`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `memlimit = "512MB"
+1048576
+4294967296
+`, stdout.String())
+	assert.Contains(t, stderr.String(), "out of memory")
+	assert.Contains(t, stderr.String(), "signal: killed")
+}
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		value string
+		size  int64
+	}{
+		{"", 0},
+		{"0", 0},
+		{"100", 100},
+		{"64KB", 64 << 10},
+		{"64k", 64 << 10},
+		{"2 MB", 2 << 20},
+		{"1G", 1 << 30},
+		{"8589934591G", 8589934591 << 30},
+	}
+	for _, tc := range testCases {
+		size, err := parseSize(tc.value)
+		require.NoError(t, err, tc.value)
+		assert.Equal(t, tc.size, size, tc.value)
+	}
+	for _, value := range []string{"KB", "-1", "1.5MB", "1TB", "8589934592G", "9223372036854775808"} {
+		_, err := parseSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLimitedWriter(t *testing.T) {
+	var buf strings.Builder
+	l := &outputLimit{limit: 4}
+	w := l.writer(&buf)
+
+	for _, s := range []string{"", "ab", "", "cdef", ""} {
+		n, err := w.Write([]byte(s))
+		require.NoError(t, err)
+		assert.Equal(t, len(s), n)
+	}
+	assert.Equal(t, "abcd", buf.String())
+	assert.Equal(t, int64(2), l.omitted)
+}
+
+func TestColorWriter(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.capture.stderr.w = &colorWriter{w: &stderr, color: colorError}
+
+	result, _ := s.Eval(`:foo`)
+	assert.Equal(t, "command not found: foo\n", result.Error)
+	assert.Equal(t, colorError+"command not found: foo\n"+colorReset, stderr.String())
+}
+
+func TestAction_Set_race(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`func racy() int { n := 0; done := make(chan bool); go func() { n++; done <- true }(); n++; <-done; return n }`,
+		`racy()`,
+		`:set race on`,
+		`:set race`,
+		`racy()`,
+		`:env CGO_ENABLED=0`,
+		`:set race off`,
+		`:set race on`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `2
+race = "on"
+2
+`, stdout.String())
+	assert.Contains(t, stderr.String(), "WARNING: DATA RACE")
+	assert.True(t, strings.HasSuffix(stderr.String(), "set: the race detector requires cgo (CGO_ENABLED=1)\n"), stderr.String())
+	assert.False(t, s.run.race)
+}
+
+func TestAction_Set_deterministic(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:set deterministic on`,
+		`:import math/rand runtime`,
+		`now()`,
+		`rng.Intn(1000)`,
+		`rand.Intn(1000)`,
+		`runtime.GOMAXPROCS(0)`,
+		`map[string]int{"b": 2, "a": 1, "c": 3}`,
+		`:clear`,
+		`rng.Intn(1000)`,
+		`:set deterministic off`,
+		`now()`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+81
+81
+1
+map[string]int{"a":1, "b":2, "c":3}
+81
+`, stdout.String())
+	assert.Contains(t, stderr.String(), "undefined: now")
+	assert.False(t, s.run.deterministic)
+}
+
+func TestAction_Set_timeout(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import time`,
+		`:set timeout 1s`,
+		`:set timeout`,
+		`time.Sleep(time.Minute)`,
+		`1 + 2`,
+		`:set timeout 0`,
+		`:set timeout`,
+		`:set timeout 1m`,
+		`:set timeout foo`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `timeout = "1s"
+3
+timeout = ""
+`, stdout.String())
+	assert.Contains(t, stderr.String(), "timed out after 1s\n")
+	assert.True(t, strings.HasSuffix(stderr.String(), "set: invalid duration: \"foo\"\n"), stderr.String())
+	assert.Equal(t, time.Minute, s.run.timeout)
+}
+
+func TestAction_Set_wait(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import fmt time`,
+		`go func() { time.Sleep(10 * time.Millisecond); fmt.Println("done") }()`,
+		`:set wait 1s`,
+		`1 + 2`,
+		`:set wait`,
+		`:set wait 0`,
+		`3 + 4`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `3
+done
+wait = "1s"
+7
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestAction_Set_rerunDefers(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import fmt`,
+		`defer fmt.Println("bye")`,
+		`1 + 2`,
+		`:set rerun-defers off`,
+		`4 + 5`,
+		`6 + 7`,
+		`defer fmt.Println("bye")`,
+		`8 + 9`,
+		`:set rerun-defers`,
+	}
+
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, `bye
+3
+bye
+9
+bye
+13
+bye
+17
+rerun-defers = "off"
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestAction_Set_grouping(t *testing.T) {
+	t.Setenv("LC_ALL", "en_US.UTF-8")
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:set grouping on`,
+		`:set grouping`,
+		`1234567`,
+		`-1234`,
+		`uint64(1 << 40)`,
+		`uintptr(1 << 20)`,
+		`int8(-128)`,
+		`123`,
+		`"1234"`,
+		`:set grouping off`,
+		`1234567`,
+		`:set grouping maybe`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `grouping = "on"
+1,234,567
+-1,234
+1,099,511,627,776
+1,048,576
+-128
+123
+"1234"
+1234567
+`, stdout.String())
+	assert.Equal(t, "set: invalid boolean: \"maybe\"\n", stderr.String())
+}
+
+func TestAction_File(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import strings`,
+		`:file helper.go`,
+		`type greeter struct { name string }`,
+		`func (g greeter) greet() string { return "hello, " + strings.ToUpper(g.name) }`,
+		`func twice(n int) int { return n * 2 }`,
+		`:file`,
+		`:file main`,
+		`func twice(n int) int { return n * 3 }`,
+		`greeter{"gore"}.greet()`,
+		`twice(2)`,
+		`:file helper.go`,
+		`func broken() int { return "" }`,
+		`:file ../x.go`,
+		`:file`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `  main
+* helper.go
+"hello, GORE"
+6
+  main
+* helper.go
+`, stdout.String())
+	src, err := os.ReadFile(filepath.Join(s.tempDir, "helper.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(src), `"strings"`)
+	assert.Contains(t, string(src), "type greeter struct")
+	assert.NotContains(t, string(src), "twice")
+	assert.NotContains(t, string(src), "broken")
+	assert.Contains(t, stderr.String(), "file: invalid file name: \"../x.go\"\n")
+
+	// the declarations of the files are printed by :print and written by :write
+	source, err := s.userSource(false)
+	require.NoError(t, err)
+	assert.Contains(t, source, `"strings"`)
+	assert.Contains(t, source, "type greeter struct")
+	assert.Contains(t, source, "func twice(n int) int { return n * 3 }")
+}
@@ -2,8 +2,18 @@ package gore
 
 import (
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/types/typeutil"
 
 	"github.com/x-motemen/gore/gocode"
 )
@@ -51,14 +61,22 @@ func (s *Session) completeWord(line string, pos int) (string, []string, string)
 		return "", nil, ""
 	}
 
-	if !gocode.Available() {
-		return "", nil, ""
-	}
-
 	if strings.TrimSpace(line[:pos]) == "" {
+		if !gocode.Available() {
+			return "", nil, ""
+		}
 		return "", []string{line[:pos] + indent}, line[pos:]
 	}
 
+	// the signature of the function called is shown by the hint, keeping
+	// the line (by the empty candidate, to redraw the line under the hint)
+	if s.hint != nil {
+		if sig, ok := s.signatureAt(line, pos); ok {
+			s.hint(sig)
+			return line[:pos], []string{""}, line[pos:]
+		}
+	}
+
 	// code completion
 	pos, cands, err := s.completeCode(line, pos, true)
 	if err != nil {
@@ -66,16 +84,31 @@ func (s *Session) completeWord(line string, pos int) (string, []string, string)
 		return "", nil, ""
 	}
 
+	if s.hint != nil && len(cands) > 1 && len(cands) <= maxCandidateDocs {
+		if docs := s.memberDocs(line, pos, cands); docs != nil {
+			s.hint(formatMemberDocs(cands, docs))
+		}
+	}
+
 	return line[0:pos], cands, ""
 }
 
-// completeCode does code completion within the session using gocode.
+// completeCode does code completion within the session using gocode, or the
+// type information for the selectors of the values (see completeSelector).
 // in and pos specifies the current input and the cursor position (0 <= pos <= len(in)) respectively.
 // If exprMode is set to true, the completion is done as an expression (e.g. appends "(" to functions).
 // Return value keep specifies how many characters of in should be kept and candidates are what follow in[0:keep].
 func (s *Session) completeCode(in string, pos int, exprMode bool) (keep int, candidates []string, err error) {
 	s.clearQuickFix()
 
+	if keep, candidates, ok := s.completeSelector(in, pos, exprMode); ok {
+		return keep, candidates, nil
+	}
+	if !gocode.Available() {
+		keep, candidates = s.completeScope(in, pos, exprMode)
+		return keep, candidates, nil
+	}
+
 	source, err := s.source(false)
 	if err != nil {
 		return
@@ -86,10 +119,15 @@ func (s *Session) completeCode(in string, pos int, exprMode bool) (keep int, can
 	editingSource := source[0:p] + in + source[p:]
 	cursor := len(source[0:p]) + pos
 
-	result, err := gocode.Query([]byte(editingSource), cursor)
+	result, err := gocode.QueryEnv([]byte(editingSource), cursor, s.loadEnviron())
 	if err != nil {
 		return
 	}
+	if len(result.Candidates) == 0 {
+		// gocode may not know the syntax of the session (e.g. the type parameters)
+		keep, candidates = s.completeScope(in, pos, exprMode)
+		return keep, candidates, nil
+	}
 
 	keep = pos - result.Cursor
 	candidates = make([]string, 0, len(result.Candidates))
@@ -106,3 +144,297 @@ func (s *Session) completeCode(in string, pos int, exprMode bool) (keep int, can
 
 	return
 }
+
+// completeSelector completes the fields and the methods of the value before
+// the dot at the cursor (e.g. v.<TAB>, f().x.<TAB>), or the members of the
+// package (e.g. strings.<TAB>), by type checking the session with the
+// expression. The types declared in the session are not known by gocode,
+// which is used if ok is false (e.g. for the types).
+func (s *Session) completeSelector(in string, pos int, exprMode bool) (keep int, candidates []string, ok bool) {
+	keep = strings.LastIndexFunc(in[:pos], func(r rune) bool { return !isIdentRune(r) }) + 1
+	if keep == 0 || in[keep-1] != '.' {
+		return
+	}
+	prefix := in[keep:pos]
+	expr, info, pkg, found := s.typeOfExpr(in[selectorStart(in[:keep-1]) : keep-1])
+	if !found {
+		return
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		if pkgName, ok := info.Uses[ident].(*types.PkgName); ok {
+			return keep, packageMembers(pkgName.Imported(), prefix, exprMode), true
+		}
+	}
+	tv := info.Types[expr]
+	if tv.Type == nil || !tv.IsValue() {
+		return
+	}
+
+	// the names of the fields are collected from the embedded structs, and
+	// the promoted or ambiguous ones are resolved by LookupFieldOrMethod
+	var names []string
+	seen := make(map[types.Type]bool)
+	var collectFields func(types.Type)
+	collectFields = func(t types.Type) {
+		if p, ok := t.Underlying().(*types.Pointer); ok {
+			t = p.Elem()
+		}
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok || seen[t] {
+			return
+		}
+		seen[t] = true
+		for i := 0; i < st.NumFields(); i++ {
+			names = append(names, st.Field(i).Name())
+			if st.Field(i).Embedded() {
+				collectFields(st.Field(i).Type())
+			}
+		}
+	}
+	collectFields(tv.Type)
+	for _, sel := range typeutil.IntuitiveMethodSet(tv.Type, nil) {
+		names = append(names, sel.Obj().Name())
+	}
+
+	done := make(map[string]bool)
+	for _, name := range names {
+		if done[name] || !hasPrefixFold(name, prefix) || isGoreName(name) {
+			continue
+		}
+		done[name] = true
+		obj, _, _ := types.LookupFieldOrMethod(tv.Type, true, pkg, name)
+		if obj == nil || !obj.Exported() && obj.Pkg() != pkg {
+			continue
+		}
+		if _, isFunc := obj.(*types.Func); isFunc && exprMode {
+			name += "("
+		}
+		candidates = append(candidates, name)
+	}
+	sort.Strings(candidates)
+	return keep, candidates, true
+}
+
+// completeScope completes the identifier at the cursor by the names in the
+// scope of the end of main, by type checking the session, as gocode does
+// without the type information of the session.
+func (s *Session) completeScope(in string, pos int, exprMode bool) (keep int, candidates []string) {
+	keep = strings.LastIndexFunc(in[:pos], func(r rune) bool { return !isIdentRune(r) }) + 1
+	prefix := in[keep:pos]
+	if prefix == "" || keep > 0 && in[keep-1] == '.' {
+		return pos, nil
+	}
+	var mainFunc *ast.FuncDecl
+	for _, decl := range s.file.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body == s.mainBody {
+			mainFunc = fd
+		}
+	}
+	if mainFunc == nil {
+		return pos, nil
+	}
+	_, info := s.typeCheck()
+	done := make(map[string]bool)
+	for scope := info.Scopes[mainFunc.Type]; scope != nil; scope = scope.Parent() {
+		for _, name := range scope.Names() {
+			if done[name] || !hasPrefixFold(name, prefix) || isGoreName(name) || name == "main" {
+				continue
+			}
+			done[name] = true
+			switch scope.Lookup(name).(type) {
+			case *types.Func, *types.Builtin:
+				if exprMode {
+					name += "("
+				}
+			}
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return keep, candidates
+}
+
+// packageMembers returns the exported members of the package with the prefix.
+func packageMembers(pkg *types.Package, prefix string, exprMode bool) []string {
+	var candidates []string
+	for _, name := range pkg.Scope().Names() {
+		obj := pkg.Scope().Lookup(name)
+		if !obj.Exported() || !hasPrefixFold(name, prefix) {
+			continue
+		}
+		if _, isFunc := obj.(*types.Func); isFunc && exprMode {
+			name += "("
+		}
+		candidates = append(candidates, name)
+	}
+	return candidates
+}
+
+// hasPrefixFold reports whether the name begins with the prefix ignoring the
+// case, as gocode matches the candidates.
+func hasPrefixFold(name, prefix string) bool {
+	return len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix)
+}
+
+// typeOfExpr type checks the session with the expression of the source, as
+// the last statement of main, and returns the expression and the information
+// of the types. The packages imported by :import can be referred by it.
+func (s *Session) typeOfExpr(src string) (expr ast.Expr, info *types.Info, pkg *types.Package, ok bool) {
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		return
+	}
+	expandLastResult(expr, s.results.last)
+
+	// the packages imported by :import are blank until used
+	for _, imp := range s.file.Imports {
+		if imp.Name != nil && imp.Name.Name == "_" {
+			name := imp.Name
+			imp.Name = s.importName(imp)
+			defer func(imp *ast.ImportSpec) { imp.Name = name }(imp)
+		}
+	}
+
+	list := s.mainBody.List
+	defer func() { s.mainBody.List = list }()
+	s.mainBody.List = append(list[:len(list):len(list)], &ast.AssignStmt{
+		Lhs: []ast.Expr{ast.NewIdent("_")}, Tok: token.ASSIGN, Rhs: []ast.Expr{expr},
+	})
+	pkg, info = s.typeCheck()
+	return expr, info, pkg, true
+}
+
+// signatureAt returns the signature of the function called at the cursor,
+// where the argument is to be typed (e.g. strconv.ParseInt(<TAB>), as
+// "strconv.ParseInt(s string, base int, bitSize int) (i int64, err error)".
+func (s *Session) signatureAt(in string, pos int) (string, bool) {
+	open, depth := -1, 0
+	for i := pos - 1; i >= 0 && open < 0; i-- {
+		switch c := in[i]; {
+		case c == ')' || c == ']' || c == '}':
+			depth++
+		case c == '(' && depth == 0:
+			open = i
+		case c == '(' || c == '[' || c == '{':
+			if depth == 0 {
+				return "", false
+			}
+			depth--
+		case depth == 0 && i == pos-1 && isIdentRune(rune(c)):
+			// an argument is being typed, to be completed
+			return "", false
+		}
+	}
+	if open < 0 {
+		return "", false
+	}
+
+	src := strings.TrimSpace(in[selectorStart(in[:open]):open])
+	expr, info, pkg, ok := s.typeOfExpr(src)
+	if !ok {
+		return "", false
+	}
+	var typ types.Type
+	if tv := info.Types[expr]; tv.Type != nil && tv.IsValue() {
+		typ = tv.Type
+	} else if fn, ok := usedObject(info, expr).(*types.Func); ok {
+		// the generic function is not a value until instantiated, and its
+		// signature is shown with the type parameters (e.g. Map[T any](...))
+		typ = fn.Type()
+	} else {
+		return "", false
+	}
+	sig, ok := typ.Underlying().(*types.Signature)
+	if !ok {
+		return "", false
+	}
+	return src + strings.TrimPrefix(types.TypeString(sig, types.RelativeTo(pkg)), "func"), true
+}
+
+// usedObject returns the object referred to by the identifier or the
+// selector, or nil.
+func usedObject(info *types.Info, expr ast.Expr) types.Object {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return info.Uses[e]
+	case *ast.SelectorExpr:
+		return info.Uses[e.Sel]
+	}
+	return nil
+}
+
+// selectorStart returns the start of the operand of the selector at the end
+// of the input, skipping the brackets (e.g. xs[i].f(x)).
+func selectorStart(in string) int {
+	var depth int
+	for i := len(in) - 1; i >= 0; i-- {
+		switch c := in[i]; {
+		case c == ')' || c == ']' || c == '}':
+			depth++
+		case c == '(' || c == '[' || c == '{':
+			if depth == 0 {
+				return i + 1
+			}
+			depth--
+		case depth == 0 && c != '.' && c < utf8.RuneSelf && !isIdentRune(rune(c)):
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// completeFile completes the last word of the argument of the commands
+// taking a file (e.g. :write), as the path relative to the current directory.
+func completeFile(_ *Session, arg string) []string {
+	i := strings.LastIndexFunc(arg, unicode.IsSpace) + 1
+	result := completePath(arg[i:], ".", false)
+	for j := range result {
+		result[j] = arg[:i] + result[j]
+	}
+	return result
+}
+
+// completePath completes the path of the file or the directory, relative to
+// the directory unless it is absolute. The leading ~ is kept in the result,
+// and the directories are completed with the trailing separator.
+func completePath(prefix, base string, dirOnly bool) []string {
+	if prefix == "~" {
+		return []string{prefix + string(filepath.Separator)}
+	}
+	dir, name := filepath.Split(prefix)
+	path := expandHome(dir)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil
+	}
+	var result []string
+	for _, e := range entries {
+		// hidden files are completed only if asked
+		if strings.HasPrefix(e.Name(), ".") && !strings.HasPrefix(name, ".") {
+			continue
+		}
+		if !strings.HasPrefix(e.Name(), name) {
+			continue
+		}
+		isDir := e.IsDir()
+		if e.Type()&os.ModeSymlink != 0 {
+			if fi, err := os.Stat(filepath.Join(path, e.Name())); err == nil {
+				isDir = fi.IsDir()
+			}
+		}
+		if isDir {
+			result = append(result, dir+e.Name()+string(filepath.Separator))
+		} else if !dirOnly {
+			result = append(result, dir+e.Name())
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -1,6 +1,8 @@
 package gore
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -35,11 +37,42 @@ func TestSession_completeWord(t *testing.T) {
 	assert.Equal(t, []string{
 		" : :import ",
 		" : :type ",
+		" : :ast ",
+		" : :bench ",
+		" : :test ",
+		" : :prof ",
 		" : :print",
+		" : :file ",
 		" : :write ",
+		" : :save-session ",
+		" : :restore-session ",
+		" : :share",
+		" : :copy",
 		" : :clear",
 		" : :doc ",
-		" : :help",
+		" : :vars",
+		" : :funcs",
+		" : :types",
+		" : :methods ",
+		" : :imports",
+		" : :deps",
+		" : :mark ",
+		" : :goto ",
+		" : :drop ",
+		" : :checkpoint ",
+		" : :rollback ",
+		" : :cd ",
+		" : :pwd",
+		" : :env ",
+		" : :args ",
+		" : :cgo ",
+		" : :stdin ",
+		" : :tags ",
+		" : :history ",
+		" : :record ",
+		" : :replay ",
+		" : :set ",
+		" : :help ",
 		" : :quit",
 	}, cands)
 	assert.Equal(t, post, "")
@@ -97,6 +130,11 @@ func TestSession_completeWord(t *testing.T) {
 	assert.Equal(t, []string{":clear"}, cands)
 	assert.Equal(t, post, "")
 
+	pre, cands, post = s.completeWord(":help d", 7)
+	assert.Equal(t, ":help ", pre)
+	assert.Equal(t, []string{"doc"}, cands)
+	assert.Equal(t, post, "")
+
 	pre, cands, post = s.completeWord(" : : q", 6)
 	assert.Equal(t, "", pre)
 	assert.Equal(t, []string{" : : quit"}, cands)
@@ -115,3 +153,161 @@ func TestSession_completeWord(t *testing.T) {
 	assert.Equal(t, []string{" fmt"}, cands)
 	assert.Equal(t, post, "")
 }
+
+func TestSession_completeSelector(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`type point struct { x, y int }`,
+		`func (p *point) scale(n int) { p.x *= n; p.y *= n }`,
+		`type named struct { point; name string }`,
+		`v := named{name: "origin"}`,
+		`vs := []named{v}`,
+		`:import strings`,
+		`var b strings.Builder`,
+	}
+	for _, code := range codes {
+		_, err = s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	pre, cands, post := s.completeWord("v.", 2)
+	assert.Equal(t, "v.", pre)
+	assert.Equal(t, []string{"name", "point", "scale(", "x", "y"}, cands)
+	assert.Equal(t, "", post)
+
+	pre, cands, _ = s.completeWord("fmt.Println(vs[0].point.s", 25)
+	assert.Equal(t, "fmt.Println(vs[0].point.", pre)
+	assert.Equal(t, []string{"scale("}, cands)
+
+	_, cands, _ = s.completeWord("b.Wr", 4)
+	assert.Equal(t, []string{"Write(", "WriteByte(", "WriteRune(", "WriteString("}, cands)
+
+	pre, cands, _ = s.completeWord("strings.HasP", 12)
+	assert.Equal(t, "strings.", pre)
+	assert.Equal(t, []string{"HasPrefix("}, cands)
+
+	_, cands, _ = s.completeWord("strings.re", 10)
+	assert.Equal(t, []string{"Reader", "Repeat(", "Replace(", "ReplaceAll(", "Replacer"}, cands)
+
+	_, cands, ok := s.completeSelector("named.", 6, true)
+	assert.False(t, ok, "%v", cands)
+}
+
+func TestSession_signatureAt(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import strconv`,
+		`type point struct { x, y int }`,
+		`func (p point) add(q point) point { return point{p.x + q.x, p.y + q.y} }`,
+		`p := point{1, 2}`,
+		`func apply[T any](x T, f func(T) T) T { return f(x) }`,
+	}
+	for _, code := range codes {
+		_, err = s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	var hints []string
+	s.hint = func(hint string) { hints = append(hints, hint) }
+
+	pre, cands, post := s.completeWord("strconv.ParseInt()", 17)
+	assert.Equal(t, "strconv.ParseInt(", pre)
+	assert.Equal(t, []string{""}, cands)
+	assert.Equal(t, ")", post)
+
+	_, cands, _ = s.completeWord("x := p.add(point{3, 4}, ", 24)
+	assert.Equal(t, []string{""}, cands)
+
+	_, _, _ = s.completeWord("strconv.ParseInt(p", 18)
+	_, _, _ = s.completeWord("len(", 4)
+	_, _, _ = s.completeWord("(p", 1)
+	_, _, _ = s.completeWord("apply(", 6)
+
+	assert.Equal(t, []string{
+		"strconv.ParseInt(s string, base int, bitSize int) (i int64, err error)",
+		"p.add(q point) point",
+		"apply[T any](x T, f func(T) T) T",
+	}, hints)
+}
+
+func TestSession_completeScope(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`type List[T any] []T`,
+		`func Map[T, U any](xs []T, f func(T) U) []U { return nil }`,
+		`mine := List[int]{1}`,
+	}
+	for _, code := range codes {
+		_, err = s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	keep, cands := s.completeScope("x := Map", 8, true)
+	assert.Equal(t, 5, keep)
+	assert.Equal(t, []string{"Map("}, cands)
+
+	_, cands = s.completeScope("mine", 4, false)
+	assert.Equal(t, []string{"mine"}, cands)
+
+	_, cands = s.completeScope("L", 1, true)
+	assert.Equal(t, []string{"List", "len("}, cands)
+
+	_, cands = s.completeScope("mine.L", 6, true)
+	assert.Empty(t, cands)
+}
+
+func TestSession_memberDocs(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	_, err = s.Eval(`:import strings`)
+	require.NoError(t, err)
+
+	var hints []string
+	s.hint = func(hint string) { hints = append(hints, hint) }
+
+	_, cands, _ := s.completeWord("strings.Rep", 11)
+	assert.Equal(t, []string{"Repeat(", "Replace(", "ReplaceAll(", "Replacer"}, cands)
+	require.Len(t, hints, 1)
+	lines := strings.Split(hints[0], "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "Repeat(     — returns a new string consisting of count copies of the string s", lines[0])
+	assert.True(t, strings.HasPrefix(lines[3], "Replacer    — "), lines[3])
+
+	_, _, _ = s.completeWord("strings.", 8)
+	assert.Len(t, hints, 1)
+
+	docs := s.memberDocs("x := strings.Con", 13, []string{"Contains(", "foo"})
+	assert.Equal(t, []string{"reports whether substr is within s", ""}, docs)
+	assert.Nil(t, s.memberDocs("x.Con", 2, []string{"Contains("}))
+}
+
+func TestCompleteFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	for _, name := range []string{"script.go", "other.go", ".hidden"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	t.Setenv("HOME", dir)
+	sep := string(filepath.Separator)
+
+	assert.Equal(t, []string{dir + sep + "script.go", dir + sep + "sub" + sep}, completeFile(nil, dir+sep+"s"))
+	assert.Equal(t, []string{"2..3 ~/script.go", "2..3 ~/sub" + sep}, completeFile(nil, "2..3 ~/s"))
+	assert.Equal(t, []string{"~/.hidden"}, completeFile(nil, "~/."))
+	assert.Equal(t, []string{"~" + sep}, completeFile(nil, "~"))
+	assert.Equal(t, []string{"sub" + sep}, completeCd(&Session{workDir: dir}, ""))
+}
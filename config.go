@@ -0,0 +1,73 @@
+package gore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The config in the store (e.g. ~/.gore/config) has the settings applied on
+// starting a session, one by a line as :set takes them (e.g. floatfmt %.4g).
+// The empty lines and the lines starting with # are ignored.
+
+// autosaveName is the name of the session saved on quitting, to be restored
+// by :restore-session.
+const autosaveName = "autosave"
+
+// loadConfig applies the settings of the config in the store, reporting the
+// lines failed.
+func (s *Session) loadConfig() {
+	if s.store == nil {
+		return
+	}
+	data, err := s.store.Load(storeConfig)
+	if err != nil {
+		if !isNotExist(err) {
+			fmt.Fprintf(s.stderr, "config: %s\n", err)
+		}
+		return
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := s.applyConfig(line); err != nil {
+			fmt.Fprintf(s.stderr, "config:%d: %s\n", i+1, err)
+		}
+	}
+}
+
+func (s *Session) applyConfig(line string) error {
+	name, value, _ := strings.Cut(line, " ")
+	st, err := s.lookupSetting(name)
+	if err != nil {
+		return err
+	}
+	value = strings.TrimSpace(value)
+	if v, err := strconv.Unquote(value); err == nil {
+		value = v
+	}
+	return st.set(s, value)
+}
+
+// autosave saves the session to the store on quitting, if anything is input.
+func (s *Session) autosave() error {
+	if !s.autosaveEnabled || s.store == nil || s.inputNumber == 0 {
+		return nil
+	}
+	data, err := s.bundle()
+	if err != nil {
+		return err
+	}
+	return s.store.Save(sessionStoreName(autosaveName), data)
+}
+
+func setAutosave(s *Session, value string) error {
+	b, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.autosaveEnabled = b
+	return nil
+}
@@ -0,0 +1,61 @@
+package gore
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommands are the commands to put the standard input on the
+// clipboard, tried in order by the platforms.
+var clipboardCommands = map[string][][]string{
+	"darwin":  {{"pbcopy"}},
+	"windows": {{"clip.exe"}},
+	"linux": {
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+		{"clip.exe"}, // WSL
+	},
+}
+
+// clipboardCommand returns the first command of the platform available.
+func clipboardCommand() []string {
+	for _, args := range clipboardCommands[runtime.GOOS] {
+		if _, err := exec.LookPath(args[0]); err == nil {
+			return args
+		}
+	}
+	return nil
+}
+
+// actionCopy puts the gofmt-ed source of the session without the scaffolding
+// on the clipboard. The source is printed instead if no clipboard command is
+// available, e.g. on a remote machine.
+func actionCopy(s *Session, _ string) error {
+	src, err := s.formattedSource()
+	if err != nil {
+		return err
+	}
+
+	args := clipboardCommand()
+	if args == nil {
+		infof("No clipboard command found; printing the source")
+		_, err := s.stdout.Write(src)
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", args[0], msg)
+		}
+		return fmt.Errorf("%s: %s", args[0], err)
+	}
+	infof("Source copied to the clipboard")
+	return nil
+}
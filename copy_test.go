@@ -0,0 +1,46 @@
+package gore
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Copy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake clipboard command is a shell script")
+	}
+	clipboard := filepath.Join(t.TempDir(), "clipboard")
+	defer func(cmds [][]string) { clipboardCommands[runtime.GOOS] = cmds }(clipboardCommands[runtime.GOOS])
+	clipboardCommands[runtime.GOOS] = [][]string{{"gore-no-such-command"}, {"sh", "-c", "cat > " + clipboard}}
+
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	_, err = s.Eval(`x := 1 + 2`)
+	require.NoError(t, err)
+	_, err = s.Eval(`:copy`)
+	require.NoError(t, err)
+
+	src, err := os.ReadFile(clipboard)
+	require.NoError(t, err)
+	assert.Equal(t, `package main
+
+func main() {
+	x := 1 + 2
+}
+`, string(src))
+
+	stdout.Reset()
+	clipboardCommands[runtime.GOOS] = nil
+	_, err = s.Eval(`:copy`)
+	require.NoError(t, err)
+	assert.Equal(t, string(src), stdout.String())
+}
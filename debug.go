@@ -1,6 +1,3 @@
-//go:build debug
-// +build debug
-
 package gore
 
 import (
@@ -10,7 +7,15 @@ import (
 	"runtime"
 )
 
+// debugging tells whether the debug messages are printed, which is on by
+// the debug build tag or the Debug option.
+var debugging = debugBuild
+
 func debugf(format string, args ...any) {
+	if !debugging {
+		return
+	}
+
 	_, file, line, ok := runtime.Caller(1)
 
 	if ok {
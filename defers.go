@@ -0,0 +1,70 @@
+package gore
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// The defer statements in main run at the end of every run, as all the inputs
+// are run again. With :set rerun-defers off, they run at the end of the run of
+// the inputs only: the ones of the earlier inputs are written as the function
+// literals not called (e.g. _ = func() { f.Close() }) in the later runs, which
+// keep the variables used. They are told by the sources, as the statements are
+// parsed again by the quickfix.
+
+// deferState is the state of the defer statements run once.
+type deferState struct {
+	once    bool           // whether to run the defer statements once
+	ran     map[string]int // the numbers of the defer statements run, by the sources
+	written map[string]int // the numbers of the defer statements written to run
+}
+
+// skipRanDefers writes the defer statements run by the earlier inputs not to
+// run, returning the function to restore the code.
+func (s *Session) skipRanDefers() func() {
+	if !s.defers.once {
+		return func() {}
+	}
+	stmts := s.mainBody.List
+	list := make([]ast.Stmt, len(stmts))
+	copy(list, stmts)
+	written := make(map[string]int)
+	for i, stmt := range stmts {
+		stmt, ok := stmt.(*ast.DeferStmt)
+		if !ok {
+			continue
+		}
+		key := showNode(s.fset, stmt)
+		written[key]++
+		if written[key] <= s.defers.ran[key] {
+			list[i] = &ast.AssignStmt{
+				Lhs: []ast.Expr{ast.NewIdent("_")},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{&ast.FuncLit{
+					Type: &ast.FuncType{Params: &ast.FieldList{}},
+					Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: stmt.Call}}},
+				}},
+			}
+		}
+	}
+	s.defers.written = written
+	s.mainBody.List = list
+	return func() { s.mainBody.List = stmts }
+}
+
+// commitDefers remembers the defer statements run, if the program ran.
+func (s *Session) commitDefers(ok bool) {
+	if s.defers.once && ok {
+		s.defers.ran = s.defers.written
+	}
+	s.defers.written = nil
+}
+
+func setRerunDefers(s *Session, value string) error {
+	on, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.defers.once, s.defers.ran = !on, nil
+	return nil
+}
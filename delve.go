@@ -0,0 +1,118 @@
+package gore
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// The last statement is debugged by :debug with Delve, launched on the
+// program built without the optimizations and with the breakpoint set at
+// the statement. The statements of main are written in the lines of their
+// own, since the printer may write main in a line.
+const (
+	debugProgramName = "gore_debug"
+	debugInitName    = "gore_debug.dlv"
+)
+
+// actionDebug builds the program to debug, and runs dlv exec on it until
+// quitting the debugger.
+func actionDebug(s *Session, _ string) error {
+	if len(s.mainBody.List) == 0 {
+		return s.errorf("no statement to debug")
+	}
+	dlv, err := exec.LookPath("dlv")
+	if err != nil {
+		return s.errorf("command not found: %s", "dlv")
+	}
+
+	if err := s.writeSource(); err != nil {
+		return err
+	}
+	src, err := os.ReadFile(s.tempFilePath)
+	if err != nil {
+		return err
+	}
+	src, line, err := debugSource(src, len(s.mainBody.List))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.tempFilePath, src, 0o644); err != nil {
+		return err
+	}
+
+	name := debugProgramName
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	path := filepath.Join(s.tempDir, name)
+	args := append(append([]string{"build", "-o", path, "-gcflags=all=-N -l"}, s.runFlags()...), s.runFiles()...)
+	if err := s.goExec(args, s.stdout); err != nil {
+		debugf("debug :: err = %s", err)
+		return s.errorf("could not build the program")
+	}
+	defer os.Remove(path)
+
+	init := filepath.Join(s.tempDir, debugInitName)
+	commands := fmt.Sprintf("break %s:%d\ncontinue\n", s.tempFilePath, line)
+	if err := os.WriteFile(init, []byte(commands), 0o644); err != nil {
+		return err
+	}
+	defer os.Remove(init)
+
+	cmd := exec.Command(dlv, append([]string{"exec", path, "--init", init, "--"}, s.args...)...)
+	cmd.Dir = s.currentWorkDir()
+	cmd.Env = s.environ()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = s.stdin, s.stdout, s.stderr
+	restore := func() {}
+	if s.stdin != nil && s.terminal != nil {
+		restore = s.terminal()
+	}
+	defer restore()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	s.running.set(cmd.Process)
+	defer s.running.set(nil)
+	return cmd.Wait()
+}
+
+// debugSource rewrites the source to write the statements of main in the
+// lines of their own, and returns the line of the n-th statement.
+func debugSource(src []byte, n int) ([]byte, int, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	var body *ast.BlockStmt
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+			body = fn.Body
+		}
+	}
+	if body == nil || n < 1 || n > len(body.List) {
+		return nil, 0, fmt.Errorf("no such statement: %d", n)
+	}
+
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	var b bytes.Buffer
+	b.Write(src[:offset(body.Lbrace)+1])
+	var line int
+	for i, stmt := range body.List {
+		b.WriteString("\n\t")
+		if i == n-1 {
+			line = bytes.Count(b.Bytes(), []byte("\n")) + 1
+		}
+		b.Write(src[offset(stmt.Pos()):offset(stmt.End())])
+	}
+	b.WriteString("\n")
+	b.Write(src[offset(body.Rbrace):])
+	return b.Bytes(), line, nil
+}
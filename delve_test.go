@@ -0,0 +1,38 @@
+package gore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugSource(t *testing.T) {
+	src := `package main
+
+import "fmt"
+
+func main()	{ x := 1; _, _ = fmt.Println(x); if x > 0 {
+	x++
+}; _, _ = fmt.Println(x) }
+`
+	out, line, err := debugSource([]byte(src), 3)
+	require.NoError(t, err)
+	assert.Equal(t, `package main
+
+import "fmt"
+
+func main()	{
+	x := 1
+	_, _ = fmt.Println(x)
+	if x > 0 {
+	x++
+}
+	_, _ = fmt.Println(x)
+}
+`, string(out))
+	assert.Equal(t, 8, line)
+
+	_, _, err = debugSource([]byte(src), 5)
+	assert.EqualError(t, err, "no such statement: 5")
+}
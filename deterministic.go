@@ -0,0 +1,95 @@
+package gore
+
+import (
+	"go/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The deterministic mode makes the runs reproducible for the transcripts: an
+// extra file declares now() returning a fixed time and rng, the source of
+// random numbers seeded by a fixed value, and seeds math/rand. The program
+// runs on one processor, and the results are printed by fmt, which sorts the
+// keys of the maps. The file is type checked with the session, to complete
+// and fix the code using the helpers.
+const deterministicFileName = "gore_deterministic.go"
+
+const deterministicSource = `package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// now returns the fixed time of the deterministic mode, instead of time.Now.
+func now() time.Time {
+	return time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// rng is the source of the random numbers seeded by the deterministic mode.
+var rng = rand.New(rand.NewSource(1))
+
+func init() {
+	rand.Seed(1)
+}
+`
+
+func setDeterministic(s *Session, value string) error {
+	on, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	if on == s.run.deterministic {
+		return nil
+	}
+	s.run.deterministic = on
+	if on {
+		if err := s.addDeterministicFile(); err != nil {
+			s.run.deterministic = false
+			return err
+		}
+	} else {
+		path := filepath.Join(s.tempDir, deterministicFileName)
+		for i, p := range s.extraFilePaths {
+			if p == path {
+				s.extraFilePaths = append(s.extraFilePaths[:i:i], s.extraFilePaths[i+1:]...)
+				s.extraFiles = append(s.extraFiles[:i:i], s.extraFiles[i+1:]...)
+				break
+			}
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return s.updatePrinter()
+}
+
+// addDeterministicFile writes the file of the helpers of the deterministic
+// mode and adds it to the session.
+func (s *Session) addDeterministicFile() error {
+	path := filepath.Join(s.tempDir, deterministicFileName)
+	if err := os.WriteFile(path, []byte(deterministicSource), 0o644); err != nil {
+		return err
+	}
+	f, err := parser.ParseFile(s.fset, path, deterministicSource, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	s.extraFilePaths = append(s.extraFilePaths, path)
+	s.extraFiles = append(s.extraFiles, f)
+	return nil
+}
+
+// withDeterministicEnv adds the environment of the deterministic mode to env:
+// one processor, and randseednop=0 as the global Seed of math/rand is a no-op
+// since Go 1.24 without it. The GODEBUG settings given are kept.
+func withDeterministicEnv(env []string) []string {
+	godebug := "randseednop=0"
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, "GODEBUG="); ok && value != "" {
+			godebug = value + "," + godebug
+		}
+	}
+	return append(env[:len(env):len(env)], "GOMAXPROCS=1", "GODEBUG="+godebug)
+}
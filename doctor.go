@@ -0,0 +1,100 @@
+package gore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/x-motemen/gore/gocode"
+)
+
+// doctorCheck is a check of the environment gore depends on.
+type doctorCheck struct {
+	name string
+	// check returns the description, and whether it is a warning or a failure
+	check func() (desc string, warning, failure bool)
+}
+
+var doctorChecks = []doctorCheck{
+	{"go", checkGoCommand},
+	{"build cache", checkBuildCache},
+	{"printer", checkPrinter},
+	{"completion", checkGocode},
+	{"debugger", checkDelve},
+	{"home", checkHome},
+}
+
+func checkGoCommand() (string, bool, bool) {
+	path, err := exec.LookPath("go")
+	if err != nil {
+		return "the go command is not found in PATH", false, true
+	}
+	out, err := exec.Command(path, "env", "GOVERSION").Output()
+	if err != nil {
+		return fmt.Sprintf("%s: %s", path, err), false, true
+	}
+	return fmt.Sprintf("%s (%s)", strings.TrimSpace(string(out)), path), false, false
+}
+
+func checkBuildCache() (string, bool, bool) {
+	if problem := goCacheProblem(nil); problem != "" {
+		return problem + "; evaluations will be slow", true, false
+	}
+	return "available", false, false
+}
+
+func checkPrinter() (string, bool, bool) {
+	pp := printerPkgs[0]
+	if lookupGoModule(pp.path, pp.version) {
+		return fmt.Sprintf("%s@%s", pp.path, pp.version), false, false
+	}
+	if canAccessGoproxy() {
+		return fmt.Sprintf("%s@%s will be downloaded", pp.path, pp.version), false, false
+	}
+	return fmt.Sprintf("%s is not available; values are printed by fmt", pp.path), true, false
+}
+
+func checkGocode() (string, bool, bool) {
+	if !gocode.Available() {
+		return "gocode is not found; install github.com/mdempsky/gocode to complete code", true, false
+	}
+	return "gocode", false, false
+}
+
+func checkDelve() (string, bool, bool) {
+	path, err := exec.LookPath("dlv")
+	if err != nil {
+		return "dlv is not found; install github.com/go-delve/delve/cmd/dlv to :debug", true, false
+	}
+	return path, false, false
+}
+
+func checkHome() (string, bool, bool) {
+	dir, err := homeDir()
+	if err != nil {
+		return fmt.Sprintf("%s; the history is not saved", err), true, false
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Sprintf("%s; the history is not saved", err), true, false
+	}
+	return dir, false, false
+}
+
+// Doctor checks the environment gore depends on, and writes the results to w.
+// It reports false if gore cannot work in the environment.
+func Doctor(w io.Writer) bool {
+	ok := true
+	for _, c := range doctorChecks {
+		desc, warning, failure := c.check()
+		status := "ok"
+		if failure {
+			status, ok = "fail", false
+		} else if warning {
+			status = "warn"
+		}
+		fmt.Fprintf(w, "%-4s  %s: %s\n", status, c.name, desc)
+	}
+	return ok
+}
@@ -0,0 +1,139 @@
+package gore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Driver feeds scripted lines to a session as typed in the REPL, without a
+// terminal, and captures the outputs of each input. It is meant for testing
+// the tools embedding gore, and the features of gore, deterministically.
+type Driver struct {
+	session        *Session
+	liner          *contLiner // keeps the input and the prompt, not reading a terminal
+	prompt         string     // prompt of the first line of the input
+	stdout, stderr strings.Builder
+}
+
+// Step is an input evaluated by the Driver.
+type Step struct {
+	// Prompt is the prompt shown for the first line of the input.
+	Prompt string
+	// Input is the lines of the input.
+	Input string
+	Result
+	// Err is the error of the evaluation, e.g. ErrQuit or ErrCmdRun.
+	Err error
+}
+
+// NewDriver creates a Driver with a new session configured by the options.
+// The programs evaluated read no standard input.
+func NewDriver(opts ...Option) (*Driver, error) {
+	g := New(opts...)
+	d := &Driver{liner: &contLiner{a11y: g.a11y}}
+	s, err := g.newSession(&d.stdout, &d.stderr)
+	if err != nil {
+		s.Clear()
+		return nil, err
+	}
+	s.stdin = nil
+	d.session = s
+	g.newSessionGroup(s, &d.stdout, &d.stderr)
+	return d, nil
+}
+
+// Session returns the session driven, switched by :session.
+func (d *Driver) Session() *Session {
+	return d.session
+}
+
+// Prompt returns the prompt shown for the next line.
+func (d *Driver) Prompt() string {
+	d.liner.number = 0
+	if d.session.numberedPrompt {
+		d.liner.number = d.session.inputNumber + 1
+	}
+	d.liner.platform = d.session.platform()
+	d.liner.session = d.session.group.promptName()
+	return d.liner.promptString()
+}
+
+// Feed feeds a line, and returns the Step if the line completes an input,
+// or nil if the input continues or the line is empty.
+func (d *Driver) Feed(line string) *Step {
+	prompt := d.Prompt()
+	if d.liner.buffer == "" {
+		if line == "" {
+			return nil
+		}
+		d.prompt = prompt
+		d.liner.buffer = line
+	} else if d.liner.continueLine(line, nil) {
+		if d.liner.buffer == "" {
+			return nil
+		}
+	} else {
+		d.liner.buffer += "\n" + line
+	}
+	in := d.liner.buffer
+	d.stdout.Reset()
+	d.stderr.Reset()
+
+	// the prompt is not redrawn by Reindent as there is no terminal
+	if d.liner.depth = d.liner.countDepth(); d.liner.depth < 0 {
+		fmt.Fprintf(&d.stderr, "error: %s\n", errUnmatchedBraces)
+		d.liner.Clear()
+		return d.step(in, errUnmatchedBraces)
+	}
+
+	_, err := d.session.Eval(in)
+	d.session = d.session.group.current
+	switch err {
+	case ErrContinue:
+		return nil
+	case nil, ErrCmdRun, ErrQuit:
+		d.liner.buffer = ""
+	default:
+		d.liner.Clear()
+	}
+	return d.step(in, err)
+}
+
+func (d *Driver) step(in string, err error) *Step {
+	source, serr := d.session.userSource(false)
+	if serr != nil {
+		source = ""
+	}
+	return &Step{
+		Prompt: d.prompt,
+		Input:  in,
+		Result: Result{Output: d.stdout.String(), Error: d.stderr.String(), Source: source},
+		Err:    err,
+	}
+}
+
+// Run feeds the lines and returns the Steps of the inputs completed, until
+// the session quits. The input not completed by the lines is left pending.
+func (d *Driver) Run(lines ...string) []Step {
+	var steps []Step
+	for _, line := range lines {
+		if step := d.Feed(line); step != nil {
+			steps = append(steps, *step)
+			if step.Err == ErrQuit {
+				break
+			}
+		}
+	}
+	return steps
+}
+
+// Close clears the sessions.
+func (d *Driver) Close() error {
+	var err error
+	for _, s := range d.session.group.sessions {
+		if e := s.Clear(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
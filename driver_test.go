@@ -0,0 +1,162 @@
+package gore
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriver(t *testing.T) {
+	d, err := NewDriver()
+	require.NoError(t, err)
+	t.Cleanup(func() { d.Close() })
+
+	steps := d.Run(
+		`:set numbered on`,
+		`x := 3`,
+		``,
+		`func f(n int) int {`,
+		`return n * 2`,
+		`}`,
+		`f(x)`,
+		`y`,
+		`}`,
+		`:quit`,
+		`x`,
+	)
+	require.Len(t, steps, 7)
+
+	assert.Equal(t, ":= ", steps[0].Prompt)
+	assert.Equal(t, "[2] := ", steps[1].Prompt)
+	assert.Equal(t, "func f(n int) int {\nreturn n * 2\n}", steps[2].Input)
+	assert.Equal(t, "[4] := ", steps[3].Prompt)
+	assert.Equal(t, "6\n", steps[3].Output)
+	assert.Contains(t, steps[3].Source, "x := 3")
+	assert.Equal(t, "undefined: y\n", steps[4].Error)
+	assert.Equal(t, ErrCmdRun, steps[4].Err)
+	assert.Equal(t, "error: unmatched braces\n", steps[5].Error)
+	assert.Equal(t, errUnmatchedBraces, steps[5].Err)
+	assert.Equal(t, ErrQuit, steps[6].Err)
+}
+
+func TestDriver_Prompt(t *testing.T) {
+	d, err := NewDriver(A11y(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { d.Close() })
+
+	assert.Equal(t, promptA11y, d.Prompt())
+	assert.Nil(t, d.Feed(`if true {`))
+	assert.Equal(t, promptA11yContinue, d.Prompt())
+	step := d.Feed(`}`)
+	require.NotNil(t, step)
+	assert.Equal(t, promptA11y, step.Prompt)
+	assert.NoError(t, step.Err)
+}
+
+func TestDriver_Continuation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the editor is a shell script")
+	}
+	editor := filepath.Join(t.TempDir(), "editor")
+	require.NoError(t, os.WriteFile(editor, []byte("#!/bin/sh\nprintf 'func g(n int) int {\\n\\treturn n * 3\\n}\\n' > \"$1\"\n"), 0o700))
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	d, err := NewDriver()
+	require.NoError(t, err)
+	t.Cleanup(func() { d.Close() })
+
+	steps := d.Run(
+		`func f(n int) int {`,
+		`return n *`,
+		`:cancel`,
+		`func g(n int) int {`,
+		`return n *`,
+		`:edit`,
+		`g(2)`,
+	)
+	require.Len(t, steps, 2)
+
+	assert.Equal(t, "func g(n int) int {\n\treturn n * 3\n}", steps[0].Input)
+	assert.NoError(t, steps[0].Err)
+	assert.Equal(t, "6\n", steps[1].Output)
+	assert.NotContains(t, steps[1].Source, "func f")
+}
+
+func TestDriver_Platform(t *testing.T) {
+	goos, symbol := "windows", "syscall.GetCommandLine"
+	if runtime.GOOS == goos {
+		goos, symbol = "linux", "syscall.Getpgrp"
+	}
+
+	d, err := NewDriver()
+	require.NoError(t, err)
+	t.Cleanup(func() { d.Close() })
+
+	steps := d.Run(
+		`:set goos `+goos,
+		`:set goarch amd64`,
+		`:import syscall`,
+		`:type `+symbol,
+		`:set goos plan10`,
+		`:set goos ""`,
+		`:set goarch ""`,
+		`1`,
+	)
+	require.Len(t, steps, 8)
+
+	prompt := "(" + goos + "/amd64) := "
+	if runtime.GOARCH != "amd64" {
+		assert.Equal(t, "("+goos+"/"+runtime.GOARCH+") := ", steps[1].Prompt)
+	} else {
+		assert.Equal(t, prompt, steps[1].Prompt)
+	}
+	assert.Equal(t, prompt, steps[2].Prompt)
+	assert.NoError(t, steps[3].Err)
+	assert.Contains(t, steps[3].Output, "func()")
+	assert.Equal(t, "set: unknown GOOS: plan10\n", steps[4].Error)
+	assert.Equal(t, ":= ", steps[7].Prompt)
+	assert.Equal(t, "1\n", steps[7].Output)
+}
+
+func TestDriver_Session(t *testing.T) {
+	d, err := NewDriver()
+	require.NoError(t, err)
+	t.Cleanup(func() { d.Close() })
+
+	first := d.Session()
+	steps := d.Run(
+		`x := 3`,
+		`:set numbered on`,
+		`:session new scratch2`,
+		`:session new scratch2`,
+		`:session list`,
+		`:session switch scratch2`,
+		`x`,
+		`x := "two"`,
+		`:session list`,
+		`:session switch default`,
+		`x`,
+		`:session switch nothing`,
+		`:session drop scratch2`,
+	)
+	require.Len(t, steps, 13)
+
+	assert.Equal(t, "", steps[2].Error)
+	assert.Equal(t, "session: session already exists: scratch2\n", steps[3].Error)
+	assert.Equal(t, "  * default     1 statements\n    scratch2    0 statements\n", steps[4].Output)
+	assert.Equal(t, "[6] default: := ", steps[5].Prompt)
+	assert.Equal(t, "scratch2: := ", steps[6].Prompt)
+	assert.Equal(t, "undefined: x\n", steps[6].Error)
+	assert.Equal(t, "    default     1 statements\n  * scratch2    1 statements\n", steps[8].Output)
+	assert.Equal(t, "[7] default: := ", steps[10].Prompt)
+	assert.Equal(t, "3\n", steps[10].Output)
+	assert.Equal(t, "session: no such session: nothing\n", steps[11].Error)
+	assert.Equal(t, "session: unknown subcommand: drop\n", steps[12].Error)
+	assert.Same(t, first, d.Session())
+	assert.Len(t, first.group.sessions, 2)
+}
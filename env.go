@@ -0,0 +1,187 @@
+package gore
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// envOverride is an environment variable set or unset by :env.
+type envOverride struct {
+	value string
+	unset bool
+}
+
+// environ returns the environment for the evaluated code,
+// or nil to inherit the environment of gore. The variables set by :env
+// take precedence over the gocache setting and the build context.
+func (s *Session) environ() []string {
+	return s.mergeEnviron(s.contextEnv(false))
+}
+
+// loadEnviron returns the environment for loading the packages to type
+// check the code, which also targets GOOS and GOARCH of the build context.
+func (s *Session) loadEnviron() []string {
+	return s.mergeEnviron(s.withGoEnv(s.contextEnv(true)))
+}
+
+// goEnviron returns the environment for the go command building the code,
+// with the variables of the settings of the go command (e.g. goproxy), which
+// are not given to the evaluated code.
+func (s *Session) goEnviron() []string {
+	return s.mergeEnviron(s.withGoEnv(s.contextEnv(false)))
+}
+
+// withGoEnv adds the variables of the settings of the go command to the
+// overrides.
+func (s *Session) withGoEnv(overrides map[string]envOverride) map[string]envOverride {
+	for key, value := range s.goEnv {
+		overrides[key] = envOverride{value: value}
+	}
+	return overrides
+}
+
+// goEnvSetting returns the setting of the variable of the go command.
+func goEnvSetting(key, document string) setting {
+	return setting{
+		name: strings.ToLower(key),
+		set: func(s *Session, value string) error {
+			if value == "" {
+				delete(s.goEnv, key)
+				return nil
+			}
+			if s.goEnv == nil {
+				s.goEnv = map[string]string{}
+			}
+			s.goEnv[key] = value
+			return nil
+		},
+		get:      func(s *Session) string { return s.goEnv[key] },
+		document: document,
+	}
+}
+
+// contextEnv returns the variables of the build context differing from
+// build.Default. GOOS and GOARCH are included only for the target, since
+// the compiled program runs on this machine (or in the container of linux
+// if sandboxed, built without cgo). The module cache stays in the
+// default GOPATH not to download the modules again.
+func (s *Session) contextEnv(target bool) map[string]envOverride {
+	overrides := map[string]envOverride{}
+	set := func(key, value, def string) {
+		if value != def {
+			overrides[key] = envOverride{value: value}
+		}
+	}
+	set("GOPATH", s.buildContext.GOPATH, build.Default.GOPATH)
+	if _, ok := overrides["GOPATH"]; ok && os.Getenv("GOMODCACHE") == "" {
+		gopath := filepath.SplitList(build.Default.GOPATH)
+		if len(gopath) > 0 {
+			overrides["GOMODCACHE"] = envOverride{value: filepath.Join(gopath[0], "pkg", "mod")}
+		}
+	}
+	set("GOROOT", s.buildContext.GOROOT, build.Default.GOROOT)
+	if target {
+		set("GOOS", s.buildContext.GOOS, build.Default.GOOS)
+		set("GOARCH", s.buildContext.GOARCH, build.Default.GOARCH)
+	} else if s.sandbox.kind != "" {
+		// the program runs in the container of linux
+		overrides["GOOS"] = envOverride{value: "linux"}
+		overrides["CGO_ENABLED"] = envOverride{value: "0"}
+	}
+	return overrides
+}
+
+func (s *Session) mergeEnviron(overrides map[string]envOverride) []string {
+	if len(s.env) == 0 && s.cache.dir == "" && len(overrides) == 0 {
+		return nil
+	}
+	if s.cache.dir != "" {
+		overrides["GOCACHE"] = envOverride{value: s.cache.dir}
+	}
+	for key, o := range s.env {
+		overrides[key] = o
+	}
+	var env []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := overrides[key]; !ok {
+			env = append(env, kv)
+		}
+	}
+	for key, o := range overrides {
+		if !o.unset {
+			env = append(env, key+"="+o.value)
+		}
+	}
+	return env
+}
+
+func actionEnv(s *Session, arg string) error {
+	if arg == "" {
+		keys := make([]string, 0, len(s.env))
+		for key := range s.env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if o := s.env[key]; o.unset {
+				fmt.Fprintf(s.stdout, "-u %s\n", key)
+			} else {
+				fmt.Fprintf(s.stdout, "%s=%s\n", key, o.value)
+			}
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(arg, "-u ") {
+		key := strings.TrimSpace(strings.TrimPrefix(arg, "-u "))
+		if !isEnvName(key) {
+			return s.errorf("invalid name: %q", key)
+		}
+		s.env[key] = envOverride{unset: true}
+		return nil
+	}
+
+	key, value, ok := strings.Cut(arg, "=")
+	if !ok {
+		return s.errorf("invalid argument: %s (KEY=VALUE or -u KEY)", arg)
+	}
+	if !isEnvName(key) {
+		return s.errorf("invalid name: %q", key)
+	}
+	s.env[key] = envOverride{value: value}
+	return nil
+}
+
+func completeEnv(s *Session, prefix string) []string {
+	// complete the names to set with "=", and to unset as is
+	opt, suffix := "", "="
+	if strings.HasPrefix(prefix, "-u ") {
+		opt, suffix = "-u ", ""
+		prefix = strings.TrimPrefix(prefix, opt)
+	}
+	keys := map[string]bool{}
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		keys[key] = true
+	}
+	for key := range s.env {
+		keys[key] = true
+	}
+	var result []string
+	for key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			result = append(result, opt+key+suffix)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func isEnvName(key string) bool {
+	return key != "" && !strings.ContainsAny(key, "= \t\x00")
+}
@@ -8,12 +8,19 @@ import (
 )
 
 func newErrFilter(w io.Writer) io.WriteCloser {
-	return transform.NewWriter(w, &errTransformer{})
+	return newLineFilter(w, replaceErrMsg)
 }
 
-type errTransformer struct{}
+// newLineFilter returns the writer to w replacing each line by replace.
+func newLineFilter(w io.Writer, replace func([]byte) []byte) io.WriteCloser {
+	return transform.NewWriter(w, &lineTransformer{replace: replace})
+}
+
+type lineTransformer struct {
+	replace func([]byte) []byte
+}
 
-func (*errTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+func (t *lineTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
 	var i int
 	for {
 		if atEOF {
@@ -26,7 +33,7 @@ func (*errTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, e
 				break
 			}
 		}
-		res := replaceErrMsg(src[:i+1])
+		res := t.replace(src[:i+1])
 		if nDst+len(res) > len(dst) {
 			err = transform.ErrShortDst
 			break
@@ -41,7 +48,7 @@ func (*errTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, e
 	return
 }
 
-func (*errTransformer) Reset() {}
+func (*lineTransformer) Reset() {}
 
 func replaceErrMsg(p []byte) []byte {
 	if bytes.HasPrefix(p, []byte("# command-line-arguments")) {
@@ -53,7 +60,7 @@ func replaceErrMsg(p []byte) []byte {
 	if bytes.HasPrefix(p, []byte(`warning: pattern "all" matched no module dependencies`)) {
 		return nil
 	}
-	if i := bytes.Index(p, []byte("gore_session.go")); i >= 0 {
+	if i := sessionFileIndex(p); i >= 0 {
 		if j := bytes.IndexRune(p[i:], ' '); j >= 0 {
 			return p[i+j+1:]
 		}
@@ -61,3 +68,12 @@ func replaceErrMsg(p []byte) []byte {
 	}
 	return p
 }
+
+// sessionFileIndex returns the index of the name of the session file (or
+// the file of :test) in the message, or -1 if not found.
+func sessionFileIndex(p []byte) int {
+	if i := bytes.Index(p, []byte("gore_session.go")); i >= 0 {
+		return i
+	}
+	return bytes.Index(p, []byte(testFileName))
+}
@@ -31,6 +31,11 @@ func TestErrFilter(t *testing.T) {
 			"/tmp/gore_session.go:10:24: undefined: foo",
 			"undefined: foo",
 		},
+		{
+			"gore_session_test.go",
+			"./gore_session_test.go:12:5: undefined: foo",
+			"undefined: foo",
+		},
 		{
 			"command-line-arguments and gore_session.go",
 			"# command-line-arguments foo\n/tmp/gore_session.go:10:24: undefined: foo",
@@ -0,0 +1,138 @@
+package gore
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	exitFuncName   = "__gore_exit"
+	exitStmtName   = "__gore_stmt"
+	exitFileName   = "gore_exit.go"
+	exitReportName = "gore_exit"
+)
+
+// The exit by os.Exit is reported by __gore_exit declared in an extra file,
+// which writes the code and the statement running to the report file in the
+// temporary directory, not to be confused with the outputs of the program.
+const exitSourceTemplate = `package main
+
+import (
+	"os"
+	"strconv"
+)
+
+var __gore_stmt int
+
+func __gore_exit(code int) int {
+	os.WriteFile(%q, []byte(strconv.Itoa(code)+" "+strconv.Itoa(__gore_stmt)), 0o644)
+	return code
+}
+`
+
+// exitState is the state of the exits of the program.
+type exitState struct {
+	last   *exitInfo // the exit of the last run by os.Exit, or nil
+	status int       // the exit status of the program of the last run, or -1 if not run
+	keep   bool      // whether to keep the input calling os.Exit
+	file   bool      // whether the extra file declaring __gore_exit is written
+}
+
+// exitInfo tells that the program exited by os.Exit.
+type exitInfo struct {
+	code int
+	stmt int // the statement number in main, or 0 if outside main
+}
+
+// exitStatus returns the exit status of the program of the last run, or
+// ExitCodeCompile if the program did not run (e.g. compile errors).
+func (s *Session) exitStatus() int {
+	if s.exit.last != nil {
+		return s.exit.last.code
+	}
+	if s.exit.status >= 0 {
+		return s.exit.status
+	}
+	return ExitCodeCompile
+}
+
+// instrumentExits rewrites os.Exit(code) to os.Exit(__gore_exit(code)),
+// which reports the code and the statement running, and makes the main body
+// keep track of the statement. It returns the function to restore the code,
+// which must be called after the source is printed.
+func (s *Session) instrumentExits() (func(), error) {
+	var osName string
+	for _, imp := range s.file.Imports {
+		if imp.Path.Value == strconv.Quote("os") {
+			osName = "os"
+			if imp.Name != nil {
+				osName = imp.Name.Name
+			}
+		}
+	}
+	if osName == "" || osName == "_" || osName == "." {
+		return func() {}, nil
+	}
+
+	var calls []*ast.CallExpr
+	ast.Inspect(s.file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 {
+			return true
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Exit" && isNamedIdent(sel.X, osName) {
+			calls = append(calls, call)
+		}
+		return true
+	})
+	if len(calls) == 0 {
+		return func() {}, nil
+	}
+	if !s.exit.file {
+		src := fmt.Sprintf(exitSourceTemplate, filepath.Join(s.tempDir, exitReportName))
+		if err := os.WriteFile(filepath.Join(s.tempDir, exitFileName), []byte(src), 0o644); err != nil {
+			return nil, err
+		}
+		s.exit.file = true
+	}
+
+	for _, call := range calls {
+		call.Args[0] = &ast.CallExpr{Fun: ast.NewIdent(exitFuncName), Args: []ast.Expr{call.Args[0]}}
+	}
+
+	stmts := s.mainBody.List
+	list := make([]ast.Stmt, 0, len(stmts)*2)
+	for i, stmt := range stmts {
+		list = append(list, &ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(exitStmtName)},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(i + 1)}},
+		}, stmt)
+	}
+	s.mainBody.List = list
+
+	return func() {
+		for _, call := range calls {
+			call.Args[0] = call.Args[0].(*ast.CallExpr).Args[0]
+		}
+		s.mainBody.List = stmts
+	}, nil
+}
+
+// readExit returns the exit reported by __gore_exit, or nil if not exited
+// by os.Exit.
+func (s *Session) readExit() *exitInfo {
+	b, err := os.ReadFile(filepath.Join(s.tempDir, exitReportName))
+	if err != nil {
+		return nil
+	}
+	var exit exitInfo
+	if _, err := fmt.Sscanf(string(b), "%d %d", &exit.code, &exit.stmt); err != nil {
+		return nil
+	}
+	return &exit
+}
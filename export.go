@@ -0,0 +1,293 @@
+package gore
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// exportSource returns the source of the statements in the range (inclusive)
+// with the declarations and the imports they depend on.
+func (s *Session) exportSource(from, to int) (string, error) {
+	stmts := s.foldBlankCalls(s.foldResults(s.mainBody.List[from-1 : to]))
+	decls := s.userDecls()
+
+	used := map[string]bool{}
+	collectIdents(used, &ast.BlockStmt{List: stmts})
+
+	// add the declarations until no more names are found
+	needed := make([]bool, len(decls))
+	for changed := true; changed; {
+		changed = false
+		for i, decl := range decls {
+			if needed[i] || !declaresAny(decl, used) {
+				continue
+			}
+			needed[i], changed = true, true
+			collectIdents(used, decl)
+		}
+	}
+
+	var out []ast.Decl
+	for i, decl := range decls {
+		if needed[i] {
+			out = append(out, decl)
+		}
+	}
+	return s.renderProgram(out, stmts, used)
+}
+
+// userSource returns the source of the session without the scaffolding for
+// the evaluation (the printer, the blank assignments of the printed values
+// and the imports not used), to be shown to or exported for the user.
+func (s *Session) userSource(space bool) (string, error) {
+	stmts := s.mainBody.List
+	names := make([]*ast.Ident, len(s.file.Imports))
+	for i, imp := range s.file.Imports {
+		names[i] = imp.Name
+	}
+	defer func() {
+		s.mainBody.List = stmts
+		for i, imp := range s.file.Imports {
+			imp.Name = names[i]
+		}
+	}()
+
+	// clear the quickfix on the copy of the statements
+	s.mainBody.List = append([]ast.Stmt(nil), stmts...)
+	s.clearQuickFix()
+	body := s.foldBlankCalls(s.foldResults(s.mainBody.List))
+
+	decls := s.userDecls()
+	used := map[string]bool{}
+	collectIdents(used, &ast.BlockStmt{List: body})
+	for _, decl := range decls {
+		collectIdents(used, decl)
+	}
+	src, err := s.renderProgram(decls, body, used)
+	if err != nil || !space {
+		return src, err
+	}
+
+	// reprint with spaces for the terminal
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	config := &printer.Config{Mode: printer.UseSpaces, Tabwidth: 4}
+	err = config.Fprint(&sb, fset, f)
+	return sb.String(), err
+}
+
+// userDecls returns the top-level declarations of the user, including the
+// ones of the files of :file, except for the imports and main.
+func (s *Session) userDecls() []ast.Decl {
+	all := s.file.Decls
+	for _, path := range s.declFilePaths() {
+		all = append(all[:len(all):len(all)], s.extraFileOf(path).Decls...)
+	}
+	var decls []ast.Decl
+	for _, decl := range all {
+		if decl == s.mainFunc() || isGoreDecl(decl) {
+			continue
+		}
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT {
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	return decls
+}
+
+// renderProgram returns the formatted source of the program with the
+// declarations, the statements of main and the imports used by the names.
+func (s *Session) renderProgram(decls []ast.Decl, stmts []ast.Stmt, used map[string]bool) (string, error) {
+	var out []ast.Decl
+	var importSpecs []ast.Spec
+	for _, imp := range s.file.Imports {
+		if !importUsed(imp, used) || imp.Path.Value == strconv.Quote("C") {
+			continue
+		}
+		importSpecs = append(importSpecs, &ast.ImportSpec{Name: imp.Name, Path: &ast.BasicLit{Kind: token.STRING, Value: imp.Path.Value}})
+	}
+	if len(importSpecs) > 0 {
+		out = append(out, &ast.GenDecl{Tok: token.IMPORT, Lparen: 1, Specs: importSpecs})
+	}
+	out = append(out, decls...)
+	main := &ast.FuncDecl{
+		Name: ast.NewIdent("main"),
+		Type: &ast.FuncType{Params: &ast.FieldList{}},
+		Body: &ast.BlockStmt{List: stmts},
+	}
+	out = append(out, main)
+
+	// print the declarations one by one to separate them by blank lines
+	var sb strings.Builder
+	sb.WriteString("package main\n")
+	if s.cgoImportDecl() >= 0 {
+		sb.WriteString("\n" + s.cgoComment() + cgoImport + "\n")
+	}
+	config := &printer.Config{Tabwidth: 8}
+	for _, decl := range out {
+		// print main without the positions, which are not of the same file,
+		// leaving the statements as they are for the later inputs
+		fset := s.fset
+		if decl == main {
+			fset = token.NewFileSet()
+		}
+		sb.WriteString("\n")
+		if err := config.Fprint(&sb, fset, decl); err != nil {
+			return "", err
+		}
+		sb.WriteString("\n")
+	}
+	src, err := format.Source([]byte(sb.String()))
+	return string(src), err
+}
+
+// foldBlankCalls returns the statements with the blank assignments of the
+// function calls, which are made for the printed values, folded to the calls.
+func (s *Session) foldBlankCalls(stmts []ast.Stmt) []ast.Stmt {
+	folded := make([]ast.Stmt, len(stmts))
+	for i, stmt := range stmts {
+		folded[i] = stmt
+		assign, ok := stmt.(*ast.AssignStmt)
+		if !ok || assign.Tok != token.ASSIGN || len(assign.Rhs) != 1 {
+			continue
+		}
+		call, ok := assign.Rhs[0].(*ast.CallExpr)
+		if !ok || !s.isFuncCall(call) {
+			continue
+		}
+		blank := true
+		for _, lhs := range assign.Lhs {
+			blank = blank && isNamedIdent(lhs, "_")
+		}
+		if blank {
+			folded[i] = &ast.ExprStmt{X: call}
+		}
+	}
+	return folded
+}
+
+// isFuncCall reports whether the call is a function call,
+// which can be a statement unlike conversions and some builtins.
+func (s *Session) isFuncCall(call *ast.CallExpr) bool {
+	tv, ok := s.typeInfo.Types[call.Fun]
+	if !ok || tv.IsType() || tv.IsBuiltin() {
+		return false
+	}
+	_, ok = tv.Type.Underlying().(*types.Signature)
+	return ok
+}
+
+// isGoreDecl reports whether the declaration is for the evaluation by gore.
+func isGoreDecl(decl ast.Decl) bool {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Recv == nil && isGoreName(decl.Name.Name)
+	case *ast.GenDecl:
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				if !isGoreName(spec.Name.Name) {
+					return false
+				}
+			case *ast.ValueSpec:
+				for _, name := range spec.Names {
+					if !isGoreName(name.Name) {
+						return false
+					}
+				}
+			default:
+				return false
+			}
+		}
+		return len(decl.Specs) > 0
+	}
+	return false
+}
+
+// importUsed reports whether the import is used by the names.
+func importUsed(imp *ast.ImportSpec, names map[string]bool) bool {
+	if imp.Name != nil && imp.Name.Name != "_" && imp.Name.Name != "." {
+		return names[imp.Name.Name]
+	}
+	path, _ := strconv.Unquote(imp.Path.Value)
+	return names[importName(path)]
+}
+
+// collectIdents adds the names of the identifiers in node to names.
+func collectIdents(names map[string]bool, node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			names[ident.Name] = true
+		}
+		return true
+	})
+}
+
+// declaresAny reports whether decl declares any of the names,
+// or methods of types of the names.
+func declaresAny(decl ast.Decl, names map[string]bool) bool {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Recv != nil {
+			for _, field := range decl.Recv.List {
+				typ := field.Type
+				if star, ok := typ.(*ast.StarExpr); ok {
+					typ = star.X
+				}
+				switch index := typ.(type) {
+				case *ast.IndexExpr:
+					typ = index.X
+				case *ast.IndexListExpr:
+					typ = index.X
+				}
+				if ident, ok := typ.(*ast.Ident); ok && names[ident.Name] {
+					return true
+				}
+			}
+			return false
+		}
+		return names[decl.Name.Name]
+	case *ast.GenDecl:
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				if names[spec.Name.Name] {
+					return true
+				}
+			case *ast.ValueSpec:
+				for _, name := range spec.Names {
+					if names[name.Name] {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// importName guesses the package name of the import path.
+func importName(path string) string {
+	name := path[strings.LastIndex(path, "/")+1:]
+	if strings.HasPrefix(name, "v") && strings.Trim(name[1:], "0123456789") == "" && name != "v" {
+		if i := strings.LastIndex(path, "/"); i > 0 {
+			return importName(path[:i])
+		}
+	}
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.IndexAny(name, ".-"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,123 @@
+package gore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Write_range(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	codes := []string{
+		`:import strings fmt`,
+		`type T struct{ s string }`,
+		`func (t T) upper() string { return strings.ToUpper(t.s) }`,
+		`type U int`,
+		`func double(n int) int { return n * 2 }`,
+		`x := double(21)`,
+		`:mark a`,
+		`t := T{"hello"}`,
+		`fmt.Println(t.upper())`,
+		`:write a..3 ` + filepath.Join(dir, "a.go"),
+		`:write 1 ` + filepath.Join(dir, "b.go"),
+		`:write 4 ` + filepath.Join(dir, "c.go"),
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, "write: no such statement: 4\n", stderr.String())
+
+	content, err := os.ReadFile(filepath.Join(dir, "a.go"))
+	require.NoError(t, err)
+	assert.Equal(t, `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type T struct{ s string }
+
+func (t T) upper() string { return strings.ToUpper(t.s) }
+
+func double(n int) int { return n * 2 }
+
+func main() {
+	x := double(21)
+	t := T{"hello"}
+	fmt.Println(t.upper())
+}
+`, string(content))
+
+	content, err = os.ReadFile(filepath.Join(dir, "b.go"))
+	require.NoError(t, err)
+	assert.Equal(t, `package main
+
+func double(n int) int { return n * 2 }
+
+func main() {
+	x := double(21)
+}
+`, string(content))
+}
+
+func TestImportName(t *testing.T) {
+	for path, name := range map[string]string{
+		"fmt":                            "fmt",
+		"math/rand":                      "rand",
+		"github.com/k0kubun/pp/v3":       "pp",
+		"github.com/motemen/go-quickfix": "quickfix",
+		"gopkg.in/yaml.v3":               "yaml",
+	} {
+		assert.Equal(t, name, importName(path), path)
+	}
+}
+
+func TestAction_Print(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import strings os`,
+		`func double(n int) int { return n * 2 }`,
+		`x := 1`,
+		`strings.ToUpper("a")`,
+		`len("abc")`,
+		`x`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+	stdout.Reset()
+
+	_, err = s.Eval(`:print`)
+	require.NoError(t, err)
+	assert.Equal(t, `package main
+
+import (
+    "strings"
+)
+
+func double(n int) int { return n * 2 }
+
+func main() {
+    x := 1
+    strings.ToUpper("a")
+}
+
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
@@ -0,0 +1,216 @@
+package gore
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// The declarations (functions and types) can be added to another file of the
+// package by :file, to organize them apart from the main file. The files are
+// compiled with the main file as the extra files, sharing the imports of the
+// session, which are blanked by quickfix if unused in the file.
+
+// mainFileName is the name of :file to add the declarations to the main file.
+const mainFileName = "main"
+
+// actionFile switches the file which the declarations are added to, or shows
+// the files of the session.
+func actionFile(s *Session, arg string) error {
+	if arg == "" {
+		current := mainFileName
+		if s.declFile != nil {
+			current = filepath.Base(s.fset.File(s.declFile.Pos()).Name())
+		}
+		names := []string{mainFileName}
+		for _, path := range s.declFilePaths() {
+			names = append(names, filepath.Base(path))
+		}
+		for _, name := range names {
+			mark := " "
+			if name == current {
+				mark = "*"
+			}
+			fmt.Fprintf(s.stdout, "%s %s\n", mark, name)
+		}
+		return nil
+	}
+
+	if arg == mainFileName {
+		s.declFile = nil
+		return nil
+	}
+	if !strings.HasSuffix(arg, ".go") || strings.HasSuffix(arg, "_test.go") ||
+		strings.ContainsAny(arg, `/\`) || strings.HasPrefix(arg, "gore_") {
+		return s.errorf("invalid file name: %q", arg)
+	}
+
+	path := filepath.Join(s.tempDir, arg)
+	for i, p := range s.extraFilePaths {
+		if p == path {
+			s.declFile = s.extraFiles[i]
+			return nil
+		}
+	}
+
+	source := "package main\n"
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		return err
+	}
+	f, err := parser.ParseFile(s.fset, path, source, parser.Mode(0))
+	if err != nil {
+		return err
+	}
+	s.extraFiles = append(s.extraFiles, f)
+	s.extraFilePaths = append(s.extraFilePaths, path)
+	s.declFile = f
+	return nil
+}
+
+// declFilePaths returns the paths of the files added by :file.
+func (s *Session) declFilePaths() []string {
+	var paths []string
+	for _, path := range s.extraFilePaths {
+		if s.isDeclFile(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// isDeclFile reports whether the extra file of the path is added by :file,
+// not by gore (e.g. gore_bench.go) or by -context.
+func (s *Session) isDeclFile(path string) bool {
+	return filepath.Dir(path) == s.tempDir && !strings.HasPrefix(filepath.Base(path), "gore_")
+}
+
+// addDecl adds the declaration to the file switched by :file.
+func (s *Session) addDecl(decl ast.Decl) {
+	f := s.file
+	if s.declFile != nil {
+		f = s.declFile
+	}
+	f.Decls = append(f.Decls, decl)
+}
+
+// removeFuncDecls removes the functions of the key from the files of :file,
+// to be declared again.
+func (s *Session) removeFuncDecls(key string) {
+	for _, path := range s.declFilePaths() {
+		f := s.extraFileOf(path)
+		for i, d := range f.Decls {
+			if d, ok := d.(*ast.FuncDecl); ok && funcKey(d) == key {
+				f.Decls = append(f.Decls[:i:i], f.Decls[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// storeDeclFiles returns the declarations of the files of :file by the paths,
+// to be restored by restoreDeclFiles.
+func (s *Session) storeDeclFiles() map[string][]ast.Decl {
+	decls := make(map[string][]ast.Decl)
+	for _, path := range s.declFilePaths() {
+		decls[path] = append([]ast.Decl(nil), s.extraFileOf(path).Decls...)
+	}
+	return decls
+}
+
+// restoreDeclFiles restores the declarations of the files of :file, and
+// writes the files again not to leave the ones failed (e.g. for :write).
+func (s *Session) restoreDeclFiles(decls map[string][]ast.Decl) {
+	if len(decls) == 0 {
+		return
+	}
+	for path, ds := range decls {
+		if f := s.extraFileOf(path); f != nil {
+			f.Decls = ds
+		}
+	}
+	if err := s.writeDeclFiles(); err != nil {
+		debugf("restoreDeclFiles :: err = %s", err)
+	}
+}
+
+// resetDeclFiles parses the files of :file again as the main file is reset,
+// so that the positions of the declarations added are of the file.
+func (s *Session) resetDeclFiles() error {
+	for i, path := range s.extraFilePaths {
+		if f := s.extraFiles[i]; s.isDeclFile(path) {
+			var buf bytes.Buffer
+			if err := printer.Fprint(&buf, s.fset, f); err != nil {
+				return err
+			}
+			g, err := parser.ParseFile(s.fset, path, buf.Bytes(), parser.Mode(0))
+			if err != nil {
+				return err
+			}
+			if s.declFile == f {
+				s.declFile = g
+			}
+			s.extraFiles[i] = g
+		}
+	}
+	return nil
+}
+
+// syncFileImports gives the imports of the main file to the files of :file,
+// as the imports are not shared by the files of a package.
+func (s *Session) syncFileImports() {
+	for _, path := range s.declFilePaths() {
+		f := s.extraFileOf(path)
+		var decls []ast.Decl
+		for _, decl := range f.Decls {
+			if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT {
+				continue
+			}
+			decls = append(decls, decl)
+		}
+		f.Decls, f.Imports = decls, nil
+		for _, imp := range s.file.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || path == "C" {
+				continue
+			}
+			var name string
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			astutil.AddNamedImport(s.fset, f, name, path)
+		}
+	}
+}
+
+// writeDeclFiles writes the files of :file to run them.
+func (s *Session) writeDeclFiles() error {
+	for _, path := range s.declFilePaths() {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, s.fset, s.extraFileOf(path)); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extraFileOf returns the extra file of the path.
+func (s *Session) extraFileOf(path string) *ast.File {
+	for i, p := range s.extraFilePaths {
+		if p == path {
+			return s.extraFiles[i]
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ require (
 	github.com/motemen/go-quickfix v0.0.0-20230925231438-5cf0001766ff
 	github.com/peterh/liner v1.2.2
 	github.com/stretchr/testify v1.3.0
+	golang.org/x/mod v0.12.0
 	golang.org/x/text v0.13.0
 	golang.org/x/tools v0.13.0
 )
@@ -15,6 +16,5 @@ require (
 	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
-	golang.org/x/mod v0.12.0 // indirect
 	golang.org/x/sys v0.12.0 // indirect
 )
@@ -0,0 +1,110 @@
+package gore
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Every input builds the whole program by go build, which depends on the
+// build cache to be fast. A run is regarded as slow if the build takes
+// longer than slowRunThreshold, and consecutive slow runs are reported once.
+const (
+	slowRunThreshold = 5 * time.Second
+	slowRunsToReport = 3
+)
+
+// buildCache is the state of the build cache of the session.
+type buildCache struct {
+	dir      string // the build cache directory of the session, or "" to use GOCACHE
+	slowRuns int    // the number of the consecutive slow runs
+	checked  bool   // whether the build cache is checked
+}
+
+// goCacheProblem returns the problem of the build cache with the environment,
+// or an empty string if there is none.
+func goCacheProblem(env []string) string {
+	cmd := exec.Command("go", "env", "GOCACHE")
+	cmd.Env = env
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Sprintf("cannot get GOCACHE: %s", err)
+	}
+	dir := strings.TrimSpace(string(out))
+	switch dir {
+	case "off":
+		return "the build cache is disabled (GOCACHE=off)"
+	case "":
+		return "the build cache directory cannot be determined"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Sprintf("the build cache is not available: %s", err)
+	}
+	f, err := os.CreateTemp(dir, "gore-")
+	if err != nil {
+		return fmt.Sprintf("the build cache is not writable: %s", err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return ""
+}
+
+// checkGoCache reports the problem of the build cache, if any. It is called
+// on the first run instead of the start of the session, not to delay it.
+func (s *Session) checkGoCache() {
+	if problem := goCacheProblem(s.goEnviron()); problem != "" {
+		fmt.Fprintf(s.stderr, s.tr("warning: %s; evaluations will be slow (:set gocache <dir> to use another cache)")+"\n", problem)
+	}
+}
+
+// recordRunTime keeps track of slow runs, and reports the build cache
+// problem on consecutive slow runs.
+func (s *Session) recordRunTime(d time.Duration) {
+	if d < slowRunThreshold {
+		s.cache.slowRuns = 0
+		return
+	}
+	s.cache.slowRuns++
+	if s.cache.slowRuns != slowRunsToReport {
+		return
+	}
+	if problem := goCacheProblem(s.goEnviron()); problem != "" {
+		fmt.Fprintf(s.stderr, s.tr("warning: evaluations are slow (%.1fs); %s (:set gocache <dir> to use another cache)")+"\n", d.Seconds(), problem)
+	} else {
+		fmt.Fprintf(s.stderr, s.tr("warning: evaluations are slow (%.1fs); the build cache may be full or trimmed (:set gocache <dir> to use a dedicated cache)")+"\n", d.Seconds())
+	}
+}
+
+func setGoCache(s *Session, value string) error {
+	if value == "" {
+		s.cache.dir = ""
+		return nil
+	}
+	dir, err := filepath.Abs(expandHome(value))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	prev := s.cache.dir
+	s.cache.dir = dir
+	if problem := goCacheProblem(s.goEnviron()); problem != "" {
+		s.cache.dir = prev
+		return fmt.Errorf("%s", problem)
+	}
+	return nil
+}
+
+// expandHome expands the leading ~ of the path to the home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
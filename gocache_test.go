@@ -0,0 +1,56 @@
+package gore
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoCacheProblem(t *testing.T) {
+	assert.Equal(t, "", goCacheProblem(nil))
+	assert.Equal(t, "the build cache is disabled (GOCACHE=off)", goCacheProblem(append(os.Environ(), "GOCACHE=off")))
+}
+
+func TestSession_recordRunTime(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	for _, d := range []time.Duration{6, 7, 1, 6, 6, 6, 6} {
+		s.recordRunTime(d * time.Second)
+	}
+
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, "warning: evaluations are slow (6.0s); the build cache may be full or trimmed (:set gocache <dir> to use a dedicated cache)\n", stderr.String())
+}
+
+func TestAction_Set_gocache(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	dir := filepath.Join(t.TempDir(), "cache")
+	codes := []string{
+		`:set gocache ` + dir,
+		`:import os`,
+		`os.Getenv("GOCACHE")`,
+		`:set gocache ""`,
+		`:set gocache`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, strconv.Quote(dir)+"\ngocache = \"\"\n", stdout.String())
+	assert.Equal(t, "", stderr.String())
+	assert.DirExists(t, dir)
+}
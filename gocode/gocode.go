@@ -43,6 +43,12 @@ func Query(source []byte, cursor int) (*Result, error) {
 	return DefaultCompleter.Query(source, cursor)
 }
 
+// QueryEnv is Query with the environment of gocode (e.g. GOOS and GOARCH to
+// complete the code for), or the environment of the process if env is nil.
+func QueryEnv(source []byte, cursor int, env []string) (*Result, error) {
+	return DefaultCompleter.QueryEnv(source, cursor, env)
+}
+
 // Available checks if gocode executable is available or not.
 func Available() bool {
 	return DefaultCompleter.Available()
@@ -50,7 +56,14 @@ func Available() bool {
 
 // Query asks gocode for completion of Go code source for a cursor position cursor.
 func (c *Completer) Query(source []byte, cursor int) (*Result, error) {
+	return c.QueryEnv(source, cursor, nil)
+}
+
+// QueryEnv is Query with the environment of gocode, or the environment of
+// the process if env is nil.
+func (c *Completer) QueryEnv(source []byte, cursor int, env []string) (*Result, error) {
 	cmd := exec.Command(c.GocodePath, "-f=json", "autocomplete", fmt.Sprintf("%d", cursor))
+	cmd.Env = env
 
 	in, err := cmd.StdinPipe()
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -19,10 +20,51 @@ func (s *Session) initGoMod() error {
 	tempModule := filepath.Base(s.tempDir)
 	goModPath := filepath.Join(s.tempDir, "go.mod")
 	directives := s.listModuleDirectives()
+	// the language version of the go command, or go 1.16 is assumed,
+	// rejecting the type parameters
+	if lang := goLangVersion(s.goVersion()); lang != "" {
+		directives = append([]string{"go " + lang}, directives...)
+		s.types.GoVersion = "go" + lang
+		if built := goLangVersion(runtime.Version()); built != "" && langVersionLess(built, lang) {
+			// go/types of gore checks up to the version it is built with
+			s.types.GoVersion = "go" + built
+		}
+	}
 	mod := "module " + tempModule + "\n" + strings.Join(directives, "\n")
 	return os.WriteFile(goModPath, []byte(mod), 0o644)
 }
 
+// goLangVersion returns the language version of the Go version (e.g. 1.21 of
+// go1.21.3), or "" if it is not of a release (e.g. devel).
+func goLangVersion(version string) string {
+	v, ok := strings.CutPrefix(version, "go")
+	if !ok {
+		return ""
+	}
+	major, minor, _ := strings.Cut(v, ".")
+	if i := strings.IndexFunc(minor, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minor = minor[:i]
+	}
+	if _, err := strconv.Atoi(major); err != nil || minor == "" {
+		return ""
+	}
+	return major + "." + minor
+}
+
+// langVersionLess reports whether the language version a is older than b.
+func langVersionLess(a, b string) bool {
+	am, an, _ := strings.Cut(a, ".")
+	bm, bn, _ := strings.Cut(b, ".")
+	if am != bm {
+		x, _ := strconv.Atoi(am)
+		y, _ := strconv.Atoi(bm)
+		return x < y
+	}
+	x, _ := strconv.Atoi(an)
+	y, _ := strconv.Atoi(bn)
+	return x < y
+}
+
 func (s *Session) listModuleDirectives() []string {
 	var directives []string
 	for i, pp := range printerPkgs {
@@ -54,11 +96,7 @@ func (s *Session) listModuleDirectives() []string {
 		// only the first printer is checked (assuming printerPkgs[1] is fmt)
 		break
 	}
-	modules, err := goListAll()
-	if err != nil {
-		return directives
-	}
-	for _, m := range modules {
+	for _, m := range s.goModules() {
 		if m.Main || m.Replace != nil {
 			directives = append(directives, "replace "+m.Path+" => "+strconv.Quote(m.Dir))
 			s.requiredModules = append(s.requiredModules, m.Path)
@@ -73,6 +111,20 @@ type goModule struct {
 	Replace            *goModule
 }
 
+// goModules returns the modules listed by go list -m all. The list is
+// cached for the session, as it is used on every initialization and
+// completion of imports, and the directory of gore does not change.
+func (s *Session) goModules() []*goModule {
+	if s.modules == nil {
+		modules, err := goListAll()
+		if err != nil {
+			debugf("go list -m all: %s", err)
+		}
+		s.modules = append([]*goModule{}, modules...)
+	}
+	return s.modules
+}
+
 func goListAll() ([]*goModule, error) {
 	cmd := exec.Command("go", "list", "-json", "-m", "all")
 	out, err := cmd.Output()
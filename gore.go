@@ -1,11 +1,21 @@
+// Package gore provides a Go REPL.
+//
+// The REPL is started by Gore.Run. To embed the evaluator in another tool,
+// create a Session by NewSession and feed inputs to Session.Eval, which
+// returns the outputs of each input as a Result. To test the REPL without
+// a terminal, a Driver feeds the lines to a session as typed in the REPL.
 package gore
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"go/build"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // Version of gore.
@@ -14,8 +24,26 @@ const Version = "0.5.7"
 // Gore ...
 type Gore struct {
 	autoImport           bool
+	server               bool
+	plain                bool
+	kernel               string
+	httpAddr             string
+	checkUpdate          bool
+	a11y                 bool
+	buildContext         *build.Context
+	store                Store
 	extFiles             string
 	packageName          string
+	bundle               string
+	maxOutput            string
+	race                 bool
+	deterministic        bool
+	timeout              string
+	sandbox              string
+	sandboxImage         string
+	noColor              bool
+	tempRoot             string
+	confirm              func(prompt string) bool // asks the user to confirm the bundle opened
 	outWriter, errWriter io.Writer
 }
 
@@ -28,16 +56,66 @@ func New(opts ...Option) *Gore {
 	return g
 }
 
-// Run ...
-func (g *Gore) Run() error {
-	s, err := NewSession(g.outWriter, g.errWriter)
-	defer s.Clear()
+// newSession creates a session configured by the options.
+func (g *Gore) newSession(stdout, stderr io.Writer) (*Session, error) {
+	if g.a11y {
+		stdout = &a11yWriter{w: stdout}
+		stderr = &a11yWriter{w: stderr, label: a11yErrorLabel}
+	}
+	start := time.Now()
+	s, err := newSessionIn(g.tempRoot, stdout, stderr)
 	if err != nil {
-		return err
+		return s, err
 	}
 	s.autoImport = g.autoImport
+	s.store = g.store
+	if s.store == nil {
+		// the default store is created here, not to fail without home
+		if s.store, err = NewStore(""); err != nil {
+			errorf("home: %s", err)
+		}
+	}
+	s.loadConfig()
+	if g.buildContext != nil {
+		s.buildContext = *g.buildContext
+	}
 
-	fmt.Fprintf(g.errWriter, "gore version %s  :help for help\n", Version)
+	if g.maxOutput != "" {
+		if err := setMaxOutput(s, g.maxOutput); err != nil {
+			return s, err
+		}
+	}
+
+	if g.race {
+		if err := setRace(s, "on"); err != nil {
+			return s, err
+		}
+	}
+
+	if g.deterministic {
+		if err := setDeterministic(s, "on"); err != nil {
+			return s, err
+		}
+	}
+
+	if g.timeout != "" {
+		if err := setTimeout(s, g.timeout); err != nil {
+			return s, err
+		}
+	}
+
+	if g.sandbox != "" {
+		if err := s.setSandbox(g.sandbox, g.sandboxImage); err != nil {
+			return s, err
+		}
+	}
+
+	if g.a11y || g.noColor {
+		s.a11y, s.format.noColor = g.a11y, g.noColor
+		if err := s.updatePrinter(); err != nil {
+			return s, err
+		}
+	}
 
 	if g.extFiles != "" {
 		extFiles := strings.Split(g.extFiles, ",")
@@ -46,37 +124,165 @@ func (g *Gore) Run() error {
 
 	if g.packageName != "" {
 		if err := s.includePackage(g.packageName); err != nil {
-			return err
+			return s, err
+		}
+	}
+
+	if g.bundle != "" {
+		s.confirm = g.confirm
+		if err := s.openBundle(g.bundle); err != nil {
+			return s, err
 		}
 	}
 
-	rl := newContLiner()
-	defer rl.Close()
+	debugf("session started in %s", time.Since(start))
+	return s, nil
+}
+
+// newSessionGroup returns the sessions with the first session, in which
+// :session new creates the sessions configured by the options, except the
+// bundle opened in the first one, and reading the input as it does.
+func (g *Gore) newSessionGroup(s *Session, stdout, stderr io.Writer) *sessionGroup {
+	return newSessionGroup(s, func() (*Session, error) {
+		o := *g
+		o.bundle = ""
+		t, err := o.newSession(stdout, stderr)
+		if err != nil {
+			t.Clear()
+			return nil, err
+		}
+		t.stdin = s.stdin
+		return t, nil
+	})
+}
+
+// Run ...
+func (g *Gore) Run() error {
+	if g.httpAddr != "" {
+		return g.serveHTTP(g.httpAddr)
+	}
+
+	var rl *contLiner
+	if !g.server && !g.plain && g.kernel == "" {
+		// the liner is created first to confirm the bundle opened
+		rl = newContLiner()
+		defer rl.Close()
+		if rl.mode != nil {
+			g.confirm = rl.confirm
+		}
+	}
 
-	var historyFile string
-	home, err := homeDir()
+	s, err := g.newSession(g.outWriter, g.errWriter)
+	defer s.Clear()
 	if err != nil {
-		errorf("home: %s", err)
-	} else {
-		historyFile = filepath.Join(home, "history")
+		return err
+	}
+
+	// the interrupt is taken by the line editor and the program run in the
+	// REPL on the terminal, and terminates gore otherwise
+	sigs := terminateSignals
+	if rl == nil || rl.mode == nil {
+		sigs = append([]os.Signal{os.Interrupt}, sigs...)
+	}
+	// the sessions are switched only in the REPL
+	sessions := g.newSessionGroup(s, g.outWriter, g.errWriter)
+	defer sessions.clear()
+	defer clearOnSignal(func() {
+		if rl != nil {
+			rl.Close()
+		}
+		sessions.clear()
+	}, sigs...)()
+
+	if g.server || g.plain || g.kernel != "" {
+		s.group = nil
+	}
+
+	if g.server {
+		// stdin is used for the protocol
+		s.stdin = nil
+		return s.serve(os.Stdin, g.outWriter)
+	}
 
-		f, err := os.Open(historyFile)
+	if g.plain {
+		// stdin is used for the inputs
+		s.stdin = nil
+		s.format.noColor = true
+		if err := s.updatePrinter(); err != nil {
+			return err
+		}
+		return s.runPlain(os.Stdin, g.outWriter)
+	}
+
+	if g.kernel != "" {
+		// input requests are not supported
+		s.stdin = nil
+		return s.runKernel(g.kernel)
+	}
+
+	fmt.Fprintf(g.errWriter, "gore version %s  :help for help\n", Version)
+
+	rl.a11y = g.a11y
+
+	st := s.store
+	if g.checkUpdate && st != nil {
+		checkUpdate(st, g.errWriter, time.Now())
+	}
+	// the summary is of the session current on quitting, as the autosave is
+	defer func() {
+		for _, name := range sessions.names {
+			if err := sessions.sessions[name].saveHistory(); err != nil {
+				errorf("while saving history: %s", err)
+			}
+		}
+		if err := s.exitSummary(); err != nil {
+			errorf("while saving the session: %s", err)
+		}
+	}()
+
+	if st != nil {
+		history, err := st.Load(storeHistory)
 		if err != nil {
-			if !os.IsNotExist(err) {
+			if !isNotExist(err) {
 				errorf("%s", err)
 			}
 		} else {
-			_, err := rl.ReadHistory(f)
-			if err != nil {
+			if err := rl.readHistory(bytes.NewReader(history)); err != nil {
 				errorf("while reading history: %s", err)
 			}
-			f.Close()
 		}
 	}
 
-	rl.SetWordCompleter(s.completeWord)
+	// attach connects the current session to the line editor
+	attach := func() {
+		rl.history = &s.histControl
+		rl.SetWordCompleter(s.completeWord)
+		s.imports.chooser = func(prompt string, options []string) int {
+			return rl.choose(s.stderr, prompt, options)
+		}
+		s.terminal = rl.handOver
+		s.hint = func(hint string) { rl.hint(s.stderr, hint) }
+		// the summary is for the sessions typed in
+		if rl.mode == nil {
+			s.summaryEnabled = false
+		}
+		s.colorStderr()
+	}
+	attach()
+
+	// the first input failed, to exit with the status if the inputs are piped
+	var failure *ExitError
+	piped := rl.mode == nil
 
 	for {
+		s.reportJobs()
+		s.checkWatch()
+		rl.number = 0
+		if s.numberedPrompt {
+			rl.number = s.inputNumber + 1
+		}
+		rl.platform = s.platform()
+		rl.session = sessions.promptName()
 		in, err := rl.Prompt()
 		if err != nil {
 			if err == io.EOF {
@@ -95,7 +301,15 @@ func (g *Gore) Run() error {
 			continue
 		}
 
-		err = s.Eval(in)
+		s.exit.last, s.exit.status = nil, -1
+		_, err = s.Eval(in)
+		if sessions.current != s {
+			s = sessions.current
+			attach()
+		}
+		if piped && failure == nil && err != nil && err != ErrContinue && err != ErrQuit {
+			failure = &ExitError{Code: s.exitStatus()}
+		}
 		if err != nil {
 			if err == ErrContinue {
 				continue
@@ -109,24 +323,87 @@ func (g *Gore) Run() error {
 		rl.Accepted()
 	}
 
-	if historyFile != "" {
-		err := os.MkdirAll(filepath.Dir(historyFile), 0o755)
-		if err != nil {
+	if st != nil {
+		var history bytes.Buffer
+		if err := rl.writeHistory(&history); err != nil {
+			errorf("while saving history: %s", err)
+		} else if err := st.Save(storeHistory, history.Bytes()); err != nil {
 			errorf("%s", err)
-		} else {
-			f, err := os.Create(historyFile)
-			if err != nil {
-				errorf("%s", err)
-			} else {
-				_, err := rl.WriteHistory(f)
-				if err != nil {
-					errorf("while saving history: %s", err)
-				}
-				f.Close()
-			}
 		}
 	}
 
+	if failure != nil {
+		return failure
+	}
+	return nil
+}
+
+// ExitCodeCompile is the exit status of gore for the input failed without
+// running the program, e.g. by compile errors.
+const ExitCodeCompile = 125
+
+// ExitError is the error of the input failed in the non-interactive modes,
+// with the exit status for gore to exit with: the exit status of the
+// program, or ExitCodeCompile if the program did not run.
+type ExitError struct {
+	Line int // the line of the input, or 0 if unknown
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("evaluation failed at line %d", e.Line)
+	}
+	return "evaluation failed"
+}
+
+// Script evaluates the inputs read from r line by line as typed in the REPL,
+// and stops at the first input which fails, returning an *ExitError.
+func (g *Gore) Script(r io.Reader) error {
+	s, err := g.newSession(g.outWriter, g.errWriter)
+	defer s.Clear()
+	if err != nil {
+		return err
+	}
+	sessions := g.newSessionGroup(s, g.outWriter, g.errWriter)
+	defer sessions.clear()
+	defer clearOnSignal(func() { sessions.clear() }, append([]os.Signal{os.Interrupt}, terminateSignals...)...)()
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	var in string
+	var line, start int
+	for sc.Scan() {
+		line++
+		if in == "" {
+			if strings.TrimSpace(sc.Text()) == "" {
+				continue
+			}
+			in, start = sc.Text(), line
+		} else {
+			in += "\n" + sc.Text()
+		}
+
+		s.exit.last, s.exit.status = nil, -1
+		_, err := s.Eval(in)
+		s = sessions.current
+		switch err {
+		case nil:
+		case ErrContinue:
+			continue
+		case ErrQuit:
+			return nil
+		default:
+			return &ExitError{Line: start, Code: s.exitStatus()}
+		}
+		in = ""
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if in != "" {
+		return fmt.Errorf("unexpected end of input at line %d", start)
+	}
 	return nil
 }
 
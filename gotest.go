@@ -0,0 +1,95 @@
+package gore
+
+import (
+	"bytes"
+	"go/ast"
+	"io"
+	"os"
+	"path/filepath"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The test functions declared in the session are run by go test, with the
+// session written to a test file instead of the file to run.
+const testFileName = "gore_session_test.go"
+
+// actionTest runs the test functions of the session, or the ones matching
+// the pattern (as go test -run).
+func actionTest(s *Session, arg string) error {
+	if !s.hasTests() {
+		return s.errorf("no test functions")
+	}
+
+	s.storeCode()
+	defer s.restoreCode()
+	s.doQuickFix()
+
+	path := filepath.Join(s.tempDir, testFileName)
+	if err := s.writeSourceTo(path); err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	args := append([]string{"test"}, s.runFlags()...)
+	args = append(args, "-vet=off", "-v")
+	if arg != "" {
+		args = append(args, "-run", arg)
+	}
+	for _, file := range s.runFiles() {
+		if file == s.tempFilePath {
+			file = path
+		}
+		args = append(args, file)
+	}
+
+	w := newTestFilter(s.stdout)
+	err := s.goExec(args, w)
+	w.Close()
+	if err != nil {
+		debugf("test :: err = %s", err)
+		return s.errorf("tests failed")
+	}
+	return nil
+}
+
+// hasTests reports whether the session declares a test function.
+func (s *Session) hasTests() bool {
+	for _, decl := range s.userDecls() {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && isTestName(fn.Name.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTestName reports whether the name is of a test function, as go test.
+func isTestName(name string) bool {
+	if len(name) < 4 || name[:4] != "Test" {
+		return false
+	}
+	if len(name) == 4 {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[4:])
+	return !unicode.IsLower(r)
+}
+
+// newTestFilter returns the writer to w of the outputs of go test, dropping
+// the result of the package and the lines after it, and the positions in
+// the test file.
+func newTestFilter(w io.Writer) io.WriteCloser {
+	var done bool
+	return newLineFilter(w, func(p []byte) []byte {
+		if done || bytes.Contains(p, []byte("\tcommand-line-arguments")) {
+			done = true
+			return nil
+		}
+		if i := bytes.Index(p, []byte(testFileName+":")); i >= 0 {
+			if j := bytes.Index(p[i:], []byte(": ")); j >= 0 {
+				return append(p[:i:i], p[i+j+2:]...)
+			}
+		}
+		return p
+	})
+}
@@ -0,0 +1,271 @@
+package gore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peterh/liner"
+)
+
+// The inputs are recorded with the metadata, and saved to the store
+// at the end of the session, in JSON lines.
+const (
+	storeHistoryDB    = "history.jsonl"
+	historyDBMaxSize  = 10000
+	historyListLength = 20
+)
+
+// historySize is the maximum number of the entries of the line editor.
+const historySize = liner.HistoryLimit
+
+type historyEntry struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Input    string        `json:"input"`
+	Failed   bool          `json:"failed,omitempty"`
+	Session  string        `json:"session"`
+}
+
+// historyControl selects the inputs kept in the histories, of the line
+// editor and of :history.
+type historyControl struct {
+	size   int            // the maximum number of the entries of the line editor
+	dedupe bool           // whether to skip the input same as the previous entry
+	ignore *regexp.Regexp // the inputs not kept, or nil
+}
+
+// keep reports whether to keep the input after the previous entry.
+func (h *historyControl) keep(in, prev string) bool {
+	if h.ignore != nil && h.ignore.MatchString(in) {
+		return false
+	}
+	return !h.dedupe || in != prev
+}
+
+// recordHistory records the input evaluated.
+func (s *Session) recordHistory(in string, start time.Time, err error) {
+	var prev string
+	if n := len(s.history); n > 0 {
+		prev = s.history[n-1].Input
+	}
+	if !s.histControl.keep(in, prev) {
+		return
+	}
+	s.history = append(s.history, historyEntry{
+		Time:     start,
+		Duration: time.Since(start),
+		Input:    in,
+		Failed:   err != nil && err != ErrQuit,
+		Session:  s.id,
+	})
+}
+
+// loadHistory returns the saved entries followed by the ones of the session.
+func (s *Session) loadHistory() ([]historyEntry, error) {
+	var entries []historyEntry
+	if s.store != nil {
+		data, err := s.store.Load(storeHistoryDB)
+		if err != nil && !isNotExist(err) {
+			return nil, err
+		}
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			var e historyEntry
+			if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+				debugf("history: %s", err)
+				continue
+			}
+			entries = append(entries, e)
+		}
+	}
+	return append(entries, s.history...), nil
+}
+
+// saveHistory saves the entries of the session to the store.
+func (s *Session) saveHistory() error {
+	if s.store == nil || len(s.history) == 0 {
+		return nil
+	}
+	entries, err := s.loadHistory()
+	if err != nil {
+		return err
+	}
+	if len(entries) > historyDBMaxSize {
+		entries = entries[len(entries)-historyDBMaxSize:]
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	if err := s.store.Save(storeHistoryDB, buf.Bytes()); err != nil {
+		return err
+	}
+	s.history = nil
+	return nil
+}
+
+// historyFilter selects the history entries.
+type historyFilter struct {
+	failed, ok bool
+	session    string
+	since      time.Time
+	terms      []string
+}
+
+// parseHistoryFilter parses the filters, which are
+//   - -failed, -ok: only failed or succeeded inputs
+//   - -session: only inputs of the current session
+//   - -since <duration or date>: only inputs since then (e.g. 7d, 3h, 2006-01-02)
+//   - words: only inputs containing all of them, case insensitively
+func (s *Session) parseHistoryFilter(args []string, now time.Time) (*historyFilter, error) {
+	f := &historyFilter{}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-failed":
+			f.failed = true
+		case "-ok":
+			f.ok = true
+		case "-session":
+			f.session = s.id
+		case "-since":
+			if i++; i == len(args) {
+				return nil, errors.New("-since requires a duration or a date")
+			}
+			since, err := parseSince(args[i], now)
+			if err != nil {
+				return nil, err
+			}
+			f.since = since
+		default:
+			f.terms = append(f.terms, strings.ToLower(arg))
+		}
+	}
+	return f, nil
+}
+
+func (f *historyFilter) match(e *historyEntry) bool {
+	if f.failed && !e.Failed || f.ok && e.Failed {
+		return false
+	}
+	if f.session != "" && e.Session != f.session {
+		return false
+	}
+	if e.Time.Before(f.since) {
+		return false
+	}
+	input := strings.ToLower(e.Input)
+	for _, term := range f.terms {
+		if !strings.Contains(input, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSince parses a duration like 3h and 7d, or a date like 2006-01-02.
+func parseSince(arg string, now time.Time) (time.Time, error) {
+	if n, err := strconv.Atoi(strings.TrimSuffix(arg, "d")); err == nil && strings.HasSuffix(arg, "d") {
+		return now.AddDate(0, 0, -n), nil
+	}
+	if d, err := time.ParseDuration(arg); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", arg, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid duration or date: %s", arg)
+}
+
+func actionHistory(s *Session, arg string) error {
+	args, err := splitArgs(arg)
+	if err != nil {
+		return err
+	}
+	search := len(args) > 0 && args[0] == "search"
+	if search {
+		args = args[1:]
+	} else if len(args) > 0 {
+		return s.errorf("unknown subcommand: %s", args[0])
+	}
+
+	f, err := s.parseHistoryFilter(args, time.Now())
+	if err != nil {
+		return err
+	}
+	entries, err := s.loadHistory()
+	if err != nil {
+		return err
+	}
+
+	type numbered struct {
+		n int
+		e *historyEntry
+	}
+	var matches []numbered
+	for i := range entries {
+		if f.match(&entries[i]) {
+			matches = append(matches, numbered{i + 1, &entries[i]})
+		}
+	}
+	if !search && len(matches) > historyListLength {
+		matches = matches[len(matches)-historyListLength:]
+	}
+
+	w := tabwriter.NewWriter(s.stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	for _, m := range matches {
+		status := "ok"
+		if m.e.Failed {
+			status = "failed"
+		}
+		input, _, multi := strings.Cut(m.e.Input, "\n")
+		if multi {
+			input += " ..."
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t  %s\n", m.n, m.e.Time.Format("2006-01-02 15:04"),
+			m.e.Duration.Round(time.Millisecond), status, input)
+	}
+	return w.Flush()
+}
+
+func setHistSize(s *Session, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 || n > historySize {
+		return s.errorf("invalid size: %q (0 to %d)", value, historySize)
+	}
+	s.histControl.size = n
+	return nil
+}
+
+func setHistDedupe(s *Session, value string) error {
+	b, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.histControl.dedupe = b
+	return nil
+}
+
+func setHistIgnore(s *Session, value string) error {
+	if value == "" {
+		s.histControl.ignore = nil
+		return nil
+	}
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return s.errorf("invalid pattern: %s", err)
+	}
+	s.histControl.ignore = re
+	return nil
+}
@@ -0,0 +1,113 @@
+package gore
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_History(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.store, _ = NewStore("memory")
+
+	old := time.Now().AddDate(0, 0, -10)
+	s.history = []historyEntry{
+		{Time: old, Input: `http.Get("http://example.com")`, Session: "old"},
+		{Time: old, Input: "foo", Failed: true, Session: "old"},
+	}
+	require.NoError(t, s.saveHistory())
+	assert.Empty(t, s.history)
+
+	codes := []string{
+		`x := 1`,
+		`x +`,
+		`y`,
+		"func f() {\n}",
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+	stdout.Reset()
+	stderr.Reset()
+
+	for _, code := range []string{
+		`:history search -failed`,
+		`:history search -since 7d`,
+		`:history search HTTP`,
+		`:history search -session -ok`,
+		`:history search -since foo`,
+		`:history foo`,
+	} {
+		_, _ = s.Eval(code)
+		stdout.WriteString("--\n")
+	}
+
+	lines := regexp.MustCompile(`\d{4}-\d\d-\d\d \d\d:\d\d +\S+`).ReplaceAllString(stdout.String(), "TIME")
+	assert.Equal(t, `  2  TIME  failed  foo
+  4  TIME  failed  y
+--
+  3  TIME      ok  x := 1
+  4  TIME  failed  y
+  5  TIME      ok  func f() { ...
+  6  TIME      ok  :history search -failed
+--
+  1  TIME  ok  http.Get("http://example.com")
+--
+  3  TIME  ok  x := 1
+  5  TIME  ok  func f() { ...
+  6  TIME  ok  :history search -failed
+  7  TIME  ok  :history search -since 7d
+  8  TIME  ok  :history search HTTP
+--
+--
+--
+`, lines)
+	assert.Equal(t, `history: invalid duration or date: foo
+history: unknown subcommand: foo
+`, stderr.String())
+
+	require.NoError(t, s.saveHistory())
+	entries, err := s.loadHistory()
+	require.NoError(t, err)
+	assert.Len(t, entries, 11)
+}
+
+func TestSession_histControl(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	for _, code := range []string{
+		`:set histignore "^ "`,
+		`x := 1`,
+		` y := 2`,
+		`x`,
+		`x`,
+		`:set histdedupe off`,
+		`x`,
+		`:set histsize 2000`,
+	} {
+		_, _ = s.Eval(code)
+	}
+	var inputs []string
+	for _, e := range s.history {
+		inputs = append(inputs, e.Input)
+	}
+	assert.Equal(t, []string{
+		`:set histignore "^ "`,
+		`x := 1`,
+		`x`,
+		`:set histdedupe off`,
+		`x`,
+		`:set histsize 2000`,
+	}, inputs)
+	assert.Equal(t, "set: invalid size: \"2000\" (0 to 1000)\n", stderr.String())
+}
@@ -0,0 +1,294 @@
+package gore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+const (
+	playgroundCookie      = "gore_session"
+	playgroundIdleTimeout = 30 * time.Minute
+	playgroundMaxSessions = 16
+)
+
+var errTooManySessions = errors.New("too many sessions")
+
+// playground serves a web UI backed by a session per browser. As the
+// sessions run any code, the requests from other sites are rejected by
+// the Origin and the Content-Type, and the requests to other host names
+// (by DNS rebinding) are rejected by the Host.
+type playground struct {
+	gore     *Gore
+	host     string // the host name of the address served on, if any
+	mu       sync.Mutex
+	sessions map[string]*playgroundSession
+}
+
+type playgroundSession struct {
+	mu       sync.Mutex // serializes evaluations
+	session  *Session
+	lastUsed time.Time
+}
+
+func (g *Gore) serveHTTP(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	// serve only locally unless the host is given (e.g. 0.0.0.0:8080)
+	if host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+	p := &playground{gore: g, host: host, sessions: map[string]*playgroundSession{}}
+	defer p.clear()
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: p, ReadHeaderTimeout: 10 * time.Second}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, append([]os.Signal{os.Interrupt}, terminateSignals...)...)
+	defer signal.Stop(sigCh)
+	go func() {
+		if _, ok := <-sigCh; ok {
+			server.Close()
+		}
+	}()
+
+	fmt.Fprintf(g.errWriter, "gore version %s  serving playground on http://%s\n", Version, l.Addr())
+	if err := server.Serve(l); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (p *playground) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.allowedHost(r.Host) {
+		http.Error(w, "forbidden host", http.StatusForbidden)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/":
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, playgroundHTML)
+
+	case "/eval", "/reset":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		// the forms of other sites cannot post JSON without the preflight
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && origin != "http://"+r.Host {
+			http.Error(w, "forbidden origin", http.StatusForbidden)
+			return
+		}
+		ps, err := p.session(w, r)
+		if err == errTooManySessions {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+
+		var result evalResult
+		if r.URL.Path == "/eval" {
+			var params evalParams
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			res, err := ps.session.Eval(params.Code)
+			if err == ErrContinue {
+				res.Error = "incomplete input\n"
+			}
+			result = evalResult{Output: res.Output, Error: res.Error, Source: res.Source}
+		} else {
+			if err := ps.session.init(); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			result.Source, _ = ps.session.source(false)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// session returns the session of the browser, creating one if needed.
+func (p *playground) session(w http.ResponseWriter, r *http.Request) (*playgroundSession, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for id, ps := range p.sessions {
+		if now.Sub(ps.lastUsed) > playgroundIdleTimeout && ps.mu.TryLock() {
+			ps.session.Clear()
+			ps.mu.Unlock()
+			delete(p.sessions, id)
+		}
+	}
+
+	if c, err := r.Cookie(playgroundCookie); err == nil {
+		if ps, ok := p.sessions[c.Value]; ok {
+			ps.lastUsed = now
+			return ps, nil
+		}
+	}
+
+	if len(p.sessions) >= playgroundMaxSessions {
+		return nil, errTooManySessions
+	}
+
+	s, err := p.gore.newSession(io.Discard, io.Discard)
+	if err != nil {
+		s.Clear()
+		return nil, err
+	}
+	// the browser has no terminal to read from
+	s.stdin = nil
+
+	id := newMessageID()
+	ps := &playgroundSession{session: s, lastUsed: now}
+	p.sessions[id] = ps
+	http.SetCookie(w, &http.Cookie{
+		Name: playgroundCookie, Value: id, Path: "/",
+		HttpOnly: true, SameSite: http.SameSiteStrictMode,
+	})
+	return ps, nil
+}
+
+// allowedHost reports whether the Host header names the playground, by an IP
+// address, localhost or the host name served on.
+func (p *playground) allowedHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	return net.ParseIP(host) != nil || host == "localhost" || host == p.host
+}
+
+func (p *playground) clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, ps := range p.sessions {
+		ps.session.Clear()
+		delete(p.sessions, id)
+	}
+}
+
+const playgroundHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gore</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+textarea, pre { font-family: monospace; font-size: 14px; width: 100%; box-sizing: border-box; }
+textarea { height: 8em; }
+pre { background: #f4f4f4; padding: .5em; min-height: 4em; white-space: pre-wrap; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+<textarea id="code" placeholder="Go expressions, statements, declarations or :commands" autofocus></textarea>
+<p>
+<button id="run">Run</button> (Ctrl+Enter)
+<button id="reset">Reset</button>
+<label><input type="checkbox" id="show-source"> Show source</label>
+</p>
+<pre id="output"></pre>
+<pre id="source" hidden></pre>
+<script>
+const code = document.getElementById("code");
+const output = document.getElementById("output");
+const source = document.getElementById("source");
+
+async function post(path, body) {
+  const res = await fetch(path, {
+    method: "POST",
+    headers: {"Content-Type": "application/json"},
+    body: JSON.stringify(body || {}),
+  });
+  if (!res.ok) {
+    throw new Error(await res.text());
+  }
+  return res.json();
+}
+
+function show(input, result) {
+  if (input !== undefined) {
+    const div = document.createElement("div");
+    div.textContent = ":= " + input.replace(/\n/g, "\n.. ");
+    output.appendChild(div);
+  }
+  for (const [text, className] of [[result.output, ""], [result.error, "error"]]) {
+    if (text) {
+      const div = document.createElement("div");
+      div.textContent = text.replace(/\n$/, "");
+      div.className = className;
+      output.appendChild(div);
+    }
+  }
+  if (result.source !== undefined) {
+    source.textContent = result.source;
+  }
+  output.scrollTop = output.scrollHeight;
+}
+
+async function run() {
+  const input = code.value;
+  if (input.trim() === "") {
+    return;
+  }
+  try {
+    show(input, await post("/eval", {code: input}));
+    code.value = "";
+  } catch (e) {
+    show(input, {error: e.message});
+  }
+}
+
+document.getElementById("run").onclick = run;
+document.getElementById("reset").onclick = async () => {
+  output.textContent = "";
+  show(undefined, await post("/reset"));
+};
+document.getElementById("show-source").onchange = (e) => {
+  source.hidden = !e.target.checked;
+};
+code.onkeydown = (e) => {
+  if (e.key === "Enter" && (e.ctrlKey || e.metaKey)) {
+    e.preventDefault();
+    run();
+  }
+};
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,102 @@
+package gore
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlayground(t *testing.T) {
+	p := &playground{gore: New(), sessions: map[string]*playgroundSession{}}
+	t.Cleanup(p.clear)
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+
+	newClient := func() *http.Client {
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		return &http.Client{Jar: jar}
+	}
+	post := func(client *http.Client, path, body string) evalResult {
+		res, err := client.Post(server.URL+path, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var result evalResult
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+		return result
+	}
+
+	res, err := http.Get(server.URL)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+
+	c1, c2 := newClient(), newClient()
+
+	result := post(c1, "/eval", `{"code":"x := 42"}`)
+	assert.Equal(t, "42\n", result.Output)
+	assert.Contains(t, result.Source, "x := 42")
+
+	result = post(c1, "/eval", `{"code":"x + 1"}`)
+	assert.Equal(t, "43\n", result.Output)
+
+	result = post(c2, "/eval", `{"code":"x"}`)
+	assert.Equal(t, "undefined: x\n", result.Error)
+
+	result = post(c1, "/eval", `{"code":"func f() {"}`)
+	assert.Equal(t, "incomplete input\n", result.Error)
+
+	result = post(c1, "/reset", ``)
+	assert.NotContains(t, result.Source, "x := 42")
+	result = post(c1, "/eval", `{"code":"x"}`)
+	assert.Equal(t, "undefined: x\n", result.Error)
+
+	assert.Len(t, p.sessions, 2)
+
+	res, err = http.Get(server.URL + "/eval")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	// the simple requests of other sites
+	res, err = http.Post(server.URL+"/eval", "text/plain", strings.NewReader(`{"code":"1"}`))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+
+	for header, value := range map[string]string{"Origin": "http://example.com", "Host": "example.com"} {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/eval", strings.NewReader(`{"code":"1"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if header == "Host" {
+			req.Host = value
+		} else {
+			req.Header.Set(header, value)
+		}
+		res, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusForbidden, res.StatusCode, header)
+	}
+	assert.Len(t, p.sessions, 2)
+}
+
+func TestPlayground_maxSessions(t *testing.T) {
+	p := &playground{gore: New(), sessions: map[string]*playgroundSession{}}
+	for i := 0; i < playgroundMaxSessions; i++ {
+		p.sessions[strconv.Itoa(i)] = &playgroundSession{lastUsed: time.Now()}
+	}
+
+	_, err := p.session(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/eval", nil))
+	assert.Equal(t, errTooManySessions, err)
+}
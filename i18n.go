@@ -0,0 +1,431 @@
+package gore
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// The messages of gore are written in English, and translated by the
+// catalog keyed by the English messages (or the format strings).
+// The outputs of Go (e.g. compile errors) are not translated.
+var messageCatalog = map[string]map[string]string{
+	"ja": {
+		// commands
+		"import a package":                                                                             "パッケージをインポートする",
+		"print the type of expression":                                                                 "式の型を表示する",
+		"print the syntax tree of the code":                                                            "コードの構文木を表示する",
+		"benchmark the expression, printing ns/op and allocs/op":                                       "式をベンチマークし、ns/op と allocs/op を表示する",
+		"run the test functions declared, or the ones matching the pattern":                            "宣言されたテスト関数、またはパターンに一致するものを実行する",
+		"profile the last statement for the CPU or the allocations, printing the top entries":          "最後の文の CPU またはアロケーションをプロファイルし、上位の項目を表示する",
+		"print current source":                                                                         "現在のソースを表示する",
+		"debug the last statement by dlv, stopping at the breakpoint set at it":                        "最後の文を dlv でデバッグし、そこに設定したブレークポイントで止める",
+		"save the session by the name, to be restored after restarting gore":                           "セッションを名前をつけて保存し、gore の再起動後に復元できるようにする",
+		"clear the session and restore the one saved by the name":                                      "セッションをクリアし、その名前で保存したセッションを復元する",
+		"list the sessions, create a session by the name, or switch to it":                             "セッションを一覧表示、名前を付けてセッションを作成、またはそれに切り替える",
+		"copy the source without the scaffolding to the clipboard":                                     "足場を取り除いたソースをクリップボードにコピーする",
+		"share the source to the Go Playground (or the gist endpoint of :set share), printing the URL": "ソースを Go Playground (または :set share の gist のエンドポイント) で共有し、URL を表示する",
+		"add the declarations to another file of the package, or to the main file":                     "宣言をパッケージの別のファイル、またはメインのファイルに追加する",
+		"write out current source, or the statements with their dependencies":                          "現在のソース、または文とその依存をファイルに書き出す",
+		"clear the codes":    "コードを消去する",
+		"show documentation": "ドキュメントを表示する",
+		"list the variables with the types and the statements declaring them":                     "変数を型と宣言した文とともに一覧する",
+		"list the functions with the signatures":                                                  "関数をシグネチャとともに一覧する",
+		"list the types with the underlying types and the methods":                                "型を基底型とメソッドとともに一覧する",
+		"list the methods of the type, or of the types declared":                                  "型のメソッド、または宣言された型のメソッドを一覧する",
+		"list the imports, marking the unused ones":                                               "インポートを一覧し、未使用のものを示す",
+		"report the findings of go vet by the statements":                                         "go vet の指摘を文ごとに報告する",
+		"list the modules of the imports with the versions resolved":                              "インポートのモジュールを解決されたバージョンとともに一覧する",
+		"mark the last statement, or list the marks":                                              "最後の文に印をつける、または印を一覧する",
+		"drop the statements after the statement":                                                 "指定した文より後の文を取り除く",
+		"drop the statements":                                                                     "文を取り除く",
+		"snapshot the code by the name, or list the checkpoints":                                  "コードのスナップショットを名前をつけて取る、またはチェックポイントを一覧する",
+		"restore the code of the checkpoint (the last one if omitted), dropping the later ones":   "チェックポイントのコードを復元し (省略時は最後のもの)、それより後のものを取り除く",
+		"report whether the type implements the interface, or the methods lacked":                 "型がインターフェースを実装しているか、または足りないメソッドを示す",
+		"add the stubs of the methods lacked to implement the interface":                          "インターフェースを実装するのに足りないメソッドのスタブを追加する",
+		"change the working directory of the program (the session directory if omitted)":          "プログラムの作業ディレクトリを変更する (省略時はセッションのディレクトリ)",
+		"print the working directory of the program":                                              "プログラムの作業ディレクトリを表示する",
+		"run the command by the shell in the working directory of the program":                    "プログラムの作業ディレクトリでシェルによりコマンドを実行する",
+		"run the code in the background, reporting the output when it ends":                       "コードをバックグラウンドで実行し、終了時に出力を報告する",
+		"list the jobs running":                                                                   "実行中のジョブを一覧する",
+		"kill the job":                                                                            "ジョブを強制終了する",
+		"set or unset an environment variable, or list them":                                      "環境変数を設定・解除する、または一覧する",
+		"set the arguments of the program, or show them (:args -- to clear)":                      "プログラムの引数を設定する、または表示する (:args -- で消去)",
+		"add C code to import \"C\" by cgo, or show it (:cgo -- to clear)":                        "cgo で import \"C\" する C のコードを追加する、または表示する (:cgo -- で消去)",
+		"set the build tags, or show them (:tags -- to clear)":                                    "ビルドタグを設定する、または表示する (:tags -- で消去)",
+		"give the lines until EOF to the program as the input, or show them (:stdin -- to clear)": "EOF までの行をプログラムの入力として与える、または表示する (:stdin -- で消去)",
+		"list the recent inputs, or search the inputs of all the sessions":                        "最近の入力を一覧する、または全セッションの入力を検索する",
+		"record inputs and outputs to file, or stop recording":                                    "入出力をファイルに記録する、または記録を止める",
+		"evaluate inputs recorded in file":                                                        "ファイルに記録された入力を評価する",
+		"show or change the settings":                                                             "設定を表示・変更する",
+		"show this help":                                                                          "このヘルプを表示する",
+		"quit the session":                                                                        "セッションを終了する",
+		// settings
+		`format of float results (e.g. %.4g), "" to reset`:                                         `浮動小数点数の結果の書式 (例: %.4g)、"" で元に戻す`,
+		`fmt verb of results (%v, %+v or %#v), "" for the default printer`:                         `結果の fmt の動詞 (%v, %+v または %#v)、"" で既定のプリンタ`,
+		"print the errors and fmt.Stringers by Error and String, unless printverb is set (on/off)": "error と fmt.Stringer を Error と String で表示する、printverb の設定がなければ (on/off)",
+		`limit of the outputs of a run (e.g. 64KB), "" for no limit`:                               `実行の出力の上限 (例: 64KB)、"" で無制限`,
+		"write the outputs over maxoutput to a temporary file (on/off)":                            "maxoutput を超えた出力を一時ファイルに書き出す (on/off)",
+		"page the outputs longer than the terminal by $PAGER (on/off)":                             "端末より長い出力を $PAGER でページ送りする (on/off)",
+		"run the code with the race detector (on/off)":                                             "コードをレース検出器つきで実行する (on/off)",
+		"run reproducibly, with a fixed now(), seeded rng and math/rand, one processor (on/off)":   "固定時刻の now()、シードを固定した rng と math/rand、単一のプロセッサで再現可能に実行する (on/off)",
+		`stop the program running over the duration (e.g. 10s), "" for no timeout`:                 `実行時間がこれを超えるとプログラムを止める (例: 10s)、"" で無制限`,
+		"wait for the goroutines to finish at the end, up to the duration (e.g. 1s)":               "最後にゴルーチンの終了を待つ時間 (例: 1s)",
+		`limit of the memory allocated by the program (e.g. 1GB), "" for no limit`:                 `プログラムが割り当てるメモリの上限 (例: 1GB), "" で無制限`,
+		`limit of the CPU time of the program (e.g. 30s), "" for no limit`:                         `プログラムの CPU 時間の上限 (例: 30s), "" で無制限`,
+		"run again when the local packages imported change, checked before the prompt (on/off)":    "インポートしたローカルパッケージが変更されたら再実行する (プロンプトの前に確認, on/off)",
+		`GOOS to type check and complete the code for, "" for this machine`:                        `型検査と補完の対象の GOOS、"" でこのマシン`,
+		`GOPROXY of the go command, not of the program (e.g. off), "" to inherit`:                  `go コマンドの GOPROXY (プログラムには渡さない, 例: off)、"" で継承`,
+		`GOSUMDB of the go command, not of the program (e.g. off), "" to inherit`:                  `go コマンドの GOSUMDB (プログラムには渡さない, 例: off)、"" で継承`,
+		`GOPRIVATE of the go command, not of the program (e.g. *.corp.example.com), "" to inherit`: `go コマンドの GOPRIVATE (プログラムには渡さない, 例: *.corp.example.com)、"" で継承`,
+		`GOFLAGS of the go command, not of the program (e.g. -modcacherw), "" to inherit`:          `go コマンドの GOFLAGS (プログラムには渡さない, 例: -modcacherw)、"" で継承`,
+		`GOARCH to type check and complete the code for, "" for this machine`:                      `型検査と補完の対象の GOARCH、"" でこのマシン`,
+		"group digits of integer results by the locale separator (on/off)":                         "整数の結果をロケールの区切り文字で桁区切りする (on/off)",
+		"whether to keep or drop the input calling os.Exit (drop/keep)":                            "os.Exit を呼んだ入力を残すか取り除くか (drop/keep)",
+		`build cache directory for the session, "" to use GOCACHE`:                                 `セッションのビルドキャッシュのディレクトリ、"" で GOCACHE を使う`,
+		"show the input number (__n) in the prompt (on/off)":                                       "プロンプトに入力番号 (__n) を表示する (on/off)",
+		"rerun the init functions and variable initializers on every evaluation (on/off)":          "init 関数と変数の初期化式を評価のたびに実行し直す (on/off)",
+		"rerun the defer statements of the earlier inputs on every evaluation (on/off)":            "以前の入力の defer 文を評価のたびに再実行する (on/off)",
+		"save the session on quitting, to be restored by :restore-session autosave (on/off)":       "終了時にセッションを保存し、:restore-session autosave で復元できるようにする (on/off)",
+		"print the summary of the session on quitting (on/off)":                                    "終了時にセッションの概要を表示する (on/off)",
+		"maximum number of the entries of the history recalled and saved":                          "呼び出して保存する履歴の最大件数",
+		"skip the input same as the previous entry of the history (on/off)":                        "履歴の直前の項目と同じ入力を残さない (on/off)",
+		`pattern of the inputs not kept in the history (e.g. "^ "), "" to keep all`:                `履歴に残さない入力のパターン (例: "^ ")、"" ですべて残す`,
+		`gist endpoint to :share to (e.g. https://api.github.com/gists), "" for the Go Playground`: `:share の共有先の gist のエンドポイント (例: https://api.github.com/gists)、"" で Go Playground`,
+		`language of the messages (en, ja or pt), "" to follow the environment`:                    `メッセージの言語 (en, ja, pt)、"" で環境に従う`,
+		// messages
+		"argument is required":                             "引数が必要です",
+		"not a type: %s":                                   "型ではありません: %s",
+		"not imported: %s":                                 "インポートされていません: %s",
+		"not a type of the session: %s":                    "セッションの型ではありません: %s",
+		"inputs: %d, failed: %d, time: %s, running: %s":    "入力: %d、失敗: %d、時間: %s、実行: %s",
+		"last saved by %s":                                 "最後の保存: %s",
+		"save the session not saved?":                      "保存されていないセッションを保存しますか?",
+		"saved, to be restored by :restore-session %s":     "保存しました。:restore-session %s で復元できます",
+		"not an interface: %s":                             "インターフェースではありません: %s",
+		"type and interface are required":                  "型とインターフェースが必要です",
+		"%s implements %s":                                 "%s は %s を実装しています",
+		"%s does not implement %s:":                        "%s は %s を実装していません:",
+		"missing: %s":                                      "ありません: %s",
+		"wrong type: %s, want %s":                          "型が違います: %s、期待されるのは %s",
+		"pointer receiver: %s":                             "ポインタのレシーバです: %s",
+		"command not found: %s":                            "コマンドが見つかりません: %s",
+		"could not import %q":                              "%q をインポートできません",
+		"invalid import name: %q":                          "インポート名が不正です: %q",
+		"cannot determine the document location":           "ドキュメントの場所がわかりません",
+		"no such statement: %d":                            "文がありません: %d",
+		"no such mark: %s":                                 "印がありません: %s",
+		"invalid range: %s":                                "範囲が不正です: %s",
+		"invalid mark name: %s":                            "印の名前が不正です: %s",
+		"no statement to mark":                             "印をつける文がありません",
+		"invalid name: %q":                                 "名前が不正です: %q",
+		"invalid argument: %s (KEY=VALUE or -u KEY)":       "引数が不正です: %s (KEY=VALUE または -u KEY)",
+		"invalid argument: %s (<<MARKER or --)":            "引数が不正です: %s (<<MARKER または --)",
+		"here document not closed by %s":                   "ヒアドキュメントが %s で閉じられていません",
+		"not a directory: %s":                              "ディレクトリではありません: %s",
+		"invalid build tag: %q":                            "ビルドタグが不正です: %q",
+		"invalid bundle: %s":                               "バンドルが不正です: %s",
+		"warning: the bundle was written with %s on %s/%s": "警告: バンドルは %[2]s/%[3]s の %[1]s で書き出されました",
+		"warning: could not restore %s: %s":                "警告: %s を復元できません: %s",
+		"the bundle changes the environment:":              "バンドルは環境を変更します:",
+		"could not build the C code":                       "C のコードをビルドできません",
+		"choose the package of %s":                         "%s のパッケージを選んでください",
+		"apply the changes?":                               "変更を適用しますか?",
+		"unknown subcommand: %s":                           "不明なサブコマンドです: %s",
+		"log format is required":                           "ログ形式が必要です",
+		"unknown log format: %s":                           "不明なログ形式です: %s",
+		"invalid format: %q":                               "書式が不正です: %q",
+		"invalid value: %q (drop or keep)":                 "値が不正です: %q (drop または keep)",
+		"invalid verb: %q (%%v, %%+v or %%#v)":             "動詞が不正です: %q (%%v, %%+v または %%#v)",
+		"invalid size: %q":                                 "サイズが不正です: %q",
+		"could not run the benchmark":                      "ベンチマークを実行できません",
+		"no test functions":                                "テスト関数がありません",
+		"tests failed":                                     "テストが失敗しました",
+		"invalid profile: %q (cpu or mem)":                 "プロファイルが不正です: %q (cpu または mem)",
+		"no statement to profile":                          "プロファイルする文がありません",
+		"no statement to debug":                            "デバッグする文がありません",
+		"could not run the program":                        "プログラムを実行できません",
+		"could not build the program":                      "プログラムをビルドできません",
+		"profile written to %s":                            "プロファイルを %s に書き出しました",
+		"changed: %s, running again":                       "変更されました: %s, 再実行します",
+		"the race detector requires cgo (CGO_ENABLED=1)":   "レース検出器には cgo が必要です (CGO_ENABLED=1)",
+		"invalid duration: %q":                             "時間が不正です: %q",
+		"limits are not supported on %s":                   "%s では制限に対応していません",
+		"invalid boolean: %q":                              "真偽値が不正です: %q",
+		"%s is declared again, the old one renamed to %s":  "%s が再び宣言され、前のものは %s に名前を変えました",
+		"invalid size: %q (0 to %d)":                       "サイズが不正です: %q (0 から %d)",
+		"invalid pattern: %s":                              "パターンが不正です: %s",
+		"unknown setting: %s":                              "不明な設定です: %s",
+		"invalid file name: %q":                            "ファイル名が不正です: %q",
+		"invalid checkpoint name: %s":                      "チェックポイントの名前が不正です: %s",
+		"no checkpoint to roll back to":                    "戻るチェックポイントがありません",
+		"no such checkpoint: %s":                           "チェックポイントがありません: %s",
+		"no such job: %d":                                  "ジョブがありません: %d",
+		"no such session: %s":                              "セッションがありません: %s",
+		"session already exists: %s":                       "セッションは既に存在します: %s",
+		"sessions are not supported in this mode":          "このモードではセッションを切り替えられません",
+		"invalid argument: %s":                             "引数が不正です: %s",
+		"invalid job: %s":                                  "ジョブが不正です: %s",
+		"invalid URL: %q":                                  "URL が不正です: %q",
+		"unexpected end of input":                          "入力が途中で終わっています",
+		"invalid session name: %q":                         "セッション名が不正です: %q",
+		"no store to save the sessions":                    "セッションを保存するストアがありません",
+		"no saved session: %s":                             "保存されたセッションがありません: %s",
+		"timed out after %s":                               "%s でタイムアウトしました",
+		"unknown GOOS: %s":                                 "不明な GOOS です: %s",
+		"unknown GOARCH: %s":                               "不明な GOARCH です: %s",
+		"… %d bytes omitted":                               "… %d バイト省略しました",
+		"… %d bytes omitted (written to %s)":               "… %d バイト省略しました (%s に書き出しました)",
+		"unsupported language: %s (en, ja or pt)":          "対応していない言語です: %s (en, ja, pt)",
+		"program exited with code %d at statement #%d":     "プログラムは文 #%[2]d でコード %[1]d で終了しました",
+		"program exited with code %d":                      "プログラムはコード %d で終了しました",
+		"use :drop %d to drop the statement":               ":drop %d でその文を取り除けます",
+		"warning: %s; evaluations will be slow (:set gocache <dir> to use another cache)":                                             "警告: %s; 評価が遅くなります (:set gocache <dir> で別のキャッシュを使えます)",
+		"warning: evaluations are slow (%.1fs); %s (:set gocache <dir> to use another cache)":                                         "警告: 評価が遅くなっています (%.1f秒); %s (:set gocache <dir> で別のキャッシュを使えます)",
+		"warning: evaluations are slow (%.1fs); the build cache may be full or trimmed (:set gocache <dir> to use a dedicated cache)": "警告: 評価が遅くなっています (%.1f秒); ビルドキャッシュが一杯か削除された可能性があります (:set gocache <dir> で専用のキャッシュを使えます)",
+		"warning: init runs on every evaluation (:set rerun-decls off to run it once)":                                                "警告: init は評価のたびに実行されます (:set rerun-decls off で一度だけ実行)",
+		"warning: the initializer of %s runs on every evaluation (:set rerun-decls off to run it once)":                               "警告: %s の初期化式は評価のたびに実行されます (:set rerun-decls off で一度だけ実行)",
+		"warning: the value of %s cannot be kept; its initializer runs on every evaluation":                                           "警告: %s の値は保持できません; 初期化式は評価のたびに実行されます",
+	},
+	"pt": {
+		// commands
+		"import a package":                                                                             "importa um pacote",
+		"print the type of expression":                                                                 "mostra o tipo da expressão",
+		"print the syntax tree of the code":                                                            "mostra a árvore sintática do código",
+		"benchmark the expression, printing ns/op and allocs/op":                                       "faz o benchmark da expressão, mostrando ns/op e allocs/op",
+		"run the test functions declared, or the ones matching the pattern":                            "executa as funções de teste declaradas, ou as que casam com o padrão",
+		"profile the last statement for the CPU or the allocations, printing the top entries":          "perfila o uso de CPU ou as alocações da última instrução, mostrando as primeiras entradas",
+		"print current source":                                                                         "mostra o código atual",
+		"debug the last statement by dlv, stopping at the breakpoint set at it":                        "depura a última instrução pelo dlv, parando no breakpoint definido nela",
+		"save the session by the name, to be restored after restarting gore":                           "salva a sessão com o nome, para ser restaurada após reiniciar o gore",
+		"copy the source without the scaffolding to the clipboard":                                     "copia o código sem a estrutura auxiliar para a área de transferência",
+		"share the source to the Go Playground (or the gist endpoint of :set share), printing the URL": "compartilha o código no Go Playground (ou no endpoint de gist de :set share), exibindo a URL",
+		"clear the session and restore the one saved by the name":                                      "limpa a sessão e restaura a salva com o nome",
+		"list the sessions, create a session by the name, or switch to it":                             "lista as sessões, cria uma sessão pelo nome, ou troca para ela",
+		"add the declarations to another file of the package, or to the main file":                     "adiciona as declarações a outro arquivo do pacote, ou ao arquivo principal",
+		"write out current source, or the statements with their dependencies":                          "grava o código atual, ou as instruções com as suas dependências",
+		"clear the codes":    "limpa o código",
+		"show documentation": "mostra a documentação",
+		"list the variables with the types and the statements declaring them":                     "lista as variáveis com os tipos e as instruções que as declaram",
+		"list the functions with the signatures":                                                  "lista as funções com as assinaturas",
+		"list the types with the underlying types and the methods":                                "lista os tipos com os tipos subjacentes e os métodos",
+		"list the methods of the type, or of the types declared":                                  "lista os métodos do tipo, ou dos tipos declarados",
+		"list the imports, marking the unused ones":                                               "lista os imports, marcando os não usados",
+		"report the findings of go vet by the statements":                                         "relata os apontamentos do go vet por instrução",
+		"list the modules of the imports with the versions resolved":                              "lista os módulos dos imports com as versões resolvidas",
+		"mark the last statement, or list the marks":                                              "marca a última instrução, ou lista as marcas",
+		"drop the statements after the statement":                                                 "descarta as instruções depois da instrução",
+		"drop the statements":                                                                     "descarta as instruções",
+		"snapshot the code by the name, or list the checkpoints":                                  "tira um instantâneo do código com o nome, ou lista os pontos de controle",
+		"restore the code of the checkpoint (the last one if omitted), dropping the later ones":   "restaura o código do ponto de controle (o último se omitido), descartando os posteriores",
+		"report whether the type implements the interface, or the methods lacked":                 "informa se o tipo implementa a interface, ou os métodos que faltam",
+		"add the stubs of the methods lacked to implement the interface":                          "adiciona os stubs dos métodos que faltam para implementar a interface",
+		"change the working directory of the program (the session directory if omitted)":          "muda o diretório de trabalho do programa (o diretório da sessão se omitido)",
+		"print the working directory of the program":                                              "mostra o diretório de trabalho do programa",
+		"run the command by the shell in the working directory of the program":                    "executa o comando pelo shell no diretório de trabalho do programa",
+		"run the code in the background, reporting the output when it ends":                       "executa o código em segundo plano, relatando a saída ao terminar",
+		"list the jobs running":                                                                   "lista os jobs em execução",
+		"kill the job":                                                                            "encerra o job",
+		"set or unset an environment variable, or list them":                                      "define ou remove uma variável de ambiente, ou lista as variáveis",
+		"set the arguments of the program, or show them (:args -- to clear)":                      "define os argumentos do programa, ou mostra-os (:args -- para limpar)",
+		"add C code to import \"C\" by cgo, or show it (:cgo -- to clear)":                        "adiciona código C para import \"C\" pelo cgo, ou mostra-o (:cgo -- para limpar)",
+		"set the build tags, or show them (:tags -- to clear)":                                    "define as tags de compilação, ou mostra-as (:tags -- para limpar)",
+		"give the lines until EOF to the program as the input, or show them (:stdin -- to clear)": "fornece as linhas até EOF como a entrada do programa, ou mostra-as (:stdin -- para limpar)",
+		"list the recent inputs, or search the inputs of all the sessions":                        "lista as entradas recentes, ou busca as entradas de todas as sessões",
+		"record inputs and outputs to file, or stop recording":                                    "grava as entradas e saídas em arquivo, ou para a gravação",
+		"evaluate inputs recorded in file":                                                        "avalia as entradas gravadas em arquivo",
+		"show or change the settings":                                                             "mostra ou altera as configurações",
+		"show this help":                                                                          "mostra esta ajuda",
+		"quit the session":                                                                        "encerra a sessão",
+		// settings
+		`format of float results (e.g. %.4g), "" to reset`:                                         `formato dos resultados de ponto flutuante (ex.: %.4g), "" para restaurar`,
+		`fmt verb of results (%v, %+v or %#v), "" for the default printer`:                         `verbo fmt dos resultados (%v, %+v ou %#v), "" para a impressora padrão`,
+		"print the errors and fmt.Stringers by Error and String, unless printverb is set (on/off)": "mostra os erros e os fmt.Stringer por Error e String, a menos que printverb esteja definido (on/off)",
+		`limit of the outputs of a run (e.g. 64KB), "" for no limit`:                               `limite das saídas de uma execução (ex.: 64KB), "" para sem limite`,
+		"write the outputs over maxoutput to a temporary file (on/off)":                            "gravar as saídas além de maxoutput em um arquivo temporário (on/off)",
+		"page the outputs longer than the terminal by $PAGER (on/off)":                             "pagina as saídas mais longas que o terminal com $PAGER (on/off)",
+		"run the code with the race detector (on/off)":                                             "executar o código com o detector de corridas (on/off)",
+		"run reproducibly, with a fixed now(), seeded rng and math/rand, one processor (on/off)":   "executa de forma reproduzível, com now() fixo, rng e math/rand com semente fixa e um processador (on/off)",
+		`stop the program running over the duration (e.g. 10s), "" for no timeout`:                 `parar o programa que executar além da duração (ex.: 10s), "" para sem limite`,
+		"wait for the goroutines to finish at the end, up to the duration (e.g. 1s)":               "espera as goroutines terminarem no final, até a duração (ex.: 1s)",
+		`limit of the memory allocated by the program (e.g. 1GB), "" for no limit`:                 `limite da memória alocada pelo programa (ex.: 1GB), "" para sem limite`,
+		`limit of the CPU time of the program (e.g. 30s), "" for no limit`:                         `limite do tempo de CPU do programa (ex.: 30s), "" para sem limite`,
+		"run again when the local packages imported change, checked before the prompt (on/off)":    "executa de novo quando os pacotes locais importados mudam, verificado antes do prompt (on/off)",
+		`GOOS to type check and complete the code for, "" for this machine`:                        `GOOS para verificar os tipos e completar o código, "" para esta máquina`,
+		`GOPROXY of the go command, not of the program (e.g. off), "" to inherit`:                  `GOPROXY do comando go, não do programa (ex.: off), "" para herdar`,
+		`GOSUMDB of the go command, not of the program (e.g. off), "" to inherit`:                  `GOSUMDB do comando go, não do programa (ex.: off), "" para herdar`,
+		`GOPRIVATE of the go command, not of the program (e.g. *.corp.example.com), "" to inherit`: `GOPRIVATE do comando go, não do programa (ex.: *.corp.example.com), "" para herdar`,
+		`GOFLAGS of the go command, not of the program (e.g. -modcacherw), "" to inherit`:          `GOFLAGS do comando go, não do programa (ex.: -modcacherw), "" para herdar`,
+		`GOARCH to type check and complete the code for, "" for this machine`:                      `GOARCH para verificar os tipos e completar o código, "" para esta máquina`,
+		"group digits of integer results by the locale separator (on/off)":                         "agrupa os dígitos dos resultados inteiros pelo separador da localidade (on/off)",
+		"whether to keep or drop the input calling os.Exit (drop/keep)":                            "mantém ou descarta a entrada que chama os.Exit (drop/keep)",
+		`build cache directory for the session, "" to use GOCACHE`:                                 `diretório do cache de compilação da sessão, "" para usar GOCACHE`,
+		"show the input number (__n) in the prompt (on/off)":                                       "mostra o número da entrada (__n) no prompt (on/off)",
+		"rerun the init functions and variable initializers on every evaluation (on/off)":          "executa de novo as funções init e os inicializadores das variáveis a cada avaliação (on/off)",
+		"rerun the defer statements of the earlier inputs on every evaluation (on/off)":            "reexecuta as instruções defer das entradas anteriores a cada avaliação (on/off)",
+		"save the session on quitting, to be restored by :restore-session autosave (on/off)":       "salva a sessão ao sair, para ser restaurada por :restore-session autosave (on/off)",
+		"print the summary of the session on quitting (on/off)":                                    "mostra o resumo da sessão ao sair (on/off)",
+		"maximum number of the entries of the history recalled and saved":                          "número máximo de entradas do histórico recuperadas e salvas",
+		"skip the input same as the previous entry of the history (on/off)":                        "ignora a entrada igual à anterior do histórico (on/off)",
+		`pattern of the inputs not kept in the history (e.g. "^ "), "" to keep all`:                `padrão das entradas não mantidas no histórico (ex.: "^ "), "" para manter todas`,
+		`gist endpoint to :share to (e.g. https://api.github.com/gists), "" for the Go Playground`: `endpoint de gist para o :share (ex.: https://api.github.com/gists), "" para o Go Playground`,
+		`language of the messages (en, ja or pt), "" to follow the environment`:                    `idioma das mensagens (en, ja ou pt), "" para seguir o ambiente`,
+		// messages
+		"argument is required":                             "o argumento é obrigatório",
+		"not a type: %s":                                   "não é um tipo: %s",
+		"not imported: %s":                                 "não importado: %s",
+		"not a type of the session: %s":                    "não é um tipo da sessão: %s",
+		"inputs: %d, failed: %d, time: %s, running: %s":    "entradas: %d, falhas: %d, tempo: %s, execução: %s",
+		"last saved by %s":                                 "salva por último por %s",
+		"save the session not saved?":                      "salvar a sessão não salva?",
+		"saved, to be restored by :restore-session %s":     "salva, para ser restaurada por :restore-session %s",
+		"not an interface: %s":                             "não é uma interface: %s",
+		"type and interface are required":                  "o tipo e a interface são obrigatórios",
+		"%s implements %s":                                 "%s implementa %s",
+		"%s does not implement %s:":                        "%s não implementa %s:",
+		"missing: %s":                                      "faltando: %s",
+		"wrong type: %s, want %s":                          "tipo errado: %s, esperado %s",
+		"pointer receiver: %s":                             "receptor ponteiro: %s",
+		"command not found: %s":                            "comando não encontrado: %s",
+		"could not import %q":                              "não foi possível importar %q",
+		"invalid import name: %q":                          "nome de importação inválido: %q",
+		"cannot determine the document location":           "não foi possível determinar o local da documentação",
+		"no such statement: %d":                            "instrução inexistente: %d",
+		"no such mark: %s":                                 "marca inexistente: %s",
+		"invalid range: %s":                                "intervalo inválido: %s",
+		"invalid mark name: %s":                            "nome de marca inválido: %s",
+		"no statement to mark":                             "nenhuma instrução para marcar",
+		"invalid name: %q":                                 "nome inválido: %q",
+		"invalid argument: %s (KEY=VALUE or -u KEY)":       "argumento inválido: %s (KEY=VALUE ou -u KEY)",
+		"invalid argument: %s (<<MARKER or --)":            "argumento inválido: %s (<<MARKER ou --)",
+		"here document not closed by %s":                   "here document não fechado por %s",
+		"not a directory: %s":                              "não é um diretório: %s",
+		"invalid build tag: %q":                            "tag de compilação inválida: %q",
+		"invalid bundle: %s":                               "pacote de sessão inválido: %s",
+		"warning: the bundle was written with %s on %s/%s": "aviso: o pacote de sessão foi gravado com %s em %s/%s",
+		"warning: could not restore %s: %s":                "aviso: não foi possível restaurar %s: %s",
+		"the bundle changes the environment:":              "o pacote de sessão altera o ambiente:",
+		"could not build the C code":                       "não foi possível compilar o código C",
+		"choose the package of %s":                         "escolha o pacote de %s",
+		"apply the changes?":                               "aplicar as alterações?",
+		"unknown subcommand: %s":                           "subcomando desconhecido: %s",
+		"log format is required":                           "o formato do log é obrigatório",
+		"unknown log format: %s":                           "formato de log desconhecido: %s",
+		"invalid format: %q":                               "formato inválido: %q",
+		"invalid value: %q (drop or keep)":                 "valor inválido: %q (drop ou keep)",
+		"invalid verb: %q (%%v, %%+v or %%#v)":             "verbo inválido: %q (%%v, %%+v ou %%#v)",
+		"invalid size: %q":                                 "tamanho inválido: %q",
+		"could not run the benchmark":                      "não foi possível executar o benchmark",
+		"no test functions":                                "nenhuma função de teste",
+		"tests failed":                                     "os testes falharam",
+		"invalid profile: %q (cpu or mem)":                 "perfil inválido: %q (cpu ou mem)",
+		"no statement to profile":                          "nenhuma instrução para perfilar",
+		"no statement to debug":                            "nenhuma instrução para depurar",
+		"could not run the program":                        "não foi possível executar o programa",
+		"could not build the program":                      "não foi possível compilar o programa",
+		"profile written to %s":                            "perfil gravado em %s",
+		"changed: %s, running again":                       "alterado: %s, executando de novo",
+		"the race detector requires cgo (CGO_ENABLED=1)":   "o detector de corridas requer cgo (CGO_ENABLED=1)",
+		"invalid duration: %q":                             "duração inválida: %q",
+		"limits are not supported on %s":                   "limites não são suportados em %s",
+		"invalid boolean: %q":                              "booleano inválido: %q",
+		"%s is declared again, the old one renamed to %s":  "%s foi declarado novamente, o anterior renomeado para %s",
+		"invalid size: %q (0 to %d)":                       "tamanho inválido: %q (0 a %d)",
+		"invalid pattern: %s":                              "padrão inválido: %s",
+		"unknown setting: %s":                              "configuração desconhecida: %s",
+		"invalid file name: %q":                            "nome de arquivo inválido: %q",
+		"invalid checkpoint name: %s":                      "nome de ponto de controle inválido: %s",
+		"no checkpoint to roll back to":                    "não há ponto de controle para voltar",
+		"no such checkpoint: %s":                           "ponto de controle inexistente: %s",
+		"no such job: %d":                                  "job inexistente: %d",
+		"no such session: %s":                              "sessão inexistente: %s",
+		"session already exists: %s":                       "a sessão já existe: %s",
+		"sessions are not supported in this mode":          "sessões não são suportadas neste modo",
+		"invalid argument: %s":                             "argumento inválido: %s",
+		"invalid job: %s":                                  "job inválido: %s",
+		"invalid URL: %q":                                  "URL inválida: %q",
+		"unexpected end of input":                          "fim inesperado da entrada",
+		"invalid session name: %q":                         "nome de sessão inválido: %q",
+		"no store to save the sessions":                    "não há armazenamento para salvar as sessões",
+		"no saved session: %s":                             "nenhuma sessão salva: %s",
+		"timed out after %s":                               "tempo esgotado após %s",
+		"unknown GOOS: %s":                                 "GOOS desconhecido: %s",
+		"unknown GOARCH: %s":                               "GOARCH desconhecido: %s",
+		"… %d bytes omitted":                               "… %d bytes omitidos",
+		"… %d bytes omitted (written to %s)":               "… %d bytes omitidos (gravados em %s)",
+		"unsupported language: %s (en, ja or pt)":          "idioma não suportado: %s (en, ja ou pt)",
+		"program exited with code %d at statement #%d":     "o programa terminou com o código %d na instrução #%d",
+		"program exited with code %d":                      "o programa terminou com o código %d",
+		"use :drop %d to drop the statement":               "use :drop %d para descartar a instrução",
+		"warning: %s; evaluations will be slow (:set gocache <dir> to use another cache)":                                             "aviso: %s; as avaliações serão lentas (:set gocache <dir> para usar outro cache)",
+		"warning: evaluations are slow (%.1fs); %s (:set gocache <dir> to use another cache)":                                         "aviso: as avaliações estão lentas (%.1fs); %s (:set gocache <dir> para usar outro cache)",
+		"warning: evaluations are slow (%.1fs); the build cache may be full or trimmed (:set gocache <dir> to use a dedicated cache)": "aviso: as avaliações estão lentas (%.1fs); o cache de compilação pode estar cheio ou ter sido limpo (:set gocache <dir> para usar um cache dedicado)",
+		"warning: init runs on every evaluation (:set rerun-decls off to run it once)":                                                "aviso: init é executada a cada avaliação (:set rerun-decls off para executá-la uma só vez)",
+		"warning: the initializer of %s runs on every evaluation (:set rerun-decls off to run it once)":                               "aviso: o inicializador de %s é executado a cada avaliação (:set rerun-decls off para executá-lo uma só vez)",
+		"warning: the value of %s cannot be kept; its initializer runs on every evaluation":                                           "aviso: o valor de %s não pode ser mantido; seu inicializador é executado a cada avaliação",
+	},
+}
+
+// messageLanguages are the languages of the messages, English first.
+var messageLanguages = []language.Tag{language.English, language.Japanese, language.Portuguese}
+
+var messageLanguageMatcher = language.NewMatcher(messageLanguages)
+
+// matchLanguage returns the language of the messages for the tag,
+// or an empty string if not supported.
+func matchLanguage(tag language.Tag) string {
+	_, i, conf := messageLanguageMatcher.Match(tag)
+	if conf == language.No {
+		return ""
+	}
+	base, _ := messageLanguages[i].Base()
+	return base.String()
+}
+
+// localeLanguage returns the language of the messages specified by the
+// environment variables, defaulting to English.
+func localeLanguage() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v, _, _ := strings.Cut(os.Getenv(key), "."); v != "" {
+			if v == "C" || v == "POSIX" {
+				break
+			}
+			if t, err := language.Parse(v); err == nil {
+				if lang := matchLanguage(t); lang != "" {
+					return lang
+				}
+				break
+			}
+		}
+	}
+	return "en"
+}
+
+// tr translates the message to the language of the session.
+func (s *Session) tr(msg string) string {
+	if t, ok := messageCatalog[s.lang][msg]; ok {
+		return t
+	}
+	return msg
+}
+
+// errorf is fmt.Errorf with the format translated.
+func (s *Session) errorf(format string, args ...any) error {
+	return fmt.Errorf(s.tr(format), args...)
+}
+
+func setLang(s *Session, value string) error {
+	if value == "" {
+		s.lang = localeLanguage()
+		return nil
+	}
+	if t, err := language.Parse(value); err == nil {
+		if lang := matchLanguage(t); lang != "" {
+			s.lang = lang
+			return nil
+		}
+	}
+	return s.errorf("unsupported language: %s (en, ja or pt)", value)
+}
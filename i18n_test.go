@@ -0,0 +1,90 @@
+package gore
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	// the messages are tested in English regardless of the locale
+	os.Unsetenv("LC_ALL")
+	os.Unsetenv("LC_MESSAGES")
+	os.Setenv("LANG", "C")
+	os.Exit(m.Run())
+}
+
+func TestLocaleLanguage(t *testing.T) {
+	testCases := []struct {
+		lcAll, lcMessages, lang string
+		want                    string
+	}{
+		{"", "", "", "en"},
+		{"", "", "C.UTF-8", "en"},
+		{"", "", "en_US.UTF-8", "en"},
+		{"", "", "ja_JP.UTF-8", "ja"},
+		{"", "", "pt_BR.UTF-8", "pt"},
+		{"", "", "fr_FR.UTF-8", "en"},
+		{"", "ja_JP.UTF-8", "en_US.UTF-8", "ja"},
+		{"C", "", "ja_JP.UTF-8", "en"},
+	}
+	for _, tc := range testCases {
+		t.Setenv("LC_ALL", tc.lcAll)
+		t.Setenv("LC_MESSAGES", tc.lcMessages)
+		t.Setenv("LANG", tc.lang)
+		assert.Equal(t, tc.want, localeLanguage(), "LC_ALL=%q LC_MESSAGES=%q LANG=%q", tc.lcAll, tc.lcMessages, tc.lang)
+	}
+}
+
+func TestMessageCatalog(t *testing.T) {
+	// the catalogs translate the same messages
+	for lang, catalog := range messageCatalog {
+		for _, command := range commands {
+			assert.Contains(t, catalog, command.document, lang)
+		}
+		for _, st := range settings {
+			assert.Contains(t, catalog, st.document, lang)
+		}
+		assert.Equal(t, len(messageCatalog["ja"]), len(catalog), lang)
+	}
+}
+
+func TestSetting_Lang(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:help quit`,
+		`:set lang ja`,
+		`:help quit`,
+		`:drop`,
+		`:set lang pt-BR`,
+		`:set lang`,
+		`:drop`,
+		`:set foo`,
+		`:set race maybe`,
+		`:set lang fr`,
+		`:set lang ""`,
+		`:drop`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `    :quit    quit the session
+    :quit    セッションを終了する
+lang = "pt"
+`, stdout.String())
+	assert.Equal(t, `drop: 引数が必要です
+drop: o argumento é obrigatório
+set: configuração desconhecida: foo
+set: booleano inválido: "maybe"
+set: idioma não suportado: fr (en, ja ou pt)
+drop: argument is required
+`, stderr.String())
+}
@@ -0,0 +1,248 @@
+package gore
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// implMismatch is a method of an interface a type lacks.
+type implMismatch struct {
+	method  *types.Func // the method of the interface
+	found   *types.Func // the method of the type of the name, if any
+	pointer bool        // whether the method found has the pointer receiver
+}
+
+// lookupImpl returns the type and the interface of the arguments of
+// :check-impl, e.g. Point fmt.Stringer.
+func (s *Session) lookupImpl(arg string) (*types.Package, types.Type, *types.Interface, error) {
+	args := strings.Fields(arg)
+	if len(args) != 2 {
+		return nil, nil, nil, s.errorf("type and interface are required")
+	}
+	pkg, _ := s.typeCheck()
+	typ, err := s.lookupType(pkg, args[0])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	iface, err := s.lookupType(pkg, args[1])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	it, ok := iface.Underlying().(*types.Interface)
+	if !ok {
+		return nil, nil, nil, s.errorf("not an interface: %s", args[1])
+	}
+	return pkg, typ, it, nil
+}
+
+// implMismatches returns the methods of the interface the type does not
+// implement, in the order of the names.
+func implMismatches(pkg *types.Package, typ types.Type, iface *types.Interface) []implMismatch {
+	var mismatches []implMismatch
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		obj, _, _ := types.LookupFieldOrMethod(typ, false, pkg, m.Name())
+		if f, ok := obj.(*types.Func); ok && sameSignature(f, m) {
+			continue
+		}
+		mismatch := implMismatch{method: m}
+		// the methods of the pointer receiver are not of the value
+		if obj == nil {
+			if _, ok := typ.(*types.Pointer); !ok {
+				obj, _, _ = types.LookupFieldOrMethod(types.NewPointer(typ), false, pkg, m.Name())
+				mismatch.pointer = obj != nil
+			}
+		}
+		mismatch.found, _ = obj.(*types.Func)
+		mismatches = append(mismatches, mismatch)
+	}
+	return mismatches
+}
+
+// sameSignature reports whether the methods are of the same signature,
+// regardless of the parameter names. The types are compared by the package
+// paths, as the packages are loaded by each import (e.g. time.Time of context
+// is not the one imported).
+func sameSignature(f, m *types.Func) bool {
+	fs, ms := f.Type().(*types.Signature), m.Type().(*types.Signature)
+	if fs.Variadic() != ms.Variadic() {
+		return false
+	}
+	qualifier := func(p *types.Package) string { return p.Path() }
+	for _, vars := range [][2]*types.Tuple{{fs.Params(), ms.Params()}, {fs.Results(), ms.Results()}} {
+		if vars[0].Len() != vars[1].Len() {
+			return false
+		}
+		for i := 0; i < vars[0].Len(); i++ {
+			if types.TypeString(vars[0].At(i).Type(), qualifier) != types.TypeString(vars[1].At(i).Type(), qualifier) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// signatureString returns the method without func and the receiver, e.g.
+// String() string.
+func signatureString(m *types.Func, qualifier types.Qualifier) string {
+	return m.Name() + strings.TrimPrefix(types.TypeString(m.Type(), qualifier), "func")
+}
+
+// actionCheckImpl reports whether the type implements the interface, listing
+// the methods missing or of the wrong types.
+func actionCheckImpl(s *Session, arg string) error {
+	pkg, typ, iface, err := s.lookupImpl(arg)
+	if err != nil {
+		return err
+	}
+	qualifier := types.RelativeTo(pkg)
+	args := strings.Fields(arg)
+
+	mismatches := implMismatches(pkg, typ, iface)
+	if len(mismatches) == 0 {
+		fmt.Fprintf(s.stdout, s.tr("%s implements %s")+"\n", args[0], args[1])
+		return nil
+	}
+	fmt.Fprintf(s.stdout, s.tr("%s does not implement %s:")+"\n", args[0], args[1])
+	for _, m := range mismatches {
+		want := signatureString(m.method, qualifier)
+		var msg string
+		switch {
+		case m.pointer:
+			msg = fmt.Sprintf(s.tr("pointer receiver: %s"), want)
+		case m.found != nil:
+			msg = fmt.Sprintf(s.tr("wrong type: %s, want %s"), signatureString(m.found, qualifier), want)
+		default:
+			msg = fmt.Sprintf(s.tr("missing: %s"), want)
+		}
+		fmt.Fprintf(s.stdout, "    %s\n", msg)
+	}
+	return nil
+}
+
+// implReceiver returns the type declared in the session to add the methods
+// to, with whether the receiver is the pointer.
+func (s *Session) implReceiver(pkg *types.Package, typ types.Type, name string) (*types.Named, bool, error) {
+	ptr, pointer := typ.(*types.Pointer)
+	if pointer {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() != pkg {
+		return nil, false, s.errorf("not a type of the session: %s", name)
+	}
+	return named, pointer, nil
+}
+
+// receiverName returns the name of the receiver of the methods of the type,
+// by the ones declared, or by the initial of the type name.
+func receiverName(named *types.Named) string {
+	for i := 0; i < named.NumMethods(); i++ {
+		if name := named.Method(i).Type().(*types.Signature).Recv().Name(); name != "" && name != "_" {
+			return name
+		}
+	}
+	r, _ := utf8.DecodeRuneInString(named.Obj().Name())
+	return string(unicode.ToLower(r))
+}
+
+// stubSource returns the source of the method of the interface for the type,
+// which panics until filled in.
+func stubSource(named *types.Named, pointer bool, m *types.Func, qualifier types.Qualifier) string {
+	recv := named.Obj().Name()
+	if tparams := named.TypeParams(); tparams.Len() > 0 {
+		names := make([]string, tparams.Len())
+		for i := range names {
+			names[i] = tparams.At(i).Obj().Name()
+		}
+		recv += "[" + strings.Join(names, ", ") + "]"
+	}
+	if pointer {
+		recv = "*" + recv
+	}
+	name := receiverName(named)
+	sig := m.Type().(*types.Signature)
+	for _, vars := range []*types.Tuple{sig.Params(), sig.Results()} {
+		for i := 0; i < vars.Len(); i++ {
+			if vars.At(i).Name() == name {
+				name = "_"
+			}
+		}
+	}
+	return fmt.Sprintf("func (%s %s) %s {\n\tpanic(\"TODO\")\n}", name, recv, signatureString(m, qualifier))
+}
+
+// actionImpl adds the stubs of the methods of the interface the type lacks,
+// to be filled in by :edit.
+func actionImpl(s *Session, arg string) error {
+	pkg, typ, iface, err := s.lookupImpl(arg)
+	if err != nil {
+		return err
+	}
+	args := strings.Fields(arg)
+	named, pointer, err := s.implReceiver(pkg, typ, args[0])
+	if err != nil {
+		return err
+	}
+
+	// qualify the types by the names imported, importing the packages lacked
+	imported := make(map[string]bool)
+	for _, imp := range s.file.Imports {
+		imported[strings.Trim(imp.Path.Value, `"`)] = true
+	}
+	var paths []string
+	qualifier := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		if !imported[p.Path()] {
+			imported[p.Path()] = true
+			paths = append(paths, p.Path())
+		}
+		if name, ok := s.imports.names[p.Path()]; ok {
+			return name
+		}
+		return p.Name()
+	}
+
+	var stubs []string
+	for _, m := range implMismatches(pkg, typ, iface) {
+		want := signatureString(m.method, types.RelativeTo(pkg))
+		switch {
+		case m.pointer:
+			fmt.Fprintf(s.stderr, "%s\n", fmt.Sprintf(s.tr("pointer receiver: %s"), want))
+		case m.found != nil:
+			fmt.Fprintf(s.stderr, "%s\n", fmt.Sprintf(s.tr("wrong type: %s, want %s"),
+				signatureString(m.found, types.RelativeTo(pkg)), want))
+		default:
+			stubs = append(stubs, stubSource(named, pointer, m.method, qualifier))
+		}
+	}
+	if len(stubs) == 0 {
+		return nil
+	}
+
+	s.storeCode()
+	for _, path := range paths {
+		if err := s.importPackage("", path); err != nil {
+			return err
+		}
+	}
+	// name the packages imported blank to be used by the stubs
+	s.clearQuickFix()
+	for _, stub := range stubs {
+		if err := s.evalFunc(stub); err != nil {
+			s.restoreCode()
+			return err
+		}
+	}
+	if err := s.checkCode(); err != nil {
+		s.restoreCode()
+		return err
+	}
+	fmt.Fprintf(s.stdout, "%s\n", strings.Join(stubs, "\n\n"))
+	return nil
+}
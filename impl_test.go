@@ -0,0 +1,97 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_CheckImpl(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import fmt`,
+		`type Shape interface { Area() float64; Name() string; Scale(k float64) }`,
+		`type Point struct{ X, Y int }`,
+		`func (p Point) Area() int { return 0 }`,
+		`func (p *Point) Scale(k float64) {}`,
+		`func (p Point) String() string { return "point" }`,
+		`:check-impl Point Shape`,
+		`:check-impl *Point fmt.Stringer`,
+		`:check-impl Point`,
+		`:check-impl Point Point`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `Point does not implement Shape:
+    wrong type: Area() int, want Area() float64
+    missing: Name() string
+    pointer receiver: Scale(k float64)
+*Point implements fmt.Stringer
+`, stdout.String())
+	assert.Equal(t, `check-impl: type and interface are required
+check-impl: not an interface: Point
+`, stderr.String())
+}
+
+func TestAction_Impl(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import fmt context`,
+		`type Point struct{ X, Y int }`,
+		`func (pt *Point) Scale(k float64) {}`,
+		`:impl *Point fmt.Stringer`,
+		`:check-impl *Point fmt.Stringer`,
+		`type Shape interface { Scale(k float64); Area() float64 }`,
+		`:impl Point Shape`,
+		`func (pt Point) Scale(x float64) {}`,
+		`:check-impl Point Shape`,
+		`type ctx struct{}`,
+		`:impl ctx context.Context`,
+		`:check-impl ctx context.Context`,
+		`:impl int fmt.Stringer`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `func (pt *Point) String() string {
+	panic("TODO")
+}
+*Point implements fmt.Stringer
+func (pt Point) Area() float64 {
+	panic("TODO")
+}
+Point implements Shape
+func (c ctx) Deadline() (deadline time.Time, ok bool) {
+	panic("TODO")
+}
+
+func (c ctx) Done() <-chan struct{} {
+	panic("TODO")
+}
+
+func (c ctx) Err() error {
+	panic("TODO")
+}
+
+func (c ctx) Value(key any) any {
+	panic("TODO")
+}
+ctx implements context.Context
+`, stdout.String())
+	assert.Equal(t, `pointer receiver: Scale(k float64)
+impl: not a type of the session: int
+`, stderr.String())
+}
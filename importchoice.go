@@ -0,0 +1,126 @@
+package gore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// importState is the state of the imports of the session.
+type importState struct {
+	choices map[string]string   // the package paths chosen for the names
+	names   map[string]string   // the names of the imports given by :import, by the paths
+	std     map[string][]string // the package paths of the names in std
+	chooser func(prompt string, options []string) int
+}
+
+// chooseImports adds the imports of the packages chosen by the user, for the
+// package names with several candidates in the standard library (e.g. rand
+// of crypto/rand and math/rand), before auto-importing picks one of them.
+// The choices are remembered for the session.
+func (s *Session) chooseImports(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.Mode(0))
+	if err != nil {
+		return nil, err
+	}
+
+	var added bool
+	for _, name := range missingPackages(f) {
+		path, ok := s.imports.choices[name]
+		if !ok {
+			if s.imports.chooser == nil {
+				continue
+			}
+			candidates := s.stdPackagesNamed(name)
+			if len(candidates) < 2 {
+				continue
+			}
+			i := s.imports.chooser(fmt.Sprintf(s.tr("choose the package of %s"), name), candidates)
+			if i < 0 || i >= len(candidates) {
+				continue
+			}
+			path = candidates[i]
+			if s.imports.choices == nil {
+				s.imports.choices = map[string]string{}
+			}
+			s.imports.choices[name] = path
+		}
+		added = astutil.AddImport(fset, f, path) || added
+	}
+	if !added {
+		return src, nil
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// missingPackages returns the names used as packages but not declared nor
+// imported in the file.
+func missingPackages(f *ast.File) []string {
+	imported := map[string]bool{}
+	for _, imp := range f.Imports {
+		if imp.Name != nil {
+			imported[imp.Name.Name] = true
+		} else if path, err := strconv.Unquote(imp.Path.Value); err == nil {
+			imported[importName(path)] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil && !imported[x.Name] && !seen[x.Name] && types.Universe.Lookup(x.Name) == nil {
+			seen[x.Name] = true
+			names = append(names, x.Name)
+		}
+		return true
+	})
+	return names
+}
+
+// stdPackagesNamed returns the import paths of the packages of the name in
+// the standard library.
+func (s *Session) stdPackagesNamed(name string) []string {
+	if s.imports.std == nil {
+		s.imports.std = map[string][]string{}
+		cmd := exec.Command("go", "list", "-f", "{{.Name}} {{.ImportPath}}", "std")
+		cmd.Env = s.goEnviron()
+		out, err := cmd.Output()
+		if err != nil {
+			debugf("go list std: %s", err)
+			return nil
+		}
+		sc := bufio.NewScanner(bytes.NewReader(out))
+		for sc.Scan() {
+			name, path, _ := strings.Cut(sc.Text(), " ")
+			if strings.Contains(path, "internal") || strings.HasPrefix(path, "vendor/") {
+				continue
+			}
+			s.imports.std[name] = append(s.imports.std[name], path)
+		}
+		for _, paths := range s.imports.std {
+			sort.Strings(paths)
+		}
+	}
+	return s.imports.std[name]
+}
@@ -0,0 +1,339 @@
+package gore
+
+import (
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// typeCheck type-checks the session ignoring the errors,
+// and returns the package and the information.
+func (s *Session) typeCheck() (*types.Package, *types.Info) {
+	info := &types.Info{
+		Types:     make(map[ast.Expr]types.TypeAndValue),
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+		Scopes:    make(map[ast.Node]*types.Scope),
+	}
+	conf := *s.types
+	conf.Error = func(err error) {
+		debugf("typecheck error (ignored): %s", err)
+	}
+	pkg, _ := conf.Check("_tmp", s.fset, append(s.extraFiles, s.file), info)
+	return pkg, info
+}
+
+// stmtOf returns the number of the statement in main containing the position,
+// or 0 if not found.
+func (s *Session) stmtOf(pos token.Pos) int {
+	for i, stmt := range s.mainBody.List {
+		if stmt.Pos() <= pos && pos < stmt.End() {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// nodeSummary returns the first line of the source of the node.
+func (s *Session) nodeSummary(node ast.Node) string {
+	var sb strings.Builder
+	if err := printer.Fprint(&sb, s.fset, node); err != nil {
+		return ""
+	}
+	line, _, multi := strings.Cut(sb.String(), "\n")
+	if multi {
+		line += " ..."
+	}
+	return line
+}
+
+func isGoreName(name string) bool {
+	return strings.HasPrefix(name, "__gore_")
+}
+
+func actionVars(s *Session, _ string) error {
+	_, info := s.typeCheck()
+
+	type variable struct {
+		obj  types.Object
+		stmt int
+	}
+	var vars []variable
+	scope := info.Scopes[s.mainFunc().Type]
+	if scope != nil {
+		for _, name := range scope.Names() {
+			if obj, ok := scope.Lookup(name).(*types.Var); ok && name != "_" && !isGoreName(name) {
+				vars = append(vars, variable{obj, s.stmtOf(obj.Pos())})
+			}
+		}
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].obj.Pos() < vars[j].obj.Pos() })
+
+	w := tabwriter.NewWriter(s.stdout, 0, 8, 4, ' ', 0)
+	for _, v := range vars {
+		var introduced string
+		if v.stmt > 0 {
+			introduced = fmt.Sprintf("#%d\t%s", v.stmt, s.nodeSummary(s.mainBody.List[v.stmt-1]))
+		}
+		fmt.Fprintf(w, "    %s\t%s\t%s\n", v.obj.Name(), types.TypeString(v.obj.Type(), types.RelativeTo(v.obj.Pkg())), introduced)
+	}
+	return w.Flush()
+}
+
+// sessionFuncs returns the functions declared in the session.
+func (s *Session) sessionFuncs(info *types.Info) []*types.Func {
+	var funcs []*types.Func
+	for _, decl := range s.file.Decls {
+		decl, ok := decl.(*ast.FuncDecl)
+		if !ok || decl.Recv != nil || decl == s.mainFunc() || isGoreName(decl.Name.Name) {
+			continue
+		}
+		if fn, ok := info.Defs[decl.Name].(*types.Func); ok {
+			funcs = append(funcs, fn)
+		}
+	}
+	return funcs
+}
+
+func actionFuncs(s *Session, _ string) error {
+	pkg, info := s.typeCheck()
+	for _, fn := range s.sessionFuncs(info) {
+		sig := types.TypeString(fn.Type(), types.RelativeTo(pkg))
+		fmt.Fprintf(s.stdout, "    func %s%s\n", fn.Name(), strings.TrimPrefix(sig, "func"))
+	}
+	return nil
+}
+
+func actionTypes(s *Session, _ string) error {
+	pkg, info := s.typeCheck()
+	qualifier := types.RelativeTo(pkg)
+	for _, decl := range s.file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.TypeSpec)
+			obj, ok := info.Defs[spec.Name].(*types.TypeName)
+			if !ok || isGoreName(obj.Name()) {
+				continue
+			}
+			if obj.IsAlias() {
+				fmt.Fprintf(s.stdout, "    type %s = %s\n", obj.Name(), types.TypeString(obj.Type(), qualifier))
+				continue
+			}
+			fmt.Fprintf(s.stdout, "    type %s %s\n", obj.Name(), types.TypeString(obj.Type().Underlying(), qualifier))
+			named, ok := obj.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+			for i := 0; i < named.NumMethods(); i++ {
+				fmt.Fprintf(s.stdout, "        %s\n", methodString(named.Method(i), qualifier))
+			}
+		}
+	}
+	return nil
+}
+
+// methodString returns the declaration of the method without the body.
+func methodString(m *types.Func, qualifier types.Qualifier) string {
+	sig := m.Type().(*types.Signature)
+	return fmt.Sprintf("func (%s) %s%s", types.TypeString(sig.Recv().Type(), qualifier),
+		m.Name(), strings.TrimPrefix(types.TypeString(sig, qualifier), "func"))
+}
+
+func actionMethods(s *Session, arg string) error {
+	pkg, info := s.typeCheck()
+	qualifier := types.RelativeTo(pkg)
+
+	var typs []types.Type
+	if arg == "" {
+		for _, decl := range s.file.Decls {
+			if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.TYPE {
+				for _, spec := range decl.Specs {
+					if obj, ok := info.Defs[spec.(*ast.TypeSpec).Name].(*types.TypeName); ok && !obj.IsAlias() && !isGoreName(obj.Name()) {
+						typs = append(typs, obj.Type())
+					}
+				}
+			}
+		}
+	} else {
+		typ, err := s.lookupType(pkg, arg)
+		if err != nil {
+			return err
+		}
+		typs = append(typs, typ)
+	}
+
+	for _, typ := range typs {
+		// the method set of the pointer includes the methods of both receivers
+		mset := types.NewMethodSet(typ)
+		if _, ok := typ.Underlying().(*types.Interface); !ok {
+			if _, ok := typ.(*types.Pointer); !ok {
+				mset = types.NewMethodSet(types.NewPointer(typ))
+			}
+		}
+		for i := 0; i < mset.Len(); i++ {
+			fmt.Fprintf(s.stdout, "    %s\n", methodString(mset.At(i).Obj().(*types.Func), qualifier))
+		}
+	}
+	return nil
+}
+
+// lookupType returns the type of the name, which is a type of the session
+// or of an imported package (e.g. strings.Builder), optionally with *.
+func (s *Session) lookupType(pkg *types.Package, name string) (types.Type, error) {
+	if strings.HasPrefix(name, "*") {
+		typ, err := s.lookupType(pkg, strings.TrimSpace(name[1:]))
+		if err != nil {
+			return nil, err
+		}
+		return types.NewPointer(typ), nil
+	}
+
+	scope := pkg.Scope()
+	if pkgName, typeName, ok := strings.Cut(name, "."); ok {
+		scope = nil
+		for _, imp := range pkg.Imports() {
+			if imp.Name() == pkgName {
+				scope = imp.Scope()
+				break
+			}
+		}
+		if scope == nil {
+			return nil, s.errorf("not imported: %s", pkgName)
+		}
+		name = typeName
+	}
+	_, obj := scope.LookupParent(name, token.NoPos)
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil, s.errorf("not a type: %s", name)
+	}
+	return obj.Type(), nil
+}
+
+// sessionImport is an import of the session.
+type sessionImport struct {
+	spec *ast.ImportSpec
+	used bool
+}
+
+// sessionImports returns the imports of the session, except for the ones
+// used only by gore (e.g. the printer).
+func (s *Session) sessionImports(info *types.Info) []sessionImport {
+	// the functions of gore, whose uses are not counted
+	var goreFuncs []ast.Node
+	for _, decl := range s.file.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && isGoreName(decl.Name.Name) {
+			goreFuncs = append(goreFuncs, decl)
+		}
+	}
+	byGore := func(pos token.Pos) bool {
+		for _, fn := range goreFuncs {
+			if fn.Pos() <= pos && pos < fn.End() {
+				return true
+			}
+		}
+		return false
+	}
+
+	var imports []sessionImport
+	for _, spec := range s.file.Imports {
+		obj := info.Implicits[spec]
+		if spec.Name != nil && info.Defs[spec.Name] != nil {
+			obj = info.Defs[spec.Name]
+		}
+		// the members of the package imported by . are used without the name
+		var dotPkg *types.Package
+		if pkgName, ok := obj.(*types.PkgName); ok && spec.Name != nil && spec.Name.Name == "." {
+			dotPkg = pkgName.Imported()
+		}
+		var used, usedByGore bool
+		for id, o := range info.Uses {
+			if obj != nil && (o == obj || dotPkg != nil && o.Pkg() == dotPkg) {
+				if byGore(id.Pos()) {
+					usedByGore = true
+				} else {
+					used = true
+				}
+			}
+		}
+		if !used && usedByGore {
+			continue
+		}
+		imports = append(imports, sessionImport{spec, used})
+	}
+	return imports
+}
+
+func actionImports(s *Session, _ string) error {
+	_, info := s.typeCheck()
+	w := tabwriter.NewWriter(s.stdout, 0, 8, 4, ' ', 0)
+	for _, imp := range s.sessionImports(info) {
+		spec := imp.spec.Path.Value
+		if imp.spec.Name != nil && imp.spec.Name.Name != "_" {
+			spec = imp.spec.Name.Name + " " + spec
+		}
+		if !imp.used {
+			spec += "\tunused"
+		}
+		fmt.Fprintf(w, "    %s\n", spec)
+	}
+	return w.Flush()
+}
+
+// actionDeps lists the modules of the imports of the session, except for
+// the standard library, with the versions resolved.
+func actionDeps(s *Session, _ string) error {
+	_, info := s.typeCheck()
+	var paths []string
+	for _, imp := range s.sessionImports(info) {
+		if path, err := strconv.Unquote(imp.spec.Path.Value); err == nil && path != "C" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:       packages.NeedName | packages.NeedModule,
+		Dir:        s.tempDir,
+		Env:        s.loadEnviron(),
+		BuildFlags: s.buildFlags(),
+	}, paths...)
+	if err != nil {
+		return err
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].PkgPath < pkgs[j].PkgPath })
+
+	w := tabwriter.NewWriter(s.stdout, 0, 8, 4, ' ', 0)
+	for _, pkg := range pkgs {
+		m := pkg.Module
+		if m == nil {
+			continue
+		}
+		mod := m.Path
+		if m.Version != "" {
+			mod += "@" + m.Version
+		}
+		from := "cache"
+		if r := m.Replace; r != nil {
+			from = "replace => " + r.Path
+			if r.Version != "" {
+				from += "@" + r.Version
+			}
+		}
+		fmt.Fprintf(w, "    %s\t%s\t%s\n", pkg.PkgPath, mod, from)
+	}
+	return w.Flush()
+}
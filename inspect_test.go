@@ -0,0 +1,186 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Vars(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:vars`,
+		`type T struct{ n int }`,
+		`x := 1`,
+		`s, t := "foo", T{3}`,
+		`for i := 0; i < 3; i++ {
+	x += i
+}`,
+		`var f func(int) error`,
+		`_ = t`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+	stdout.Reset()
+
+	_, err = s.Eval(`:vars`)
+	require.NoError(t, err)
+	assert.Equal(t, `    x    int                #1    x := 1
+    s    string             #2    s, t := "foo", T{3}
+    t    T                  #2    s, t := "foo", T{3}
+    f    func(int) error    #4    var f func(int) error
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestAction_Funcs(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:funcs`,
+		`type T struct{}`,
+		`func (T) M() {}`,
+		`func f(n int) (int, error) { return n, nil }`,
+		`func g(t T, xs ...string) *T { return &t }`,
+		`:funcs`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `    func f(n int) (int, error)
+    func g(t T, xs ...string) *T
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestAction_Types(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:types`,
+		`type T struct{ n int }`,
+		`func (t T) N() int { return t.n }`,
+		`func (t *T) Set(n int) { t.n = n }`,
+		`type (
+	S []string
+	A = map[string]T
+)`,
+		`:types`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `    type T struct{n int}
+        func (T) N() int
+        func (*T) Set(n int)
+    type S []string
+    type A = map[string]T
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestAction_Methods(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import strings`,
+		`type P struct{ X, Y float64 }`,
+		`type Q int`,
+		`func Norm() int { return 0 }`,
+		`func (p P) Norm() float64 { return p.X + p.Y }`,
+		`func (p *P) Scale(k float64) { p.X *= k; p.Y *= k }`,
+		`func (q Q) Norm() int { return int(q) }`,
+		`func (p P) Norm() float64 { return p.X * p.Y }`,
+		`P{2, 3}.Norm()`,
+		`Q(4).Norm() + Norm()`,
+	}
+	for _, code := range codes {
+		_, err = s.Eval(code)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "6\n4\n", stdout.String())
+	stdout.Reset()
+
+	_, err = s.Eval(`:methods`)
+	require.NoError(t, err)
+	assert.Equal(t, `    func (P) Norm() float64
+    func (*P) Scale(k float64)
+    func (Q) Norm() int
+`, stdout.String())
+	stdout.Reset()
+
+	_, err = s.Eval(`:methods strings.Builder`)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "    func (*strings.Builder) WriteString(s string) (int, error)\n")
+	_, err = s.Eval(`:methods R`)
+	assert.Error(t, err)
+	assert.Equal(t, "methods: not a type: R\n", stderr.String())
+}
+
+func TestAction_Imports(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:imports`,
+		`:import strings`,
+		`:import os`,
+		`strings.ToUpper("x")`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+	stdout.Reset()
+
+	_, err = s.Eval(`:imports`)
+	require.NoError(t, err)
+	assert.Equal(t, `    "os"    unused
+    "strings"
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestAction_Deps(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:deps`,
+		`:import strings`,
+		`:import github.com/x-motemen/gore/gocode`,
+	}
+	for _, code := range codes {
+		_, err = s.Eval(code)
+		require.NoError(t, err)
+	}
+	stdout.Reset()
+
+	_, err = s.Eval(`:deps`)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "    github.com/x-motemen/gore/gocode    github.com/x-motemen/gore")
+	assert.Contains(t, stdout.String(), "replace => ")
+	assert.NotContains(t, stdout.String(), "strings")
+	assert.Equal(t, "", stderr.String())
+}
@@ -0,0 +1,207 @@
+package gore
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// The jobs of :job run the program with the statement appended in the
+// background, while the next inputs are read. The program is built as the
+// input is run, but the statement is not added to the session. The outputs
+// of the jobs are kept, and reported before the next prompt after the jobs
+// end, as a shell does.
+
+// job is the program running in the background.
+type job struct {
+	id      int
+	code    string
+	process *os.Process
+	start   time.Time
+	output  lockedBuffer
+	done    bool
+	killed  bool
+	err     error
+}
+
+// lockedBuffer is the buffer written by the program running.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
+
+// jobList is the list of the jobs not reported yet.
+type jobList struct {
+	mu   sync.Mutex
+	jobs []*job
+	next int
+}
+
+// add starts the job of the command, and waits for it in the background.
+func (l *jobList) add(code string, cmd *exec.Cmd, path string) (*job, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.next++
+	j := &job{id: l.next, code: code, start: time.Now()}
+	cmd.Stdout, cmd.Stderr = &j.output, &j.output
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	j.process = cmd.Process
+	l.jobs = append(l.jobs, j)
+	go func() {
+		err := cmd.Wait()
+		os.Remove(path)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		j.done, j.err = true, err
+	}()
+	return j, nil
+}
+
+// lookup returns the job of the id.
+func (l *jobList) lookup(id int) *job {
+	for _, j := range l.jobs {
+		if j.id == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// takeDone removes the jobs ended from the list, and returns them.
+func (l *jobList) takeDone() []*job {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var done, running []*job
+	for _, j := range l.jobs {
+		if j.done {
+			done = append(done, j)
+		} else {
+			running = append(running, j)
+		}
+	}
+	l.jobs = running
+	return done
+}
+
+// killAll kills the jobs running, e.g. on quitting.
+func (l *jobList) killAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, j := range l.jobs {
+		if !j.done {
+			j.killed = true
+			j.process.Kill()
+		}
+	}
+}
+
+func actionJob(s *Session, in string) error {
+	if in == "" {
+		return s.errorf("argument is required")
+	}
+
+	s.storeCode()
+	defer s.restoreCode()
+	if _, err := s.evalInput(in); err != nil {
+		return syntaxError(err)
+	}
+	s.addUsedResults()
+	s.doQuickFix()
+	if err := s.writeSource(); err != nil {
+		return err
+	}
+
+	s.jobs.mu.Lock()
+	name := "gore_job_" + strconv.Itoa(s.jobs.next+1)
+	s.jobs.mu.Unlock()
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	path := filepath.Join(s.tempDir, name)
+	args := append(append([]string{"build", "-o", path}, s.runFlags()...), s.runFiles()...)
+	if err := s.goExec(args, s.stdout); err != nil {
+		debugf("job :: err = %s", err)
+		return s.errorf("could not build the program")
+	}
+
+	cmd := s.programCommand(path, s.args...)
+	cmd.Env = s.environ()
+	if s.stdinData != nil {
+		cmd.Stdin = bytes.NewReader(s.stdinData)
+	}
+	j, err := s.jobs.add(in, cmd, path)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	fmt.Fprintf(s.stderr, "[%d] %d\n", j.id, j.process.Pid)
+	return nil
+}
+
+func actionJobs(s *Session, _ string) error {
+	s.reportJobs()
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+	w := tabwriter.NewWriter(s.stdout, 0, 8, 4, ' ', 0)
+	for _, j := range s.jobs.jobs {
+		elapsed := time.Since(j.start).Round(time.Second)
+		fmt.Fprintf(w, "    [%d]\trunning\t%s\t%s\n", j.id, elapsed, j.code)
+	}
+	return w.Flush()
+}
+
+func actionKill(s *Session, arg string) error {
+	if arg == "" {
+		return s.errorf("argument is required")
+	}
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return s.errorf("invalid job: %s", arg)
+	}
+	s.jobs.mu.Lock()
+	j := s.jobs.lookup(id)
+	if j == nil || j.done {
+		s.jobs.mu.Unlock()
+		return s.errorf("no such job: %d", id)
+	}
+	j.killed = true
+	err = j.process.Kill()
+	s.jobs.mu.Unlock()
+	return err
+}
+
+// reportJobs prints the outputs of the jobs ended, with the statuses.
+func (s *Session) reportJobs() {
+	for _, j := range s.jobs.takeDone() {
+		status := "done"
+		switch {
+		case j.killed:
+			status = "killed"
+		case j.err != nil:
+			status = j.err.Error()
+		}
+		fmt.Fprintf(s.stderr, "[%d] %s: %s\n", j.id, status, j.code)
+		s.stdout.Write(j.output.Bytes())
+	}
+}
@@ -0,0 +1,59 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Job(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import fmt time`,
+		`x := 3`,
+		`:job fmt.Println(x * 2)`,
+		`:job time.Sleep(time.Minute)`,
+		`x`,
+	}
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err, code)
+	}
+	assert.Equal(t, "3\n3\n", stdout.String())
+	assert.Regexp(t, `^\[1\] \d+\n\[2\] \d+\n$`, stderr.String())
+	assert.Equal(t, "func main() { x := 3; __gore_p(x) }", strings.Join(strings.Fields(showNode(s.fset, s.mainFunc())), " "))
+
+	require.Eventually(t, func() bool {
+		s.jobs.mu.Lock()
+		defer s.jobs.mu.Unlock()
+		return s.jobs.jobs[0].done
+	}, 10*time.Second, 10*time.Millisecond)
+	stdout.Reset()
+	stderr.Reset()
+
+	_, err = s.Eval(`:jobs`)
+	require.NoError(t, err)
+	assert.Regexp(t, `^6\n2\n<nil>\n    \[2\]    running    \d+s    time.Sleep\(time.Minute\)\n$`, stdout.String())
+	assert.Equal(t, "[1] done: fmt.Println(x * 2)\n", stderr.String())
+	stdout.Reset()
+	stderr.Reset()
+
+	_, err = s.Eval(`:kill 2`)
+	require.NoError(t, err)
+	_, err = s.Eval(`:kill 3`)
+	require.Error(t, err)
+	require.Eventually(t, func() bool {
+		s.jobs.mu.Lock()
+		defer s.jobs.mu.Unlock()
+		return s.jobs.jobs[0].done
+	}, 10*time.Second, 10*time.Millisecond)
+	s.reportJobs()
+	assert.Equal(t, "kill: no such job: 3\n[2] killed: time.Sleep(time.Minute)\n", stderr.String())
+}
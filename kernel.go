@@ -0,0 +1,452 @@
+package gore
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/x-motemen/gore/zmtp"
+)
+
+// kernelConnection is the connection file given by Jupyter.
+type kernelConnection struct {
+	Transport       string `json:"transport"`
+	IP              string `json:"ip"`
+	ShellPort       int    `json:"shell_port"`
+	IOPubPort       int    `json:"iopub_port"`
+	StdinPort       int    `json:"stdin_port"`
+	ControlPort     int    `json:"control_port"`
+	HBPort          int    `json:"hb_port"`
+	SignatureScheme string `json:"signature_scheme"`
+	Key             string `json:"key"`
+}
+
+type kernelHeader struct {
+	MsgID    string `json:"msg_id"`
+	Session  string `json:"session"`
+	Username string `json:"username"`
+	Date     string `json:"date"`
+	MsgType  string `json:"msg_type"`
+	Version  string `json:"version"`
+}
+
+type kernelMessage struct {
+	Identities   [][]byte
+	Header       kernelHeader
+	ParentHeader json.RawMessage
+	Metadata     json.RawMessage
+	Content      json.RawMessage
+}
+
+const (
+	kernelProtocolVersion = "5.3"
+	kernelDelimiter       = "<IDS|MSG>"
+)
+
+type kernel struct {
+	session      *Session
+	mac          func() hash.Hash
+	mu           sync.Mutex // serializes evaluations
+	iopub        *kernelPublisher
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+// runKernel serves the session as a Jupyter kernel with the connection file.
+func (s *Session) runKernel(connectionFile string) error {
+	content, err := os.ReadFile(connectionFile)
+	if err != nil {
+		return err
+	}
+	var conn kernelConnection
+	if err := json.Unmarshal(content, &conn); err != nil {
+		return fmt.Errorf("%s: %w", connectionFile, err)
+	}
+	if conn.Transport != "tcp" {
+		return fmt.Errorf("unsupported transport: %s", conn.Transport)
+	}
+
+	// the outputs are published as the streams of the executions
+	s.setOutput(io.Discard, io.Discard)
+
+	k := &kernel{session: s, iopub: &kernelPublisher{}, shutdown: make(chan struct{})}
+	if conn.Key != "" {
+		if conn.SignatureScheme != "hmac-sha256" {
+			return fmt.Errorf("unsupported signature scheme: %s", conn.SignatureScheme)
+		}
+		key := []byte(conn.Key)
+		k.mac = func() hash.Hash { return hmac.New(sha256.New, key) }
+	}
+
+	listen := func(port int, socketType string, handle func(*zmtp.Conn)) error {
+		l, err := zmtp.Listen("tcp", fmt.Sprintf("%s:%d", conn.IP, port), socketType)
+		if err != nil {
+			return err
+		}
+		go func() {
+			<-k.shutdown
+			l.Close()
+		}()
+		go func() {
+			for {
+				c, err := l.Accept()
+				if err != nil {
+					select {
+					case <-k.shutdown:
+						return
+					default:
+					}
+					debugf("kernel: accept: %s", err)
+					continue
+				}
+				go handle(c)
+			}
+		}()
+		return nil
+	}
+
+	for _, l := range []struct {
+		port       int
+		socketType string
+		handle     func(*zmtp.Conn)
+	}{
+		{conn.ShellPort, "ROUTER", k.serveConn},
+		{conn.ControlPort, "ROUTER", k.serveConn},
+		{conn.StdinPort, "ROUTER", k.drain},
+		{conn.IOPubPort, "PUB", k.iopub.add},
+		{conn.HBPort, "REP", k.heartbeat},
+	} {
+		if err := listen(l.port, l.socketType, l.handle); err != nil {
+			k.stop()
+			return err
+		}
+	}
+
+	<-k.shutdown
+	return nil
+}
+
+func (k *kernel) stop() {
+	k.shutdownOnce.Do(func() { close(k.shutdown) })
+}
+
+// heartbeat echoes the messages back.
+func (k *kernel) heartbeat(c *zmtp.Conn) {
+	defer c.Close()
+	for {
+		msg, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := c.WriteMessage(msg); err != nil {
+			return
+		}
+	}
+}
+
+// drain discards the messages, used for the stdin channel as input requests
+// are not supported.
+func (k *kernel) drain(c *zmtp.Conn) {
+	defer c.Close()
+	for {
+		if _, err := c.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// kernelPublisher broadcasts the messages to the subscribers of iopub.
+type kernelPublisher struct {
+	mu    sync.Mutex
+	conns []*zmtp.Conn
+}
+
+func (p *kernelPublisher) add(c *zmtp.Conn) {
+	p.mu.Lock()
+	p.conns = append(p.conns, c)
+	p.mu.Unlock()
+
+	// read the subscriptions; all the messages are sent regardless of them
+	for {
+		if _, err := c.ReadMessage(); err != nil {
+			p.remove(c)
+			return
+		}
+	}
+}
+
+func (p *kernelPublisher) remove(c *zmtp.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, conn := range p.conns {
+		if conn == c {
+			p.conns = append(p.conns[:i], p.conns[i+1:]...)
+			break
+		}
+	}
+	c.Close()
+}
+
+func (p *kernelPublisher) publish(frames [][]byte) {
+	p.mu.Lock()
+	conns := append([]*zmtp.Conn(nil), p.conns...)
+	p.mu.Unlock()
+	for _, c := range conns {
+		if err := c.WriteMessage(frames); err != nil {
+			p.remove(c)
+		}
+	}
+}
+
+func (k *kernel) serveConn(c *zmtp.Conn) {
+	defer c.Close()
+	for {
+		frames, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		msg, err := k.parseMessage(frames)
+		if err != nil {
+			debugf("kernel: %s", err)
+			continue
+		}
+		reply := func(msgType string, content any) {
+			frames, err := k.encodeMessage(msg.Identities, msg, msgType, content)
+			if err == nil {
+				err = c.WriteMessage(frames)
+			}
+			if err != nil {
+				debugf("kernel: reply %s: %s", msgType, err)
+			}
+		}
+		k.handle(msg, reply)
+	}
+}
+
+func (k *kernel) handle(msg *kernelMessage, reply func(string, any)) {
+	k.publish(msg, "status", map[string]string{"execution_state": "busy"})
+	defer k.publish(msg, "status", map[string]string{"execution_state": "idle"})
+
+	switch msg.Header.MsgType {
+	case "kernel_info_request":
+		reply("kernel_info_reply", map[string]any{
+			"status":                 "ok",
+			"protocol_version":       kernelProtocolVersion,
+			"implementation":         "gore",
+			"implementation_version": Version,
+			"language_info": map[string]string{
+				"name":           "go",
+				"version":        strings.TrimPrefix(runtime.Version(), "go"),
+				"mimetype":       "text/x-go",
+				"file_extension": ".go",
+			},
+			"banner": "gore version " + Version,
+		})
+
+	case "execute_request":
+		var content struct {
+			Code   string `json:"code"`
+			Silent bool   `json:"silent"`
+		}
+		if err := json.Unmarshal(msg.Content, &content); err != nil {
+			debugf("kernel: %s", err)
+			return
+		}
+		reply("execute_reply", k.execute(msg, content.Code, content.Silent))
+
+	case "complete_request":
+		var content struct {
+			Code      string `json:"code"`
+			CursorPos int    `json:"cursor_pos"`
+		}
+		if err := json.Unmarshal(msg.Content, &content); err != nil {
+			debugf("kernel: %s", err)
+			return
+		}
+		// cursor_pos counts unicode code points
+		pos := len(content.Code)
+		for i := range content.Code {
+			if content.CursorPos == 0 {
+				pos = i
+				break
+			}
+			content.CursorPos--
+		}
+		// complete the line at the cursor
+		start := strings.LastIndexByte(content.Code[:pos], '\n') + 1
+		end := len(content.Code)
+		if i := strings.IndexByte(content.Code[pos:], '\n'); i >= 0 {
+			end = pos + i
+		}
+		k.mu.Lock()
+		prefix, cands, suffix := k.session.completeWord(content.Code[start:end], pos-start)
+		k.mu.Unlock()
+		if cands == nil {
+			cands = []string{}
+		}
+		reply("complete_reply", map[string]any{
+			"status":       "ok",
+			"matches":      cands,
+			"cursor_start": utf8.RuneCountInString(content.Code[:start+len(prefix)]),
+			"cursor_end":   utf8.RuneCountInString(content.Code[:end-len(suffix)]),
+			"metadata":     map[string]any{},
+		})
+
+	case "is_complete_request":
+		reply("is_complete_reply", map[string]string{"status": "unknown"})
+
+	case "interrupt_request":
+		// the control channel is served while the execution is running
+		if err := k.session.running.interrupt(); err != nil {
+			reply("interrupt_reply", map[string]string{"status": "error", "ename": "InterruptError", "evalue": err.Error()})
+			break
+		}
+		reply("interrupt_reply", map[string]string{"status": "ok"})
+
+	case "shutdown_request":
+		var content struct {
+			Restart bool `json:"restart"`
+		}
+		_ = json.Unmarshal(msg.Content, &content)
+		reply("shutdown_reply", map[string]any{"status": "ok", "restart": content.Restart})
+		k.stop()
+
+	default:
+		debugf("kernel: unsupported message: %s", msg.Header.MsgType)
+	}
+}
+
+func (k *kernel) execute(msg *kernelMessage, code string, silent bool) map[string]any {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// the execution count is the input number of the session
+	k.publish(msg, "execute_input", map[string]any{"code": code, "execution_count": k.session.inputNumber + 1})
+
+	r, err := k.session.Eval(code)
+	if err == ErrContinue {
+		r.Error = "incomplete input\n"
+	}
+	if !silent {
+		if r.Output != "" {
+			k.publish(msg, "stream", map[string]string{"name": "stdout", "text": r.Output})
+		}
+		if r.Error != "" {
+			k.publish(msg, "stream", map[string]string{"name": "stderr", "text": r.Error})
+		}
+	}
+
+	if err != nil && err != ErrQuit {
+		evalue := strings.TrimSpace(r.Error)
+		if evalue == "" {
+			evalue = err.Error()
+		}
+		k.publish(msg, "error", map[string]any{"ename": "Error", "evalue": evalue, "traceback": []string{}})
+		return map[string]any{
+			"status":          "error",
+			"execution_count": k.session.inputNumber,
+			"ename":           "Error",
+			"evalue":          evalue,
+			"traceback":       []string{},
+		}
+	}
+	return map[string]any{
+		"status":           "ok",
+		"execution_count":  k.session.inputNumber,
+		"user_expressions": map[string]any{},
+		"payload":          []any{},
+	}
+}
+
+func (k *kernel) publish(parent *kernelMessage, msgType string, content any) {
+	frames, err := k.encodeMessage([][]byte{[]byte(msgType)}, parent, msgType, content)
+	if err != nil {
+		debugf("kernel: publish %s: %s", msgType, err)
+		return
+	}
+	k.iopub.publish(frames)
+}
+
+func (k *kernel) sign(parts ...[]byte) []byte {
+	if k.mac == nil {
+		return nil
+	}
+	mac := k.mac()
+	for _, p := range parts {
+		mac.Write(p)
+	}
+	return []byte(hex.EncodeToString(mac.Sum(nil)))
+}
+
+func (k *kernel) parseMessage(frames [][]byte) (*kernelMessage, error) {
+	i := 0
+	for i < len(frames) && string(frames[i]) != kernelDelimiter {
+		i++
+	}
+	if len(frames) < i+6 {
+		return nil, errors.New("malformed message")
+	}
+
+	parts := frames[i+2 : i+6]
+	if sig := k.sign(parts...); !hmac.Equal(sig, frames[i+1]) {
+		return nil, errors.New("invalid signature")
+	}
+
+	msg := &kernelMessage{
+		Identities:   frames[:i],
+		ParentHeader: parts[1],
+		Metadata:     parts[2],
+		Content:      parts[3],
+	}
+	if err := json.Unmarshal(parts[0], &msg.Header); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (k *kernel) encodeMessage(identities [][]byte, parent *kernelMessage, msgType string, content any) ([][]byte, error) {
+	header, err := json.Marshal(kernelHeader{
+		MsgID:    newMessageID(),
+		Session:  parent.Header.Session,
+		Username: "gore",
+		Date:     time.Now().UTC().Format(time.RFC3339Nano),
+		MsgType:  msgType,
+		Version:  kernelProtocolVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	parentHeader, err := json.Marshal(parent.Header)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	parts := [][]byte{header, parentHeader, []byte("{}"), body}
+
+	frames := append([][]byte{}, identities...)
+	frames = append(frames, []byte(kernelDelimiter), k.sign(parts...))
+	return append(frames, parts...), nil
+}
+
+func newMessageID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprint(time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,142 @@
+package gore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"hash"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/x-motemen/gore/zmtp"
+)
+
+func TestKernel_message(t *testing.T) {
+	k := &kernel{mac: func() hash.Hash { return hmac.New(sha256.New, []byte("secret")) }}
+	parent := &kernelMessage{Header: kernelHeader{MsgID: "1", Session: "s", MsgType: "execute_request"}}
+
+	frames, err := k.encodeMessage([][]byte{[]byte("id")}, parent, "execute_reply", map[string]int{"execution_count": 1})
+	require.NoError(t, err)
+	require.Len(t, frames, 7)
+	assert.Equal(t, "id", string(frames[0]))
+	assert.Equal(t, kernelDelimiter, string(frames[1]))
+
+	msg, err := k.parseMessage(frames)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("id")}, msg.Identities)
+	assert.Equal(t, "execute_reply", msg.Header.MsgType)
+	assert.Equal(t, "s", msg.Header.Session)
+	assert.JSONEq(t, `{"execution_count":1}`, string(msg.Content))
+
+	frames[6] = []byte(`{"execution_count":2}`)
+	_, err = k.parseMessage(frames)
+	assert.EqualError(t, err, "invalid signature")
+
+	_, err = k.parseMessage(frames[:3])
+	assert.EqualError(t, err, "malformed message")
+}
+
+func TestSession_runKernel(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	ports := make([]int, 5)
+	for i := range ports {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ports[i] = l.Addr().(*net.TCPAddr).Port
+		l.Close()
+	}
+	conn := kernelConnection{
+		Transport: "tcp", IP: "127.0.0.1",
+		ShellPort: ports[0], IOPubPort: ports[1], StdinPort: ports[2], ControlPort: ports[3], HBPort: ports[4],
+		SignatureScheme: "hmac-sha256", Key: "secret",
+	}
+	content, err := json.Marshal(conn)
+	require.NoError(t, err)
+	connectionFile := filepath.Join(t.TempDir(), "connection.json")
+	require.NoError(t, os.WriteFile(connectionFile, content, 0o600))
+
+	done := make(chan error)
+	go func() { done <- s.runKernel(connectionFile) }()
+
+	dial := func(port int, socketType string) *zmtp.Conn {
+		var c *zmtp.Conn
+		var err error
+		for i := 0; i < 50; i++ {
+			if c, err = zmtp.Dial("tcp", net.JoinHostPort(conn.IP, strconv.Itoa(port)), socketType); err == nil {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+	hb := dial(conn.HBPort, "REQ")
+	iopub := dial(conn.IOPubPort, "SUB")
+	shell := dial(conn.ShellPort, "DEALER")
+
+	require.NoError(t, hb.WriteMessage([][]byte{[]byte("ping")}))
+	pong, err := hb.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("ping")}, pong)
+
+	k := &kernel{mac: func() hash.Hash { return hmac.New(sha256.New, []byte(conn.Key)) }}
+	request := func(msgType string, content any) *kernelMessage {
+		parent := &kernelMessage{Header: kernelHeader{Session: "test"}}
+		frames, err := k.encodeMessage(nil, parent, msgType, content)
+		require.NoError(t, err)
+		require.NoError(t, shell.WriteMessage(frames))
+		frames, err = shell.ReadMessage()
+		require.NoError(t, err)
+		msg, err := k.parseMessage(frames)
+		require.NoError(t, err)
+		return msg
+	}
+
+	// wait for the subscription to be accepted
+	time.Sleep(100 * time.Millisecond)
+
+	reply := request("execute_request", map[string]any{"code": `x := 6 * 7`})
+	assert.Equal(t, "execute_reply", reply.Header.MsgType)
+	assert.JSONEq(t, `{"status":"ok","execution_count":1,"user_expressions":{},"payload":[]}`, string(reply.Content))
+
+	var published []string
+	for len(published) == 0 || published[len(published)-1] != `status {"execution_state":"idle"}` {
+		frames, err := iopub.ReadMessage()
+		require.NoError(t, err)
+		msg, err := k.parseMessage(frames)
+		require.NoError(t, err)
+		published = append(published, msg.Header.MsgType+" "+string(msg.Content))
+	}
+	assert.Equal(t, []string{
+		`status {"execution_state":"busy"}`,
+		`execute_input {"code":"x := 6 * 7","execution_count":1}`,
+		`stream {"name":"stdout","text":"42\n"}`,
+		`status {"execution_state":"idle"}`,
+	}, published)
+
+	reply = request("execute_request", map[string]any{"code": `y`})
+	assert.JSONEq(t, `{"status":"error","execution_count":2,"ename":"Error","evalue":"undefined: y","traceback":[]}`, string(reply.Content))
+
+	reply = request("complete_request", map[string]any{"code": "1\n:he", "cursor_pos": 5})
+	assert.JSONEq(t, `{"status":"ok","matches":[":help "],"cursor_start":2,"cursor_end":5,"metadata":{}}`, string(reply.Content))
+
+	reply = request("shutdown_request", map[string]any{"restart": false})
+	assert.JSONEq(t, `{"status":"ok","restart":false}`, string(reply.Content))
+	require.NoError(t, <-done)
+
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
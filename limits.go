@@ -0,0 +1,74 @@
+package gore
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// The memory and the CPU time of the program are limited by :set memlimit
+// and :set cpulimit, by ulimit of the shell running the program, or by the
+// options of docker run if sandboxed. The memory is limited by the data size
+// (ulimit -d), not by the address space, which the runtime reserves much of.
+
+func setMemLimit(s *Session, value string) error {
+	size, err := parseSize(value)
+	if err != nil {
+		return s.errorf("invalid size: %q", value)
+	}
+	if size > 0 && runtime.GOOS == "windows" {
+		return s.errorf("limits are not supported on %s", runtime.GOOS)
+	}
+	s.run.memLimit = size
+	return nil
+}
+
+func setCPULimit(s *Session, value string) error {
+	var d time.Duration
+	if value != "" && value != "0" {
+		var err error
+		if d, err = time.ParseDuration(value); err != nil || d <= 0 {
+			return s.errorf("invalid duration: %q", value)
+		}
+	}
+	if d > 0 && runtime.GOOS == "windows" {
+		return s.errorf("limits are not supported on %s", runtime.GOOS)
+	}
+	s.run.cpuLimit = d
+	return nil
+}
+
+// limitedCommand returns the command to run the program of the path with the
+// limits, by ulimit of the shell.
+func (s *Session) limitedCommand(path string, args ...string) *exec.Cmd {
+	var script string
+	if s.run.memLimit > 0 {
+		// ulimit -d takes the size in KB
+		script += "ulimit -d " + strconv.FormatInt((s.run.memLimit+1023)/1024, 10) + " && "
+	}
+	if s.run.cpuLimit > 0 {
+		script += "ulimit -t " + cpuSeconds(s.run.cpuLimit) + " && "
+	}
+	if script == "" {
+		return exec.Command(path, args...)
+	}
+	return exec.Command("/bin/sh", append([]string{"-c", script + `exec "$0" "$@"`, path}, args...)...)
+}
+
+// limitArgs returns the options of docker run for the limits.
+func (s *Session) limitArgs() []string {
+	var args []string
+	if s.run.memLimit > 0 {
+		args = append(args, "--memory", strconv.FormatInt(s.run.memLimit, 10))
+	}
+	if s.run.cpuLimit > 0 {
+		args = append(args, "--ulimit", "cpu="+cpuSeconds(s.run.cpuLimit))
+	}
+	return args
+}
+
+// cpuSeconds returns the seconds of the duration, rounded up.
+func cpuSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
@@ -1,9 +1,15 @@
 package gore
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
 	"strings"
 	"text/scanner"
 
@@ -16,28 +22,110 @@ const (
 	indent         = "    "
 )
 
+// The lines typed on the continuation of an input to discard the input, and
+// to edit the input by the editor. No line of Go code begins with a colon.
+// Two empty lines in a row also discard the input, unless in a raw string
+// or a here document.
+const (
+	continueCancel = ":cancel"
+	continueAbort  = ":abort"
+	continueEdit   = ":edit"
+)
+
 type contLiner struct {
 	*liner.State
-	buffer string
-	depth  int
+	buffer   string
+	depth    int
+	number   int               // the input number shown in the prompt, if positive
+	a11y     bool              // whether the prompts are for screen readers
+	platform string            // GOOS/GOARCH emulated, shown in the prompt
+	session  string            // the name of the session, shown in the prompt if there are several
+	mode     liner.ModeApplier // the terminal mode before the line editor
+	history  *historyControl   // the inputs kept in the history, or nil to keep all
+	entries  []string          // the entries of the history of the line editor
 }
 
 func newContLiner() *contLiner {
+	mode, err := liner.TerminalMode()
+	if err != nil {
+		mode = nil
+	}
 	rl := liner.NewLiner()
 	rl.SetCtrlCAborts(true)
-	return &contLiner{State: rl}
+	return &contLiner{State: rl, mode: mode}
+}
+
+// handOver restores the terminal mode before the line editor, which reads
+// the keys without echoing them, so that the program reads the lines typed
+// as usual. The interrupt by Ctrl-C is left to the program while running.
+// It returns the function to take the terminal back to the line editor.
+func (cl *contLiner) handOver() func() {
+	if cl.mode == nil {
+		return func() {}
+	}
+	mode, err := liner.TerminalMode()
+	if err != nil {
+		return func() {}
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	if err := cl.mode.ApplyMode(); err != nil {
+		debugf("terminal: %s", err)
+	}
+	return func() {
+		if err := mode.ApplyMode(); err != nil {
+			debugf("terminal: %s", err)
+		}
+		signal.Stop(sig)
+	}
+}
+
+// hint prints the hint to w on the line under the input, which is redrawn
+// after.
+func (cl *contLiner) hint(w io.Writer, s string) {
+	fmt.Fprintf(w, "\n%s\n", s)
 }
 
 func (cl *contLiner) promptString() string {
+	var prefix string
+	if cl.number > 0 {
+		prefix = fmt.Sprintf("[%d] ", cl.number)
+	}
+	if cl.platform != "" {
+		prefix += "(" + cl.platform + ") "
+	}
+	if cl.session != "" {
+		prefix += cl.session + ": "
+	}
+
+	if cl.a11y {
+		if cl.buffer != "" {
+			return prefix + promptA11yContinue
+		}
+		return prefix + promptA11y
+	}
+
 	if cl.buffer != "" {
-		return promptContinue + strings.Repeat(indent, cl.depth)
+		// the brackets not closed are shown, e.g. ..{( for a call in a block
+		prompt := promptContinue
+		if open := openDelimiters(cl.buffer); open != "" && !heredocInput(cl.buffer) {
+			prompt = promptContinue[:2] + open + " "
+		}
+		return strings.Repeat(" ", len(prefix)) + prompt
 	}
 
-	return promptDefault
+	return prefix + promptDefault
 }
 
 func (cl *contLiner) Prompt() (string, error) {
-	line, err := cl.State.Prompt(cl.promptString())
+	var line string
+	var err error
+	if cl.buffer != "" && cl.depth > 0 && !cl.a11y {
+		// the line is indented by the depth, to be edited
+		line, err = cl.State.PromptWithSuggestion(cl.promptString(), strings.Repeat(indent, cl.depth), -1)
+	} else {
+		line, err = cl.State.Prompt(cl.promptString())
+	}
 	if err == io.EOF {
 		if cl.buffer != "" {
 			// cancel line continuation
@@ -53,6 +141,9 @@ func (cl *contLiner) Prompt() (string, error) {
 			fmt.Println("(^D to quit)")
 		}
 	} else if err == nil {
+		if cl.buffer != "" && cl.continueLine(line, os.Stdout) {
+			return cl.buffer, nil
+		}
 		if cl.buffer != "" {
 			cl.buffer = cl.buffer + "\n" + line
 		} else {
@@ -64,17 +155,173 @@ func (cl *contLiner) Prompt() (string, error) {
 }
 
 func (cl *contLiner) Accepted() {
-	cl.State.AppendHistory(cl.buffer)
+	cl.appendHistory(cl.buffer)
 	cl.buffer = ""
 }
 
+// appendHistory appends the input to the history, unless the history control
+// skips it. The oldest entries over the size are dropped.
+func (cl *contLiner) appendHistory(in string) {
+	var prev string
+	if n := len(cl.entries); n > 0 {
+		prev = cl.entries[n-1]
+	}
+	if cl.history != nil && !cl.history.keep(in, prev) {
+		return
+	}
+	cl.entries = append(cl.entries, in)
+	cl.State.AppendHistory(in)
+	if cl.history == nil || len(cl.entries) <= cl.history.size {
+		return
+	}
+	// the line editor has no way to drop an entry but clearing all
+	cl.entries = cl.entries[len(cl.entries)-cl.history.size:]
+	cl.State.ClearHistory()
+	for _, e := range cl.entries {
+		cl.State.AppendHistory(e)
+	}
+}
+
+// readHistory appends the entries of the history file read from r.
+func (cl *contLiner) readHistory(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		cl.appendHistory(sc.Text())
+	}
+	return sc.Err()
+}
+
+// writeHistory writes the entries of the history file to w.
+func (cl *contLiner) writeHistory(w io.Writer) error {
+	for _, e := range cl.entries {
+		if _, err := fmt.Fprintln(w, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (cl *contLiner) Clear() {
 	cl.buffer = ""
 	cl.depth = 0
 }
 
+// continueLine handles the line typed on the continuation of the input,
+// and reports whether handled. The input discarded is kept in the history
+// to be recalled, and the input edited is echoed to w if not nil.
+func (cl *contLiner) continueLine(line string, w io.Writer) bool {
+	switch line = strings.TrimSpace(line); {
+	case line == continueCancel, line == continueAbort, line == "" && cl.blankLast():
+		if cl.State != nil {
+			cl.appendHistory(cl.buffer)
+		}
+		cl.Clear()
+		return true
+	case line == continueEdit:
+		in, err := editInput(cl.buffer)
+		if err != nil {
+			errorf("edit: %s", err)
+			return true
+		}
+		cl.buffer = in
+		if w != nil {
+			for i, line := range strings.Split(in, "\n") {
+				prompt := promptDefault
+				if i > 0 {
+					prompt = promptContinue
+				}
+				fmt.Fprintln(w, prompt+line)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// blankLast reports whether the last line of the buffer is empty, outside
+// of a raw string, a comment and a here document.
+func (cl *contLiner) blankLast() bool {
+	i := strings.LastIndexByte(cl.buffer, '\n')
+	if i < 0 || strings.TrimSpace(cl.buffer[i+1:]) != "" || heredocInput(cl.buffer) {
+		return false
+	}
+	_, unterminated := scanLast(cl.buffer)
+	return !unterminated
+}
+
+// editInput edits the input by the editor of $VISUAL or $EDITOR.
+func editInput(in string) (string, error) {
+	f, err := os.CreateTemp("", "gore-input-*.go")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(in + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// choose asks to choose one of the options, returning the index,
+// or -1 if none is chosen.
+func (cl *contLiner) choose(w io.Writer, prompt string, options []string) int {
+	for i, option := range options {
+		fmt.Fprintf(w, "  %d) %s\n", i+1, option)
+	}
+	line, err := cl.State.Prompt(fmt.Sprintf("%s [1-%d]: ", prompt, len(options)))
+	if err != nil {
+		return -1
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(options) {
+		return -1
+	}
+	return n - 1
+}
+
+// confirm asks the question answered by yes or no, returning whether yes.
+func (cl *contLiner) confirm(prompt string) bool {
+	line, err := cl.State.Prompt(prompt + " [y/N]: ")
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
 var errUnmatchedBraces = fmt.Errorf("unmatched braces")
 
+// Reindent updates the depth of the blocks not closed, and redraws the line
+// closing the blocks dedented, if it is indented as prepopulated.
 func (cl *contLiner) Reindent() error {
 	oldDepth := cl.depth
 	cl.depth = cl.countDepth()
@@ -83,23 +330,77 @@ func (cl *contLiner) Reindent() error {
 		return errUnmatchedBraces
 	}
 
-	if cl.depth < oldDepth {
-		lines := strings.Split(cl.buffer, "\n")
-		if len(lines) > 1 {
-			lastLine := lines[len(lines)-1]
-
-			cursorUp()
-			fmt.Printf("\r%s%s", cl.promptString(), lastLine)
-			eraseInLine()
-			fmt.Print("\n")
-		}
+	i := strings.LastIndexByte(cl.buffer, '\n')
+	if i < 0 || oldDepth == 0 || heredocInput(cl.buffer) {
+		return nil
+	}
+	prev, line := cl.buffer[:i], cl.buffer[i+1:]
+	code, ok := strings.CutPrefix(line, strings.Repeat(indent, oldDepth))
+	depth := dedentDepth(oldDepth, code)
+	if !ok || depth == oldDepth {
+		return nil
 	}
+	cl.buffer = prev
+	prompt := cl.promptString()
+	line = strings.Repeat(indent, depth) + code
+	cl.buffer = prev + "\n" + line
+
+	cursorUp()
+	fmt.Printf("\r%s%s", prompt, line)
+	eraseInLine()
+	fmt.Print("\n")
 
 	return nil
 }
 
+// dedentDepth returns the depth of the line of the code in the blocks of
+// the depth, which is less by the braces and the parentheses closed first.
+func dedentDepth(depth int, code string) int {
+	for _, c := range code {
+		if c != '}' && c != ')' {
+			break
+		}
+		if depth > 0 {
+			depth--
+		}
+	}
+	return depth
+}
+
 func (cl *contLiner) countDepth() int {
-	reader := bytes.NewBufferString(cl.buffer)
+	if heredocInput(cl.buffer) {
+		return 0
+	}
+	return braceDepth(cl.buffer)
+}
+
+// openDelimiters returns the brackets not closed in the source, the
+// innermost last.
+func openDelimiters(src string) string {
+	sc := new(scanner.Scanner)
+	sc.Init(strings.NewReader(src))
+	sc.Error = func(_ *scanner.Scanner, msg string) {
+		debugf("scanner: %s", msg)
+	}
+
+	var open []rune
+	for {
+		switch tok := sc.Scan(); tok {
+		case '{', '(', '[':
+			open = append(open, tok)
+		case '}', ')', ']':
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case scanner.EOF:
+			return string(open)
+		}
+	}
+}
+
+// braceDepth returns the depth of the braces and the parentheses not closed.
+func braceDepth(src string) int {
+	reader := bytes.NewBufferString(src)
 	sc := new(scanner.Scanner)
 	sc.Init(reader)
 	sc.Error = func(_ *scanner.Scanner, msg string) {
@@ -0,0 +1,97 @@
+package gore
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/peterh/liner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContLiner_promptString(t *testing.T) {
+	testCases := []struct {
+		buffer string
+		depth  int
+		number int
+		want   string
+	}{
+		{"", 0, 0, ":= "},
+		{"", 0, 3, "[3] := "},
+		{"func f() {", 1, 0, "..{ "},
+		{"func f() {\n\tg(1,", 2, 0, "..{( "},
+		{"x := []int{\n1, 2}[", 0, 3, "    ..[ "},
+		{"s := `{(`; f(", 1, 0, "..( "},
+		{"x := 1 +", 0, 0, ".. "},
+		{":stdin <<EOF\n{", 0, 0, ".. "},
+	}
+	for _, tc := range testCases {
+		cl := &contLiner{buffer: tc.buffer, depth: tc.depth, number: tc.number}
+		assert.Equal(t, tc.want, cl.promptString(), tc.buffer)
+	}
+}
+
+func TestContLiner_continueLine(t *testing.T) {
+	testCases := []struct {
+		buffer string
+		line   string
+		want   bool
+	}{
+		{"func f() {", ":cancel", true},
+		{"func f() {", " :abort ", true},
+		{"func f() {", "", false},
+		{"func f() {\n", "", true},
+		{"func f() {\n\t", "\t", true},
+		{"func f() {\n\tg()", "", false},
+		{"s := `a\n", "", false},
+		{":stdin <<EOF\n", "", false},
+		{"func f() {", "return", false},
+	}
+	for _, tc := range testCases {
+		cl := &contLiner{buffer: tc.buffer, depth: 1}
+		assert.Equal(t, tc.want, cl.continueLine(tc.line, nil), tc.buffer)
+		if tc.want {
+			assert.Equal(t, "", cl.buffer, tc.buffer)
+			assert.Equal(t, 0, cl.depth, tc.buffer)
+		} else {
+			assert.Equal(t, tc.buffer, cl.buffer, tc.buffer)
+		}
+	}
+}
+
+func TestContLiner_appendHistory(t *testing.T) {
+	cl := &contLiner{State: liner.NewLiner(), history: &historyControl{size: 3, dedupe: true, ignore: regexp.MustCompile(`^ `)}}
+	t.Cleanup(func() { cl.Close() })
+
+	require.NoError(t, cl.readHistory(strings.NewReader("a\nb\nb\n c\n")))
+	assert.Equal(t, []string{"a", "b"}, cl.entries)
+	for _, in := range []string{"d", "d", " e", "f\ng"} {
+		cl.buffer = in
+		cl.Accepted()
+	}
+	assert.Equal(t, []string{"b", "d", "f\ng"}, cl.entries)
+
+	var buf strings.Builder
+	require.NoError(t, cl.writeHistory(&buf))
+	assert.Equal(t, "b\nd\nf\ng\n", buf.String())
+}
+
+func TestDedentDepth(t *testing.T) {
+	testCases := []struct {
+		depth int
+		code  string
+		want  int
+	}{
+		{1, "}", 0},
+		{1, "} else {", 0},
+		{2, "})", 0},
+		{2, "}", 1},
+		{1, "return", 1},
+		{1, "}}", 0},
+		{1, "f() }", 1},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, dedentDepth(tc.depth, tc.code), tc.code)
+	}
+}
@@ -0,0 +1,154 @@
+package gore
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Statements in the main function are numbered from 1 in the order of inputs.
+// Marks remember statement numbers by name, and can be used anywhere
+// a statement number is expected.
+
+// resolveStmt returns the statement number referred by a number or a mark.
+func (s *Session) resolveStmt(ref string) (int, error) {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(s.mainBody.List) {
+			return 0, s.errorf("no such statement: %d", n)
+		}
+		return n, nil
+	}
+	if n, ok := s.marks[ref]; ok {
+		return n, nil
+	}
+	return 0, s.errorf("no such mark: %s", ref)
+}
+
+// parseStmtRange parses a range of statements, which is one of
+// "<n>", "<n>..<m>", "<n>.." and "since <n>", where <n> and <m> are
+// statement numbers or marks. The returned range is inclusive.
+func (s *Session) parseStmtRange(arg string) (from, to int, err error) {
+	arg = strings.TrimSpace(arg)
+	if strings.HasPrefix(arg, "since ") {
+		arg = strings.TrimSpace(strings.TrimPrefix(arg, "since ")) + ".."
+	}
+
+	first, last, isRange := strings.Cut(arg, "..")
+	if from, err = s.resolveStmt(first); err != nil {
+		return
+	}
+	if !isRange {
+		return from, from, nil
+	}
+	if last == "" {
+		return from, len(s.mainBody.List), nil
+	}
+	if to, err = s.resolveStmt(last); err != nil {
+		return
+	}
+	if from > to {
+		return 0, 0, s.errorf("invalid range: %s", arg)
+	}
+	return
+}
+
+// dropStmts removes the statements in the range and adjusts the marks.
+// The code is restored if the remaining statements do not compile.
+func (s *Session) dropStmts(from, to int) error {
+	marks := make(map[string]int, len(s.marks))
+	for name, i := range s.marks {
+		marks[name] = i
+	}
+
+	list := s.mainBody.List
+	s.mainBody.List = append(list[:from-1:from-1], list[to:]...)
+
+	results := s.results.pending
+	s.dropResults(from, to)
+
+	n := to - from + 1
+	for name, i := range s.marks {
+		if i > to {
+			s.marks[name] = i - n
+		} else if i >= from {
+			delete(s.marks, name)
+		}
+	}
+
+	if err := s.checkCode(); err != nil {
+		s.restoreCode()
+		s.marks, s.results.pending = marks, results
+		return err
+	}
+	return nil
+}
+
+func actionMark(s *Session, name string) error {
+	if name == "" {
+		names := make([]string, 0, len(s.marks))
+		for name := range s.marks {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return s.marks[names[i]] < s.marks[names[j]] })
+
+		w := tabwriter.NewWriter(s.stdout, 0, 8, 4, ' ', 0)
+		for _, name := range names {
+			n := s.marks[name]
+			fmt.Fprintf(w, "    %s\t#%d\t%s\n", name, n, showNode(s.fset, s.mainBody.List[n-1]))
+		}
+		return w.Flush()
+	}
+
+	if _, err := strconv.Atoi(name); err == nil || strings.ContainsAny(name, ". \t") {
+		return s.errorf("invalid mark name: %s", name)
+	}
+	if len(s.mainBody.List) == 0 {
+		return s.errorf("no statement to mark")
+	}
+
+	if s.marks == nil {
+		s.marks = map[string]int{}
+	}
+	s.marks[name] = len(s.mainBody.List)
+	return nil
+}
+
+func completeMark(s *Session, prefix string) []string {
+	var result []string
+	for name := range s.marks {
+		if strings.HasPrefix(name, prefix) {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func actionGoto(s *Session, arg string) error {
+	if arg == "" {
+		return s.errorf("argument is required")
+	}
+
+	n, err := s.resolveStmt(arg)
+	if err != nil {
+		return err
+	}
+	if n == len(s.mainBody.List) {
+		return nil
+	}
+	return s.dropStmts(n+1, len(s.mainBody.List))
+}
+
+func actionDrop(s *Session, arg string) error {
+	if arg == "" {
+		return s.errorf("argument is required")
+	}
+
+	from, to, err := s.parseStmtRange(arg)
+	if err != nil {
+		return err
+	}
+	return s.dropStmts(from, to)
+}
@@ -0,0 +1,63 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Mark(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:mark a`,
+		`x := 1`,
+		`:mark a`,
+		`y := x + 1`,
+		`z := y * 10`,
+		`:mark b`,
+		`w := 5`,
+		`:mark`,
+		`:drop since b`,
+		`:mark`,
+		`z`,
+		`:goto a`,
+		`y`,
+		`x`,
+		`w := x * 100`,
+		`:drop 1`,
+		`:drop 3`,
+		`:drop since c`,
+		`:drop 2..1`,
+		`:mark 1`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `1
+2
+20
+5
+    a    #1    x := 1
+    b    #3    z := y * 10
+    a    #1    x := 1
+1
+100
+`, stdout.String())
+	assert.Equal(t, `mark: no statement to mark
+undefined: z
+undefined: y
+drop: undefined: x
+drop: no such statement: 3
+drop: no such mark: c
+drop: invalid range: 2..1
+mark: invalid mark name: 1
+`, stderr.String())
+}
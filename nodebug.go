@@ -3,4 +3,4 @@
 
 package gore
 
-func debugf(string, ...any) {}
+const debugBuild = false
@@ -1,6 +1,9 @@
 package gore
 
-import "io"
+import (
+	"go/build"
+	"io"
+)
 
 // Option for Gore
 type Option func(*Gore)
@@ -12,6 +15,134 @@ func AutoImport(autoImport bool) Option {
 	}
 }
 
+// Server option
+func Server(server bool) Option {
+	return func(g *Gore) {
+		g.server = server
+	}
+}
+
+// Plain option reads the inputs from stdin without the line editor, and
+// writes the outputs prefixed to be parsed, for the editors sending the code.
+func Plain(plain bool) Option {
+	return func(g *Gore) {
+		g.plain = plain
+	}
+}
+
+// Kernel option
+func Kernel(connectionFile string) Option {
+	return func(g *Gore) {
+		g.kernel = connectionFile
+	}
+}
+
+// HTTP option
+func HTTP(addr string) Option {
+	return func(g *Gore) {
+		g.httpAddr = addr
+	}
+}
+
+// Storage option
+func Storage(st Store) Option {
+	return func(g *Gore) {
+		g.store = st
+	}
+}
+
+// CheckUpdate option
+func CheckUpdate(checkUpdate bool) Option {
+	return func(g *Gore) {
+		g.checkUpdate = checkUpdate
+	}
+}
+
+// A11y option
+func A11y(a11y bool) Option {
+	return func(g *Gore) {
+		g.a11y = a11y
+	}
+}
+
+// BuildContext option overrides the build context (e.g. GOPATH, GOROOT,
+// GOOS and GOARCH) of the sessions. GOOS and GOARCH are used for type
+// checking and completion, not for running the code.
+func BuildContext(ctxt *build.Context) Option {
+	return func(g *Gore) {
+		g.buildContext = ctxt
+	}
+}
+
+// Open option restores the session from the bundle written by :write --bundle.
+func Open(bundle string) Option {
+	return func(g *Gore) {
+		g.bundle = bundle
+	}
+}
+
+// MaxOutput option limits the outputs of each run to the size (e.g. 64KB),
+// as :set maxoutput does.
+func MaxOutput(size string) Option {
+	return func(g *Gore) {
+		g.maxOutput = size
+	}
+}
+
+// Race option runs the code with the race detector, as :set race on does.
+func Race(race bool) Option {
+	return func(g *Gore) {
+		g.race = race
+	}
+}
+
+// TempDir option creates the temporary directories of the sessions under the
+// directory.
+func TempDir(dir string) Option {
+	return func(g *Gore) {
+		g.tempRoot = dir
+	}
+}
+
+// Sandbox option runs the programs in the containers of the kind (docker),
+// with the image ("" for the default one).
+func Sandbox(kind, image string) Option {
+	return func(g *Gore) {
+		g.sandbox, g.sandboxImage = kind, image
+	}
+}
+
+// Deterministic option runs the code reproducibly, as :set deterministic on
+// does.
+func Deterministic(deterministic bool) Option {
+	return func(g *Gore) {
+		g.deterministic = deterministic
+	}
+}
+
+// Timeout option stops the program running over the duration (e.g. 10s),
+// as :set timeout does.
+func Timeout(duration string) Option {
+	return func(g *Gore) {
+		g.timeout = duration
+	}
+}
+
+// NoColor option prints the results and the source without colors.
+func NoColor(noColor bool) Option {
+	return func(g *Gore) {
+		g.noColor = noColor
+	}
+}
+
+// Debug option prints the debug messages to the standard error. As the
+// messages are not of a session, this is shared by all the Gores.
+func Debug(debug bool) Option {
+	return func(*Gore) {
+		debugging = debugBuild || debug
+	}
+}
+
 // ExtFiles option
 func ExtFiles(extFiles string) Option {
 	return func(g *Gore) {
@@ -0,0 +1,202 @@
+package gore
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// outputLimit truncates the outputs of the program over the limit, which is
+// shared by stdout and stderr, and writes the rest to a temporary file if
+// spill is set. The writers are used concurrently by the program run.
+type outputLimit struct {
+	mu      sync.Mutex
+	limit   int64
+	written int64
+	omitted int64
+	partial bool     // whether the last line written is not terminated
+	spill   bool     // whether to write the output omitted to a file
+	file    *os.File // the file of the output omitted
+}
+
+// writer returns the writer to w limited by l.
+func (l *outputLimit) writer(w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+	return &limitedWriter{l: l, w: w}
+}
+
+type limitedWriter struct {
+	l *outputLimit
+	w io.Writer
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	l := lw.l
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := len(p)
+	if rest := l.limit - l.written; rest > 0 {
+		k := len(p)
+		if int64(k) > rest {
+			k = int(rest)
+		}
+		if _, err := lw.w.Write(p[:k]); err != nil {
+			return 0, err
+		}
+		l.written += int64(k)
+		l.partial = p[k-1] != '\n'
+		p = p[k:]
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+
+	l.omitted += int64(len(p))
+	if l.spill && l.file == nil {
+		f, err := os.CreateTemp("", "gore-output-*.txt")
+		if err != nil {
+			debugf("output: %s", err)
+			l.spill = false
+		}
+		l.file = f
+	}
+	if l.spill {
+		if _, err := l.file.Write(p); err != nil {
+			debugf("output: %s", err)
+		}
+	}
+	return n, nil
+}
+
+// closeOutputLimit reports the output omitted, if any, to w.
+func (s *Session) closeOutputLimit(l *outputLimit, w io.Writer) {
+	if l == nil || l.omitted == 0 {
+		return
+	}
+	if l.partial {
+		fmt.Fprintln(w)
+	}
+	if l.file != nil {
+		l.file.Close()
+		fmt.Fprintf(w, s.tr("… %d bytes omitted (written to %s)")+"\n", l.omitted, l.file.Name())
+		return
+	}
+	fmt.Fprintf(w, s.tr("… %d bytes omitted")+"\n", l.omitted)
+}
+
+// newOutputLimit returns the limit of the outputs of a run, or nil if the
+// outputs are not limited.
+func (s *Session) newOutputLimit() *outputLimit {
+	if s.run.maxOutput <= 0 {
+		return nil
+	}
+	return &outputLimit{limit: s.run.maxOutput, spill: s.run.spillOutput}
+}
+
+func setMaxOutput(s *Session, value string) error {
+	size, err := parseSize(value)
+	if err != nil {
+		return s.errorf("invalid size: %q", value)
+	}
+	s.run.maxOutput = size
+	return nil
+}
+
+func setOutputFile(s *Session, value string) error {
+	on, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.run.spillOutput = on
+	return nil
+}
+
+var sizeUnits = []struct {
+	name string
+	size int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize parses the size in bytes with an optional unit (e.g. 64KB),
+// where "" and 0 are for no limit.
+func parseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(value, u.name) {
+			value, unit = strings.TrimSpace(strings.TrimSuffix(value, u.name)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/unit {
+		return 0, fmt.Errorf("invalid size: %q", value)
+	}
+	return n * unit, nil
+}
+
+// formatSize formats the size in the largest unit dividing it.
+func formatSize(size int64) string {
+	if size <= 0 {
+		return ""
+	}
+	for _, u := range sizeUnits[:3] {
+		if size%u.size == 0 {
+			return strconv.FormatInt(size/u.size, 10) + u.name
+		}
+	}
+	return strconv.FormatInt(size, 10) + "B"
+}
+
+// colorError is the color of stderr written to the terminal.
+const colorError = "\x1b[31m"
+
+// colorWriter writes to w in the color.
+type colorWriter struct {
+	w     io.Writer
+	color string
+}
+
+func (w *colorWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if _, err := io.WriteString(w.w, w.color+string(p)+colorReset); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// colorStderr colors stderr if it is the terminal, to tell the errors from
+// the outputs interleaved. The result and the transcript keep the text
+// uncolored, as the color is given beneath them.
+func (s *Session) colorStderr() {
+	f, ok := s.capture.stderr.w.(*os.File)
+	if !ok || s.format.noColor || s.a11y {
+		return
+	}
+	if _, _, ok := terminalSize(f); !ok {
+		return
+	}
+	s.capture.stderr.w = &colorWriter{w: f, color: colorError}
+}
@@ -0,0 +1,253 @@
+package gore
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// pager shows long text page by page, reading the commands from in.
+type pager struct {
+	in      *bufio.Reader
+	out     io.Writer
+	lines   []string // lines for searching
+	display []string // lines for displaying, possibly highlighted
+	stmts   []int    // indices of lines where the statements start
+	height  int
+}
+
+const pagerHelp = "<enter> next, /<regexp> search, #<n> statement, q quit"
+
+func (p *pager) run() error {
+	var top int
+	for {
+		end := top + p.height
+		if end > len(p.lines) {
+			end = len(p.lines)
+		}
+		for _, line := range p.display[top:end] {
+			fmt.Fprintln(p.out, line)
+		}
+		if end == len(p.lines) {
+			return nil
+		}
+
+		next, err := p.prompt(top, end)
+		if err != nil || next < 0 {
+			return err
+		}
+		top = next
+	}
+}
+
+// prompt reads commands until a valid one is given and returns the next top line.
+func (p *pager) prompt(top, end int) (int, error) {
+	for {
+		fmt.Fprintf(p.out, "-- %d-%d/%d (%s) -- ", top+1, end, len(p.lines), pagerHelp)
+		cmd, err := p.in.ReadString('\n')
+		if err != nil {
+			fmt.Fprintln(p.out)
+			if err == io.EOF {
+				return -1, nil
+			}
+			return -1, err
+		}
+
+		next, err := p.command(strings.TrimSpace(cmd), top, end)
+		if err != nil {
+			fmt.Fprintf(p.out, "%s\n", err)
+			continue
+		}
+		return next, nil
+	}
+}
+
+// command executes the pager command and returns the next top line,
+// or -1 to quit the pager.
+func (p *pager) command(cmd string, top, end int) (int, error) {
+	switch {
+	case cmd == "":
+		return end, nil
+	case cmd == "q":
+		return -1, nil
+	case strings.HasPrefix(cmd, "/"):
+		re, err := regexp.Compile("(?i)" + cmd[1:])
+		if err != nil {
+			return 0, err
+		}
+		for i := top + 1; i < len(p.lines); i++ {
+			if re.MatchString(p.lines[i]) {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("pattern not found: %s", cmd[1:])
+	case strings.HasPrefix(cmd, "#"):
+		n, err := strconv.Atoi(cmd[1:])
+		if err != nil || n < 1 || n > len(p.stmts) {
+			return 0, fmt.Errorf("no such statement: %s", cmd[1:])
+		}
+		return p.stmts[n-1], nil
+	}
+	return 0, fmt.Errorf("unknown command: %s (%s)", cmd, pagerHelp)
+}
+
+// stdoutTerminal returns the terminal of the standard output and its height,
+// if the session is interactive. The pagers are used only in the interactive
+// mode, as the keys are read from stdin, which is the input of the script in
+// the other modes.
+func (s *Session) stdoutTerminal() (*os.File, int, bool) {
+	w, ok := s.stdout.(*captureWriter)
+	if !ok || s.terminal == nil {
+		return nil, 0, false
+	}
+	f, ok := w.w.(*os.File)
+	if !ok {
+		return nil, 0, false
+	}
+	_, height, ok := terminalSize(f)
+	return f, height, ok
+}
+
+// pageOutput writes the output of a run, through the pager of $GORE_PAGER or
+// $PAGER (less by default) if it is longer than the terminal.
+func (s *Session) pageOutput(data []byte) error {
+	f, height, ok := s.stdoutTerminal()
+	if !ok || bytes.Count(data, []byte("\n")) < height {
+		_, err := s.stdout.Write(data)
+		return err
+	}
+
+	// the output is kept for the result, though written by the pager
+	if buf := s.capture.stdout.buf; buf != nil {
+		buf.Write(data)
+	}
+	pagerCmd := os.Getenv("GORE_PAGER")
+	if pagerCmd == "" {
+		pagerCmd = os.Getenv("PAGER")
+	}
+	args := strings.Fields(pagerCmd)
+	if len(args) == 0 {
+		args = []string{"less", "-R"}
+		if runtime.GOOS == "windows" {
+			args = []string{"more"}
+		}
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(data), f, s.stderr
+	restore := s.terminal()
+	defer restore()
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return err
+		}
+		// the pager is not found
+		debugf("pager: %s", err)
+		_, err = f.Write(data)
+		return err
+	}
+	return nil
+}
+
+func setPager(s *Session, value string) error {
+	on, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.run.pager = on
+	return nil
+}
+
+// pageSource shows the session source with the pager.
+func (s *Session) pageSource(source string, in io.Reader, height int) error {
+	source = strings.TrimSuffix(source, "\n")
+	display := source
+	if !s.format.noColor {
+		display = highlightSource(source)
+	}
+	p := &pager{
+		in:      bufio.NewReader(in),
+		out:     s.stdout,
+		lines:   strings.Split(source, "\n"),
+		display: strings.Split(display, "\n"),
+		height:  height,
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", source, parser.Mode(0))
+	if err != nil {
+		return err
+	}
+	for _, decl := range f.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && isNamedIdent(decl.Name, "main") && decl.Recv == nil {
+			for _, stmt := range decl.Body.List {
+				p.stmts = append(p.stmts, fset.Position(stmt.Pos()).Line-1)
+			}
+		}
+	}
+
+	return p.run()
+}
+
+const (
+	colorReset   = "\x1b[0m"
+	colorKeyword = "\x1b[1;34m"
+	colorLiteral = "\x1b[32m"
+	colorComment = "\x1b[90m"
+)
+
+// highlightSource colorizes the Go source with ANSI escape sequences.
+// The number of lines is kept so that the lines correspond to the source.
+func highlightSource(src string) string {
+	var sc scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	sc.Init(file, []byte(src), nil, scanner.ScanComments)
+
+	var sb strings.Builder
+	var last int
+	for {
+		pos, tok, lit := sc.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		var color string
+		switch {
+		case tok.IsKeyword():
+			color = colorKeyword
+		case tok == token.STRING || tok == token.CHAR:
+			color = colorLiteral
+		case tok == token.COMMENT:
+			color = colorComment
+		default:
+			continue
+		}
+
+		offset := file.Offset(pos)
+		text := lit
+		if tok.IsKeyword() {
+			text = tok.String()
+		}
+		sb.WriteString(src[last:offset])
+		sb.WriteString(color)
+		sb.WriteString(strings.ReplaceAll(text, "\n", colorReset+"\n"+color))
+		sb.WriteString(colorReset)
+		last = offset + len(text)
+	}
+	sb.WriteString(src[last:])
+
+	return sb.String()
+}
@@ -0,0 +1,89 @@
+package gore
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPager(t *testing.T) {
+	var out strings.Builder
+	p := &pager{
+		out:     &out,
+		lines:   []string{"a", "b", "c", "d", "e", "f", "g"},
+		display: []string{"A", "B", "C", "D", "E", "F", "G"},
+		stmts:   []int{1, 5},
+		height:  2,
+	}
+
+	testCases := []struct {
+		name, in, want string
+	}{
+		{
+			"next pages", "\n\n\n",
+			"A\nB\n-- 1-2/7 (" + pagerHelp + ") -- C\nD\n-- 3-4/7 (" + pagerHelp + ") -- E\nF\n-- 5-6/7 (" + pagerHelp + ") -- G\n",
+		},
+		{
+			"search", "/E\nq\n",
+			"A\nB\n-- 1-2/7 (" + pagerHelp + ") -- E\nF\n-- 5-6/7 (" + pagerHelp + ") -- ",
+		},
+		{
+			"statement", "#2\n",
+			"A\nB\n-- 1-2/7 (" + pagerHelp + ") -- F\nG\n",
+		},
+		{
+			"errors", "/x\n#3\nfoo\n",
+			"A\nB\n-- 1-2/7 (" + pagerHelp + ") -- pattern not found: x\n" +
+				"-- 1-2/7 (" + pagerHelp + ") -- no such statement: 3\n" +
+				"-- 1-2/7 (" + pagerHelp + ") -- unknown command: foo (" + pagerHelp + ")\n" +
+				"-- 1-2/7 (" + pagerHelp + ") -- \n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out.Reset()
+			p.in = bufio.NewReader(strings.NewReader(tc.in))
+			assert.NoError(t, p.run())
+			assert.Equal(t, tc.want, out.String())
+		})
+	}
+}
+
+func TestHighlightSource(t *testing.T) {
+	src := "package main\n\n// comment\nfunc main() {\n\tx := `a\nb`\n}"
+	got := highlightSource(src)
+	assert.Equal(t, strings.Count(src, "\n"), strings.Count(got, "\n"))
+	assert.Equal(t, colorKeyword+"package"+colorReset+" main\n\n"+
+		colorComment+"// comment"+colorReset+"\n"+
+		colorKeyword+"func"+colorReset+" main() {\n"+
+		"\tx := "+colorLiteral+"`a"+colorReset+"\n"+colorLiteral+"b`"+colorReset+"\n}", got)
+}
+
+func TestAction_Set_pager(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	// the outputs are written as is without a terminal
+	codes := []string{
+		`:set pager on`,
+		`:set pager`,
+		`:import strings`,
+		`strings.Repeat("a\n", 3)`,
+	}
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, `pager = "on"
+"a\na\na\n"
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+	assert.True(t, s.run.pager)
+}
@@ -0,0 +1,118 @@
+package gore
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+)
+
+// In the plain mode, for the editors sending the code to the terminal (e.g.
+// vim-slime, tmux send-keys), the inputs are read without the line editor as
+// the chunks of the lines ending by a blank line or plainSentinel. The
+// outputs of a chunk are the lines prefixed by plainOutput or plainError,
+// followed by the line of plainDone and the status (ok or error).
+const (
+	plainSentinel = ";;"
+	plainOutput   = "| "
+	plainError    = "! "
+	plainDone     = "= "
+)
+
+// plainWriter prefixes the lines written to the underlying writer, which is
+// shared by the writers of the outputs and the errors.
+type plainWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	inLine *bool // whether the last line written is not terminated
+}
+
+func (w *plainWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(string(p), "\n") {
+		if line == "" {
+			continue
+		}
+		if !*w.inLine {
+			sb.WriteString(w.prefix)
+		}
+		sb.WriteString(line)
+		*w.inLine = !strings.HasSuffix(line, "\n")
+	}
+	if _, err := io.WriteString(w.w, sb.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// runPlain evaluates the chunks read from r, writing the outputs to w.
+// A chunk ending by a blank line is continued while incomplete (e.g. a blank
+// line in a function), but the one ending by plainSentinel is not.
+func (s *Session) runPlain(r io.Reader, w io.Writer) error {
+	var mu sync.Mutex
+	var inLine bool
+	s.setOutput(
+		&plainWriter{mu: &mu, w: w, prefix: plainOutput, inLine: &inLine},
+		&plainWriter{mu: &mu, w: w, prefix: plainError, inLine: &inLine},
+	)
+	done := func(status string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if inLine {
+			inLine = false
+			status = "\n" + plainDone + status
+		} else {
+			status = plainDone + status
+		}
+		_, err := io.WriteString(w, status+"\n")
+		return err
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	var lines []string
+	for {
+		ok := sc.Scan()
+		line := sc.Text()
+		sentinel := ok && strings.TrimSpace(line) == plainSentinel
+		if ok && !sentinel && strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+			continue
+		}
+		if len(lines) == 0 {
+			if !ok {
+				return sc.Err()
+			}
+			continue
+		}
+
+		_, err := s.Eval(strings.Join(lines, "\n"))
+		if err == ErrContinue && ok && !sentinel {
+			lines = append(lines, "")
+			continue
+		}
+		lines = nil
+		status := "ok"
+		switch err {
+		case nil:
+		case ErrQuit:
+			return done(status)
+		case ErrContinue:
+			status = "error"
+			if _, err := io.WriteString(s.stderr, s.errorf("unexpected end of input").Error()+"\n"); err != nil {
+				return err
+			}
+		default:
+			status = "error"
+		}
+		if err := done(status); err != nil {
+			return err
+		}
+		if !ok {
+			return sc.Err()
+		}
+	}
+}
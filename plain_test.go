@@ -0,0 +1,54 @@
+package gore
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_runPlain(t *testing.T) {
+	s, err := NewSession(io.Discard, io.Discard)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.format.noColor = true
+	require.NoError(t, s.updatePrinter())
+
+	input := `x := 1
+y := x + 1
+
+func f() int {
+
+	return 10
+}
+
+f()
+
+print("no newline")
+
+undefinedName
+
+func g() {
+;;
+:quit
+x
+`
+	var out strings.Builder
+	require.NoError(t, s.runPlain(strings.NewReader(input), &out))
+	assert.Equal(t, `| 1
+| 2
+= ok
+= ok
+| 10
+= ok
+! no newline
+= ok
+! undefined: undefinedName
+= error
+! unexpected end of input
+= error
+= ok
+`, out.String())
+}
@@ -0,0 +1,66 @@
+package gore
+
+import (
+	"go/build"
+	"os/exec"
+	"strings"
+)
+
+// The session can emulate another platform by GOOS and GOARCH of the build
+// context, which are used for type checking and completion, so that the code
+// for the platform (e.g. syscall) can be written. The program is still built
+// and run for this machine.
+
+// platform returns GOOS/GOARCH of the build context if it is not of this
+// machine, or "" if not emulating another platform.
+func (s *Session) platform() string {
+	if s.buildContext.GOOS == build.Default.GOOS && s.buildContext.GOARCH == build.Default.GOARCH {
+		return ""
+	}
+	return s.buildContext.GOOS + "/" + s.buildContext.GOARCH
+}
+
+func setGoos(s *Session, value string) error {
+	if value == "" {
+		s.buildContext.GOOS = build.Default.GOOS
+		return nil
+	}
+	if !s.knownPlatform(value, "") {
+		return s.errorf("unknown GOOS: %s", value)
+	}
+	s.buildContext.GOOS = value
+	return nil
+}
+
+func setGoarch(s *Session, value string) error {
+	if value == "" {
+		s.buildContext.GOARCH = build.Default.GOARCH
+		return nil
+	}
+	if !s.knownPlatform("", value) {
+		return s.errorf("unknown GOARCH: %s", value)
+	}
+	s.buildContext.GOARCH = value
+	return nil
+}
+
+// knownPlatform reports whether GOOS or GOARCH (or both) is supported by the
+// go command. The platforms are listed by go tool dist list, and any value
+// is accepted if they cannot be listed.
+func (s *Session) knownPlatform(goos, goarch string) bool {
+	if s.platforms == nil {
+		out, err := exec.Command("go", "tool", "dist", "list").Output()
+		if err != nil {
+			debugf("go tool dist list: %s", err)
+			return true
+		}
+		s.platforms = strings.Fields(string(out))
+	}
+	for _, p := range s.platforms {
+		pos, parch, _ := strings.Cut(p, "/")
+		if (goos == "" || pos == goos) && (goarch == "" || parch == goarch) {
+			return true
+		}
+	}
+	return false
+}
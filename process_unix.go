@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package gore
+
+import "syscall"
+
+// processAlive reports whether the process of the id is running.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,20 @@
+package gore
+
+import "syscall"
+
+// stillActive is the exit code of the process running.
+const stillActive = 259
+
+// processAlive reports whether the process of the id is running.
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
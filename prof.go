@@ -0,0 +1,134 @@
+package gore
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// The last statement is profiled by the calls of the functions around it,
+// declared in a file added to the session only while profiling as :bench.
+// The heap profile before the statement is written as the base, to show the
+// allocations by the statement.
+const (
+	profFileName      = "gore_prof.go"
+	profStartFuncName = "__gore_prof_start"
+	profStopFuncName  = "__gore_prof_stop"
+	profTopEntries    = 20 // the number of the entries printed from the profile
+)
+
+const profSourceTemplate = `package main
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+const (
+	__gore_prof_kind = %q
+	__gore_prof_path = %q
+	__gore_prof_base = %q
+)
+
+var __gore_prof_file *os.File
+
+func init() {
+	if __gore_prof_kind == "mem" {
+		runtime.MemProfileRate = 1
+	}
+}
+
+func __gore_prof_write(path string) {
+	runtime.GC()
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		panic(err)
+	}
+}
+
+func ` + profStartFuncName + `() {
+	if __gore_prof_kind == "mem" {
+		__gore_prof_write(__gore_prof_base)
+		return
+	}
+	f, err := os.Create(__gore_prof_path)
+	if err != nil {
+		panic(err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		panic(err)
+	}
+	__gore_prof_file = f
+}
+
+func ` + profStopFuncName + `() {
+	if __gore_prof_kind == "mem" {
+		__gore_prof_write(__gore_prof_path)
+		return
+	}
+	pprof.StopCPUProfile()
+	__gore_prof_file.Close()
+}
+`
+
+// actionProf runs the program with the last statement profiled for the CPU
+// or the allocations, and prints the top entries of the profile by pprof.
+func actionProf(s *Session, arg string) error {
+	if arg != "cpu" && arg != "mem" {
+		return s.errorf("invalid profile: %q (cpu or mem)", arg)
+	}
+	if len(s.mainBody.List) == 0 {
+		return s.errorf("no statement to profile")
+	}
+
+	f, err := os.CreateTemp("", "gore-"+arg+"-*.pprof")
+	if err != nil {
+		return err
+	}
+	path := f.Name()
+	f.Close()
+	base := path + ".base"
+	defer os.Remove(base)
+
+	s.storeCode()
+	defer s.restoreCode()
+	restore, err := s.addSessionFile(profFileName, fmt.Sprintf(profSourceTemplate, arg, path, base))
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	call := func(name string) ast.Stmt {
+		return &ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent(name)}}
+	}
+	n := len(s.mainBody.List) - 1
+	last := s.mainBody.List[n]
+	s.mainBody.List = append(s.mainBody.List[:n:n], call(profStartFuncName), last, call(profStopFuncName))
+	s.doQuickFix()
+
+	if err := s.Run(); err != nil {
+		debugf("prof :: err = %s", err)
+		os.Remove(path)
+		return s.errorf("could not run the program")
+	}
+
+	args := []string{"tool", "pprof", "-top", "-nodecount=" + strconv.Itoa(profTopEntries)}
+	if arg == "mem" {
+		args = append(args, "-sample_index=alloc_space", "-base="+base)
+	}
+	cmd := exec.Command("go", append(args, path)...)
+	cmd.Env = s.goEnviron()
+	cmd.Stdout, cmd.Stderr = s.stdout, s.stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.stderr, s.tr("profile written to %s")+"\n", path)
+	return nil
+}
@@ -1,6 +1,7 @@
 package gore
 
 import (
+	"errors"
 	"go/ast"
 	"go/token"
 	"go/types"
@@ -49,37 +50,10 @@ L:
 			if !ok {
 				continue
 			}
-
-			// "... used as value":
-			//
-			// convert
-			//   __gore_pp(funcWithSideEffectReturningNoValue())
-			// to
-			//   funcWithSideEffectReturningNoValue()
-			if strings.HasSuffix(err.Msg, " used as value") {
-				nodepath, _ := astutil.PathEnclosingInterval(s.file, err.Pos, err.Pos)
-
-				for _, node := range nodepath {
-					stmt, ok := node.(ast.Stmt)
-					if !ok {
-						continue
-					}
-
-					for i := range s.mainBody.List {
-						if s.mainBody.List[i] != stmt {
-							continue
-						}
-
-						exprs := printedExprs(stmt)
-
-						stmts := s.mainBody.List[0:i]
-						for _, expr := range exprs {
-							stmts = append(stmts, &ast.ExprStmt{X: expr})
-						}
-
-						s.mainBody.List = append(stmts, s.mainBody.List[i+1:]...)
-						continue L
-					}
+			for _, rule := range quickFixRules {
+				if rule.match(err.Msg) && rule.fix(s, err.Pos) {
+					debugf("quickFix :: %s", rule.name)
+					continue L
 				}
 			}
 		}
@@ -89,11 +63,91 @@ L:
 	}
 }
 
+// quickFixRule fixes the soft errors left by go-quickfix, which are matched
+// by the messages.
+type quickFixRule struct {
+	name  string
+	match func(msg string) bool
+	fix   func(s *Session, pos token.Pos) bool // reports whether fixed
+}
+
+var quickFixRules = []quickFixRule{
+	{
+		name:  "used as value",
+		match: func(msg string) bool { return strings.HasSuffix(msg, " used as value") },
+		fix:   (*Session).fixUsedAsValue,
+	},
+	{
+		name: "unused label",
+		match: func(msg string) bool {
+			return strings.HasPrefix(msg, "label ") && strings.HasSuffix(msg, " not used")
+		},
+		fix: (*Session).fixUnusedLabel,
+	},
+}
+
+// fixUsedAsValue converts
+//
+//	__gore_p(funcWithSideEffectReturningNoValue())
+//
+// to
+//
+//	funcWithSideEffectReturningNoValue()
+func (s *Session) fixUsedAsValue(pos token.Pos) bool {
+	nodepath, _ := astutil.PathEnclosingInterval(s.file, pos, pos)
+	for _, node := range nodepath {
+		stmt, ok := node.(ast.Stmt)
+		if !ok {
+			continue
+		}
+		for i := range s.mainBody.List {
+			if s.mainBody.List[i] != stmt {
+				continue
+			}
+
+			exprs := printedExprs(stmt)
+
+			stmts := s.mainBody.List[0:i]
+			for _, expr := range exprs {
+				stmts = append(stmts, &ast.ExprStmt{X: expr})
+			}
+
+			s.mainBody.List = append(stmts, s.mainBody.List[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fixUnusedLabel removes the label not used (e.g. L: for {} without break L).
+func (s *Session) fixUnusedLabel(pos token.Pos) bool {
+	var fixed bool
+	astutil.Apply(s.file, func(c *astutil.Cursor) bool {
+		if stmt, ok := c.Node().(*ast.LabeledStmt); ok && stmt.Label.Pos() == pos {
+			c.Replace(stmt.Stmt)
+			fixed = true
+		}
+		return !fixed
+	}, nil)
+	return fixed
+}
+
+// checkCode fixes the code and reports the remaining compile error if any.
+func (s *Session) checkCode() error {
+	s.doQuickFix()
+	_, err := s.types.Check("_tmp", s.fset, append(s.extraFiles, s.file), nil)
+	if err, ok := err.(types.Error); ok {
+		return errors.New(err.Msg)
+	}
+	return err
+}
+
 func (s *Session) clearQuickFix() {
-	// make all import specs explicit (i.e. no "_").
+	// make all import specs explicit (i.e. no "_"), by the names given.
 	for _, imp := range s.file.Imports {
-		imp.Name = nil
+		imp.Name = s.importName(imp)
 	}
+	s.syncFileImports()
 
 	for i := 0; i < len(s.mainBody.List); {
 		stmt := s.mainBody.List[i]
@@ -123,24 +177,7 @@ func (s *Session) clearQuickFix() {
 			// convert possibly impure expressions to blank assignment
 			var trailing []ast.Stmt
 			s.mainBody.List, trailing = s.mainBody.List[0:i], s.mainBody.List[i+1:]
-			for _, expr := range exprs {
-				if !s.isPureExpr(expr) {
-					t := s.typeInfo.TypeOf(expr)
-					var lhs []ast.Expr
-					if t, ok := t.(*types.Tuple); ok {
-						lhs = make([]ast.Expr, t.Len())
-						for i := 0; i < t.Len(); i++ {
-							lhs[i] = ast.NewIdent("_")
-						}
-					} else {
-						lhs = []ast.Expr{ast.NewIdent("_")}
-					}
-					s.mainBody.List = append(s.mainBody.List, &ast.AssignStmt{
-						Lhs: lhs, Tok: token.ASSIGN, Rhs: []ast.Expr{expr},
-					})
-				}
-			}
-
+			s.mainBody.List = append(s.mainBody.List, s.blankAssignStmts(exprs)...)
 			s.mainBody.List = append(s.mainBody.List, trailing...)
 			continue
 		}
@@ -151,6 +188,31 @@ func (s *Session) clearQuickFix() {
 	debugf("clearQuickFix :: %s", showNode(s.fset, s.mainBody))
 }
 
+// blankAssignStmts returns the assignments of the impure expressions to the
+// blank identifiers, to evaluate them without printing.
+func (s *Session) blankAssignStmts(exprs []ast.Expr) []ast.Stmt {
+	var stmts []ast.Stmt
+	for _, expr := range exprs {
+		if s.isPureExpr(expr) {
+			continue
+		}
+		t := s.typeInfo.TypeOf(expr)
+		var lhs []ast.Expr
+		if t, ok := t.(*types.Tuple); ok {
+			lhs = make([]ast.Expr, t.Len())
+			for i := 0; i < t.Len(); i++ {
+				lhs[i] = ast.NewIdent("_")
+			}
+		} else {
+			lhs = []ast.Expr{ast.NewIdent("_")}
+		}
+		stmts = append(stmts, &ast.AssignStmt{
+			Lhs: lhs, Tok: token.ASSIGN, Rhs: []ast.Expr{expr},
+		})
+	}
+	return stmts
+}
+
 // isPureAssignStmt returns assignment is pure and omittable.
 func (s *Session) isPureAssignStmt(stmt *ast.AssignStmt) bool {
 	for _, lhs := range stmt.Lhs {
@@ -255,7 +317,8 @@ func (s *Session) isPureExpr(expr ast.Expr) bool {
 	case *ast.TypeAssertExpr:
 		return true
 	case *ast.UnaryExpr:
-		return s.isPureExpr(expr.X)
+		// receiving from a channel is kept to receive the next value later
+		return expr.Op != token.ARROW && s.isPureExpr(expr.X)
 	case *ast.ParenExpr:
 		return s.isPureExpr(expr.X)
 
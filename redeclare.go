@@ -0,0 +1,224 @@
+package gore
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// The variables and the constants of main are declared again by :=, var or
+// const in the later inputs (e.g. x := 5 after x := "hello"), as a REPL does.
+// The statement declaring a variable of the same type by := assigns it
+// instead. Otherwise the one declared before is superseded: renamed (e.g. to
+// x_1) if it is used, or to _ if not. The types declared again replace the
+// ones declared before, keeping the methods.
+
+// redeclare handles the variables of main declared again by the statement
+// appended to main.
+func (s *Session) redeclare(stmt ast.Stmt) {
+	idents := declaredIdents(stmt)
+	if len(idents) == 0 {
+		return
+	}
+	// type check only if any name is declared before
+	declared := map[string]bool{}
+	for _, st := range s.mainBody.List[:len(s.mainBody.List)-1] {
+		for _, ident := range declaredIdents(st) {
+			declared[ident.Name] = true
+		}
+	}
+	var found bool
+	for _, ident := range idents {
+		found = found || declared[ident.Name]
+	}
+	if !found {
+		return
+	}
+
+	_, info := s.typeCheck()
+	scope := info.Scopes[s.mainFunc().Type]
+	if scope == nil {
+		return
+	}
+	lhs := map[*ast.Ident]bool{}
+	for _, ident := range idents {
+		lhs[ident] = true
+	}
+	assign, _ := stmt.(*ast.AssignStmt)
+	reused := 0
+	for i, ident := range idents {
+		old := scope.Lookup(ident.Name)
+		switch old.(type) {
+		case *types.Var, *types.Const:
+		default:
+			continue
+		}
+		if old.Pos() == ident.Pos() || info.Defs[ident] == old {
+			continue
+		}
+		if _, isVar := old.(*types.Var); isVar && assign != nil {
+			if t := assignedType(info, assign, i); t != nil && types.Identical(t, old.Type()) {
+				reused++
+				continue
+			}
+		}
+		s.supersede(info, old, lhs)
+	}
+	if assign != nil && reused == len(idents) {
+		assign.Tok = token.ASSIGN
+	}
+}
+
+// supersede renames the variable declared before, and its uses except the
+// identifiers declared again.
+func (s *Session) supersede(info *types.Info, old types.Object, skip map[*ast.Ident]bool) {
+	var decls, uses []*ast.Ident
+	for ident, obj := range info.Defs {
+		if obj == old {
+			decls = append(decls, ident)
+		}
+	}
+	for ident, obj := range info.Uses {
+		if obj == old && !skip[ident] {
+			uses = append(uses, ident)
+		}
+	}
+	// the bindings of the results not added yet refer to the variable
+	for _, r := range s.results.pending {
+		ast.Inspect(r.stmt, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && ident.Name == old.Name() {
+				uses = append(uses, ident)
+			}
+			return true
+		})
+	}
+
+	name := "_"
+	if len(uses) > 0 {
+		used := map[string]bool{}
+		collectIdents(used, s.file)
+		for n := 1; ; n++ {
+			if name = old.Name() + "_" + strconv.Itoa(n); !used[name] {
+				break
+			}
+		}
+		fmt.Fprintf(s.stderr, s.tr("%s is declared again, the old one renamed to %s")+"\n", old.Name(), name)
+	}
+	// the nodes are changed in place, to be undone if the input fails
+	for _, ident := range append(decls, uses...) {
+		ident := ident
+		s.last.undo = append(s.last.undo, func() { ident.Name = old.Name() })
+		ident.Name = name
+	}
+
+	// the statement declaring only the blank identifiers assigns them
+	for _, stmt := range s.mainBody.List {
+		if assign, ok := stmt.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE && countNonBlank(assign.Lhs) == 0 {
+			s.last.undo = append(s.last.undo, func() { assign.Tok = token.DEFINE })
+			assign.Tok = token.ASSIGN
+		}
+	}
+}
+
+// replaceTypes removes the types declared again by the declaration, which
+// replaces them.
+func (s *Session) replaceTypes(decl *ast.GenDecl) {
+	names := map[string]bool{}
+	for _, spec := range decl.Specs {
+		names[spec.(*ast.TypeSpec).Name.Name] = true
+	}
+	files := []*ast.File{s.file}
+	for _, path := range s.declFilePaths() {
+		files = append(files, s.extraFileOf(path))
+	}
+	for _, f := range files {
+		var decls []ast.Decl
+		for _, d := range f.Decls {
+			if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.TYPE {
+				var specs []ast.Spec
+				for _, spec := range d.Specs {
+					if !names[spec.(*ast.TypeSpec).Name.Name] {
+						specs = append(specs, spec)
+					}
+				}
+				if len(specs) == 0 {
+					continue
+				}
+				if orig := d.Specs; len(specs) < len(orig) {
+					s.last.undo = append(s.last.undo, func() { d.Specs = orig })
+					d.Specs = specs
+				}
+			}
+			decls = append(decls, d)
+		}
+		f.Decls = decls
+	}
+}
+
+// declaredIdents returns the identifiers of the variables and the constants
+// declared by the statement, by :=, var or const.
+func declaredIdents(stmt ast.Stmt) []*ast.Ident {
+	var idents []*ast.Ident
+	switch stmt := stmt.(type) {
+	case *ast.AssignStmt:
+		if stmt.Tok != token.DEFINE {
+			return nil
+		}
+		for _, expr := range stmt.Lhs {
+			if ident, ok := expr.(*ast.Ident); ok && ident.Name != "_" {
+				idents = append(idents, ident)
+			}
+		}
+	case *ast.DeclStmt:
+		decl, ok := stmt.Decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.VAR && decl.Tok != token.CONST {
+			return nil
+		}
+		for _, spec := range decl.Specs {
+			for _, ident := range spec.(*ast.ValueSpec).Names {
+				if ident.Name != "_" {
+					idents = append(idents, ident)
+				}
+			}
+		}
+	}
+	return idents
+}
+
+// assignedType returns the type of the value assigned to the i-th of the
+// identifiers not blank on the left side, or nil if unknown.
+func assignedType(info *types.Info, assign *ast.AssignStmt, i int) types.Type {
+	var j int
+	for k, expr := range assign.Lhs {
+		if isNamedIdent(expr, "_") {
+			continue
+		}
+		if j == i {
+			i = k
+			break
+		}
+		j++
+	}
+	if len(assign.Rhs) == len(assign.Lhs) {
+		if t := info.TypeOf(assign.Rhs[i]); t != nil {
+			return types.Default(t)
+		}
+	} else if len(assign.Rhs) == 1 {
+		if tuple, ok := info.TypeOf(assign.Rhs[0]).(*types.Tuple); ok && i < tuple.Len() {
+			return tuple.At(i).Type()
+		}
+	}
+	return nil
+}
+
+func countNonBlank(exprs []ast.Expr) int {
+	var n int
+	for _, expr := range exprs {
+		if !isNamedIdent(expr, "_") {
+			n++
+		}
+	}
+	return n
+}
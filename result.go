@@ -0,0 +1,218 @@
+package gore
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// The value of an expression is bound to the variable res0, res1, ..., so
+// that it can be used by the later inputs. The variable of the last result
+// is also referred to by _, which is replaced with the name on the input.
+//
+// The binding of a pure expression (e.g. x) is not added to main until the
+// variable is used, not to add a statement on every look at a value, which
+// would change the statement numbers. It is inserted at the position of the
+// expression, to have the value at the time.
+const resultPrefix = "res"
+
+// resultState is the state of the result variables.
+type resultState struct {
+	next    int      // the number of the next result variable
+	last    string   // the name of the last result variable
+	pending []result // the results not added to main yet
+}
+
+// result is the binding of the variable of a result.
+type result struct {
+	name string
+	stmt ast.Stmt
+	at   int // the number of the statements before the binding, or -1 if added
+}
+
+// bindResult binds the value printed by the last statement to the variable
+// of the next result, or returns nil if the value cannot be bound (e.g. no
+// value, multiple values and untyped nil).
+func (s *Session) bindResult() *result {
+	if len(s.mainBody.List) == 0 {
+		return nil
+	}
+	i := len(s.mainBody.List) - 1
+	exprs := printedExprs(s.mainBody.List[i])
+	if len(exprs) != 1 {
+		return nil
+	}
+	tv, ok := s.typeInfo.Types[exprs[0]]
+	if !ok || !tv.IsValue() || tv.IsNil() {
+		return nil
+	}
+	if _, ok := tv.Type.(*types.Tuple); ok {
+		return nil
+	}
+
+	used := map[string]bool{}
+	collectIdents(used, s.file)
+	for _, r := range s.results.pending {
+		used[r.name] = true
+	}
+	n := s.results.next
+	for used[resultPrefix+strconv.Itoa(n)] {
+		n++
+	}
+	name := resultPrefix + strconv.Itoa(n)
+	stmt := &ast.AssignStmt{Lhs: []ast.Expr{ast.NewIdent(name)}, Tok: token.DEFINE, Rhs: exprs}
+
+	if s.isPureExpr(exprs[0]) {
+		return &result{name: name, stmt: stmt, at: i}
+	}
+	s.mainBody.List = append(s.mainBody.List[:i:i], stmt,
+		&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent(printerName), Args: []ast.Expr{ast.NewIdent(name)}}},
+	)
+	return &result{name: name, stmt: stmt, at: -1}
+}
+
+// commitResult records the result bound by bindResult after the run.
+func (s *Session) commitResult(r *result) {
+	n, _ := strconv.Atoi(strings.TrimPrefix(r.name, resultPrefix))
+	s.results.next, s.results.last = n+1, r.name
+	if r.at >= 0 {
+		s.results.pending = append(s.results.pending, *r)
+	}
+}
+
+// addUsedResults adds the bindings of the results used in main.
+func (s *Session) addUsedResults() {
+	if len(s.results.pending) == 0 {
+		return
+	}
+	used := map[string]bool{}
+	collectIdents(used, s.mainBody)
+
+	var results []result
+	for _, r := range s.results.pending {
+		if !used[r.name] {
+			results = append(results, r)
+			continue
+		}
+		// the results are in the order of the positions
+		list := s.mainBody.List
+		s.mainBody.List = append(list[:r.at:r.at], append([]ast.Stmt{r.stmt}, list[r.at:]...)...)
+		for name, n := range s.marks {
+			if n > r.at {
+				s.marks[name] = n + 1
+			}
+		}
+		for i := range s.results.pending {
+			if s.results.pending[i].at > r.at {
+				s.results.pending[i].at++
+			}
+		}
+	}
+	s.results.pending = results
+}
+
+// dropResults adjusts the positions of the results on dropping the
+// statements in the range, and forgets the results in the range.
+func (s *Session) dropResults(from, to int) {
+	var results []result
+	for _, r := range s.results.pending {
+		if r.at >= to {
+			r.at -= to - from + 1
+		} else if r.at >= from {
+			continue
+		}
+		results = append(results, r)
+	}
+	s.results.pending = results
+}
+
+// expandLastResult replaces _ used as a value in the node with the name of
+// the last result, so that it refers to the same value on running again.
+func expandLastResult(node ast.Node, name string) {
+	if node == nil || name == "" {
+		return
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if !isNamedIdent(lhs, "_") {
+					expandLastResult(lhs, name)
+				}
+			}
+			for _, rhs := range n.Rhs {
+				expandLastResult(rhs, name)
+			}
+			return false
+		case *ast.RangeStmt:
+			expandLastResult(n.X, name)
+			expandLastResult(n.Body, name)
+			return false
+		case *ast.ValueSpec:
+			if n.Type != nil {
+				expandLastResult(n.Type, name)
+			}
+			for _, value := range n.Values {
+				expandLastResult(value, name)
+			}
+			return false
+		case *ast.Field:
+			expandLastResult(n.Type, name)
+			return false
+		case *ast.TypeSpec:
+			expandLastResult(n.Type, name)
+			return false
+		case *ast.Ident:
+			if n.Name == "_" {
+				n.Name = name
+			}
+		}
+		return true
+	})
+}
+
+// isResultName reports whether the name is of a result variable.
+func isResultName(name string) bool {
+	n := strings.TrimPrefix(name, resultPrefix)
+	return n != name && n != "" && strings.Trim(n, "0123456789") == ""
+}
+
+// foldResults returns the statements with the bindings of the results not
+// used by the others folded, to be shown to or exported for the user.
+func (s *Session) foldResults(stmts []ast.Stmt) []ast.Stmt {
+	used := map[string]bool{}
+	for _, stmt := range stmts {
+		if name, expr := resultBinding(stmt); name != "" {
+			collectIdents(used, expr)
+		} else {
+			collectIdents(used, stmt)
+		}
+	}
+
+	var folded []ast.Stmt
+	for _, stmt := range stmts {
+		name, expr := resultBinding(stmt)
+		switch {
+		case name == "" || used[name]:
+			folded = append(folded, stmt)
+		case !s.isPureExpr(expr):
+			folded = append(folded, &ast.AssignStmt{Lhs: []ast.Expr{ast.NewIdent("_")}, Tok: token.ASSIGN, Rhs: []ast.Expr{expr}})
+		}
+	}
+	return folded
+}
+
+// resultBinding returns the name and the value of the binding of a result.
+func resultBinding(stmt ast.Stmt) (string, ast.Expr) {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || assign.Tok != token.DEFINE || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return "", nil
+	}
+	ident, ok := assign.Lhs[0].(*ast.Ident)
+	if !ok || !isResultName(ident.Name) {
+		return "", nil
+	}
+	return ident.Name, assign.Rhs[0]
+}
@@ -0,0 +1,504 @@
+package gore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The program is run again for every evaluation, and so are the declarations
+// with side effects: the init functions and the initializers of the package
+// variables (e.g. opening a log file again and again). With
+// :set rerun-decls off, they are run once, until they are changed: the bodies
+// of the init functions run are emptied in the later runs, and the values of
+// the variables are kept in files by gob, to be loaded instead of running the
+// initializers. The values gob cannot keep as they are (e.g. functions and
+// files) are initialized on every evaluation, with a warning.
+const (
+	onceFileName = "gore_once.go"
+	onceDirName  = "gore_once"
+	onceKeepName = "__gore_keep"
+	onceLoadName = "__gore_load"
+)
+
+const onceSourceTemplate = `package main
+
+import (
+	"encoding/gob"
+	"os"
+)
+
+func init() {
+%s}
+
+func ` + onceKeepName + `(path string, v any) {
+	f, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	err = gob.NewEncoder(f).Encode(v)
+	if cerr := f.Close(); err != nil || cerr != nil {
+		os.Remove(path)
+	}
+}
+
+func ` + onceLoadName + `(path string, v any) {
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(v); err != nil {
+		panic(err)
+	}
+}
+`
+
+// runOnce is the state of the declarations run once.
+type runOnce struct {
+	enabled bool              // whether to run the declarations once (:set rerun-decls off)
+	ran     map[string]bool   // the keys of the init functions and the blank variables run
+	keeping map[string]string // the names of the variables kept by the run, by the paths of the files
+	warned  map[string]bool   // the keys of the variables warned not to be kept
+	dirty   map[string]bool   // the paths of the imported files written with the changes
+	file    bool              // whether the run has the file of onceFileName
+}
+
+// reset forgets the declarations run, as the session is cleared.
+func (o *runOnce) reset(tempDir string) {
+	o.ran, o.keeping, o.warned = nil, nil, nil
+	if err := os.RemoveAll(filepath.Join(tempDir, onceDirName)); err != nil {
+		debugf("runOnce.reset :: err = %s", err)
+	}
+}
+
+// isInitFunc reports whether the declaration is an init function.
+func isInitFunc(decl ast.Decl) bool {
+	fn, ok := decl.(*ast.FuncDecl)
+	return ok && fn.Recv == nil && fn.Name.Name == "init"
+}
+
+// warnRerunDecls warns that the declarations with side effects run on every
+// evaluation, unless :set rerun-decls off.
+func (s *Session) warnRerunDecls(decls []ast.Decl) {
+	if s.once.enabled {
+		return
+	}
+	for _, decl := range decls {
+		if isInitFunc(decl) {
+			fmt.Fprintln(s.stderr, s.tr("warning: init runs on every evaluation (:set rerun-decls off to run it once)"))
+			continue
+		}
+		for _, spec := range varSpecs(decl) {
+			if len(spec.Values) == 0 || !s.hasSideEffects(spec.Values...) {
+				continue
+			}
+			names := make([]string, len(spec.Names))
+			for i, name := range spec.Names {
+				names[i] = name.Name
+			}
+			fmt.Fprintf(s.stderr, s.tr("warning: the initializer of %s runs on every evaluation (:set rerun-decls off to run it once)")+"\n", strings.Join(names, ", "))
+		}
+	}
+}
+
+// varSpecs returns the specs of the declaration of package variables.
+func varSpecs(decl ast.Decl) []*ast.ValueSpec {
+	gen, ok := decl.(*ast.GenDecl)
+	if !ok || gen.Tok != token.VAR {
+		return nil
+	}
+	specs := make([]*ast.ValueSpec, 0, len(gen.Specs))
+	for _, spec := range gen.Specs {
+		if spec, ok := spec.(*ast.ValueSpec); ok && !strings.HasPrefix(spec.Names[0].Name, "__gore_") {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// hasSideEffects reports whether the expressions call functions or receive
+// from channels, other than the conversions and the builtin functions.
+func (s *Session) hasSideEffects(exprs ...ast.Expr) bool {
+	var found bool
+	for _, expr := range exprs {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.CallExpr:
+				if tv, ok := s.typeInfo.Types[n.Fun]; !ok || !tv.IsType() && !tv.IsBuiltin() {
+					found = true
+				}
+			case *ast.UnaryExpr:
+				if n.Op == token.ARROW {
+					found = true
+				}
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// onceFiles returns the paths of the files with the declarations to run
+// once, and the files: the main file, the files of :file and the files
+// imported.
+func (s *Session) onceFiles() ([]string, []*ast.File) {
+	paths, files := []string{s.tempFilePath}, []*ast.File{s.file}
+	for i, path := range s.extraFilePaths {
+		if s.isDeclFile(path) || strings.HasPrefix(filepath.Base(path), "gore_external_") {
+			paths, files = append(paths, path), append(files, s.extraFiles[i])
+		}
+	}
+	return paths, files
+}
+
+// onceKey returns the key of the node to tell whether it is changed.
+func (s *Session) onceKey(prefix string, node ast.Node) string {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	if err := printer.Fprint(&sb, s.fset, node); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+// onceKeepPath returns the path of the file to keep the value of the key.
+func (s *Session) onceKeepPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.tempDir, onceDirName, hex.EncodeToString(sum[:8])+".gob")
+}
+
+// applyRunOnce changes the declarations run not to run them again, writes
+// the imported files changed and the file of onceFileName, and returns the
+// function to restore the declarations, which must be called after the
+// source is written.
+func (s *Session) applyRunOnce() func() {
+	var restores []func()
+	restore := func() {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+	}
+	s.once.keeping = nil
+	if !s.once.enabled && len(s.once.dirty) == 0 {
+		return restore
+	}
+
+	var keeps []string
+	var loads int
+	paths, files := s.onceFiles()
+	for j, f := range files {
+		path, changed := paths[j], false
+		for _, decl := range f.Decls {
+			if !s.once.enabled {
+				break
+			}
+			if fn, ok := decl.(*ast.FuncDecl); ok && isInitFunc(fn) {
+				if body := fn.Body; s.once.ran[s.onceKey("", fn)] {
+					fn.Body = &ast.BlockStmt{}
+					restores = append(restores, func() { fn.Body = body })
+					changed = true
+				}
+				continue
+			}
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range varSpecs(gen) {
+				spec := spec
+				if len(spec.Values) == 0 || !s.hasSideEffects(spec.Values...) {
+					continue
+				}
+				if isBlankVar(spec) {
+					if r := s.skipBlankVar(gen, spec); r != nil {
+						restores = append(restores, r)
+						changed = true
+					}
+					continue
+				}
+				if len(spec.Names) != len(spec.Values) {
+					s.warnNotKept(spec.Names, s.onceKey("", spec))
+					continue
+				}
+				for i, name := range spec.Names {
+					i := i
+					if name.Name == "_" {
+						continue
+					}
+					key := s.onceKey(name.Name+"\x00", spec)
+					typ, ok := s.keepableType(f, spec, i)
+					if !ok {
+						s.warnNotKept(spec.Names[i:i+1], key)
+						continue
+					}
+					keepPath := s.onceKeepPath(key)
+					if _, err := os.Stat(keepPath); err != nil {
+						if s.once.keeping == nil {
+							s.once.keeping = make(map[string]string)
+						}
+						s.once.keeping[keepPath] = name.Name
+						keeps = append(keeps, fmt.Sprintf("\t%s(%s, %s)\n", onceKeepName, strconv.Quote(keepPath+".new"), name.Name))
+						continue
+					}
+					load, err := parser.ParseExpr(fmt.Sprintf("func() (v %s) { %s(%s, &v); return }()", typ, onceLoadName, strconv.Quote(keepPath)))
+					if err != nil {
+						debugf("applyRunOnce :: err = %s", err)
+						continue
+					}
+					value := spec.Values[i]
+					spec.Values[i] = load
+					restores = append(restores, func() { spec.Values[i] = value })
+					changed = true
+					loads++
+				}
+			}
+		}
+		if path == s.tempFilePath || s.isDeclFile(path) {
+			continue
+		}
+		// the imported files are written only on the import
+		if changed || s.once.dirty[path] {
+			if err := writeFile(path, s.fset, f); err != nil {
+				debugf("applyRunOnce :: err = %s", err)
+			}
+			delete(s.once.dirty, path)
+			if changed {
+				if s.once.dirty == nil {
+					s.once.dirty = make(map[string]bool)
+				}
+				s.once.dirty[path] = true
+			}
+		}
+	}
+
+	path := filepath.Join(s.tempDir, onceFileName)
+	s.once.file = len(keeps) > 0 || loads > 0
+	if !s.once.file {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			debugf("applyRunOnce :: err = %s", err)
+		}
+		return restore
+	}
+	if err := os.MkdirAll(filepath.Join(s.tempDir, onceDirName), 0o755); err != nil {
+		debugf("applyRunOnce :: err = %s", err)
+	}
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(onceSourceTemplate, strings.Join(keeps, ""))), 0o644); err != nil {
+		debugf("applyRunOnce :: err = %s", err)
+		s.once.file = false
+	}
+	return restore
+}
+
+// isBlankVar reports whether the spec declares only blank variables (e.g.
+// var _ = f()), run for the side effects.
+func isBlankVar(spec *ast.ValueSpec) bool {
+	for _, name := range spec.Names {
+		if name.Name != "_" {
+			return false
+		}
+	}
+	return true
+}
+
+// skipBlankVar removes the spec of blank variables run from the declaration,
+// and returns the function to put it back, or nil if not run.
+func (s *Session) skipBlankVar(gen *ast.GenDecl, spec *ast.ValueSpec) func() {
+	if !s.once.ran[s.onceKey("_\x00", spec)] {
+		return nil
+	}
+	specs := gen.Specs
+	gen.Specs = make([]ast.Spec, 0, len(specs))
+	for _, sp := range specs {
+		if sp != spec {
+			gen.Specs = append(gen.Specs, sp)
+		}
+	}
+	return func() { gen.Specs = specs }
+}
+
+// keepableType returns the type of the i-th variable of the spec written in
+// the file, if the value can be kept by gob.
+func (s *Session) keepableType(f *ast.File, spec *ast.ValueSpec, i int) (string, bool) {
+	if spec.Type != nil {
+		tv, ok := s.typeInfo.Types[spec.Type]
+		if !ok || !keepable(tv.Type, map[types.Type]bool{}) {
+			return "", false
+		}
+		return s.onceKey("", spec.Type), true
+	}
+	tv, ok := s.typeInfo.Types[spec.Values[i]]
+	if !ok || tv.Type == nil {
+		return "", false
+	}
+	typ := types.Default(tv.Type)
+	if !keepable(typ, map[types.Type]bool{}) {
+		return "", false
+	}
+
+	names := make(map[string]string)
+	for _, imp := range f.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if imp.Name != nil {
+			names[path] = imp.Name.Name
+		} else {
+			names[path] = ""
+		}
+	}
+	ok = true
+	str := types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg.Name() == "main" {
+			return ""
+		}
+		name, imported := names[pkg.Path()]
+		switch {
+		case !imported || name == "_":
+			ok = false
+		case name == "":
+			return pkg.Name()
+		}
+		if name == "." {
+			return ""
+		}
+		return name
+	})
+	return str, ok
+}
+
+// keepable reports whether the value of the type can be kept by gob as it
+// is, which drops the unexported fields and cannot encode functions,
+// channels and interfaces.
+func keepable(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *types.Basic:
+		return t.Info()&types.IsUntyped == 0 && t.Kind() != types.UnsafePointer
+	case *types.Pointer:
+		return keepable(t.Elem(), seen)
+	case *types.Slice:
+		return keepable(t.Elem(), seen)
+	case *types.Array:
+		return keepable(t.Elem(), seen)
+	case *types.Map:
+		return keepable(t.Key(), seen) && keepable(t.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if !t.Field(i).Exported() || !keepable(t.Field(i).Type(), seen) {
+				return false
+			}
+		}
+		return true
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() == nil || obj.Pkg().Name() != "main" && !obj.Exported() {
+			return false
+		}
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if !keepable(t.TypeArgs().At(i), seen) {
+				return false
+			}
+		}
+		mset := types.NewMethodSet(types.NewPointer(t))
+		if mset.Lookup(nil, "GobEncode") != nil || mset.Lookup(nil, "MarshalBinary") != nil {
+			return true
+		}
+		return keepable(t.Underlying(), seen)
+	}
+	return false
+}
+
+// warnNotKept warns that the values of the variables cannot be kept, once
+// for the key.
+func (s *Session) warnNotKept(names []*ast.Ident, key string) {
+	if s.once.warned[key] {
+		return
+	}
+	if s.once.warned == nil {
+		s.once.warned = make(map[string]bool)
+	}
+	s.once.warned[key] = true
+	for _, name := range names {
+		fmt.Fprintf(s.stderr, s.tr("warning: the value of %s cannot be kept; its initializer runs on every evaluation")+"\n", name.Name)
+	}
+}
+
+// commitRunOnce remembers the declarations run by the program if it exited
+// successfully, or discards the values kept by the run.
+func (s *Session) commitRunOnce(ok bool) {
+	if !s.once.enabled {
+		return
+	}
+	for path, name := range s.once.keeping {
+		if !ok {
+			os.Remove(path + ".new")
+			continue
+		}
+		if err := os.Rename(path+".new", path); err != nil {
+			debugf("commitRunOnce :: err = %s", err)
+			s.warnNotKept([]*ast.Ident{ast.NewIdent(name)}, path)
+		}
+	}
+	s.once.keeping = nil
+	if !ok {
+		return
+	}
+	if s.once.ran == nil {
+		s.once.ran = make(map[string]bool)
+	}
+	_, files := s.onceFiles()
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if isInitFunc(decl) {
+				s.once.ran[s.onceKey("", decl)] = true
+				continue
+			}
+			for _, spec := range varSpecs(decl) {
+				if isBlankVar(spec) && len(spec.Values) > 0 {
+					s.once.ran[s.onceKey("_\x00", spec)] = true
+				}
+			}
+		}
+	}
+}
+
+// writeFile writes the file of the syntax tree.
+func writeFile(path string, fset *token.FileSet, f *ast.File) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := printer.Fprint(out, fset, f); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func setRerunDecls(s *Session, value string) error {
+	on, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.once.enabled = !on
+	if on {
+		s.once.reset(s.tempDir)
+	}
+	return nil
+}
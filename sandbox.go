@@ -0,0 +1,92 @@
+package gore
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The programs run in the containers by -sandbox docker, with the session
+// directory mounted at the same path and without the network, so that the
+// code does not touch the files or the network of the host. The programs
+// are built for linux without cgo to run on any image. The go command (e.g.
+// of :test) still runs on the host.
+const sandboxDocker = "docker"
+
+// DefaultSandboxImage is the image of the containers the programs run in,
+// unless specified.
+const DefaultSandboxImage = "gcr.io/distroless/static-debian12"
+
+// sandboxConfig is the container the programs run in.
+type sandboxConfig struct {
+	kind  string // the kind of the sandbox, or "" to run on the host
+	image string
+}
+
+// setSandbox sets the sandbox of the kind ("" for none) and the image
+// ("" for the default one).
+func (s *Session) setSandbox(kind, image string) error {
+	switch kind {
+	case "", "none":
+		s.sandbox = sandboxConfig{}
+		return nil
+	case sandboxDocker:
+	default:
+		return fmt.Errorf("unknown sandbox: %s (docker)", kind)
+	}
+	if _, err := exec.LookPath(kind); err != nil {
+		return fmt.Errorf("command not found: %s", kind)
+	}
+	if image == "" {
+		image = DefaultSandboxImage
+	}
+	s.sandbox = sandboxConfig{kind: kind, image: image}
+	return nil
+}
+
+// programCommand returns the command to run the program of the path in the
+// working directory, in the container if sandboxed.
+func (s *Session) programCommand(path string, args ...string) *exec.Cmd {
+	if s.sandbox.kind == "" {
+		cmd := s.limitedCommand(path, args...)
+		cmd.Dir = s.currentWorkDir()
+		return cmd
+	}
+	return exec.Command(s.sandbox.kind, append(s.sandboxArgs(path), args...)...)
+}
+
+// sandboxArgs returns the arguments of docker run for the program. Only the
+// session directory is mounted, so the program runs in it if the working
+// directory is out of it.
+func (s *Session) sandboxArgs(path string) []string {
+	dir := s.currentWorkDir()
+	if rel, err := filepath.Rel(s.tempDir, dir); err != nil || strings.HasPrefix(rel, "..") {
+		dir = s.tempDir
+	}
+	args := []string{"run", "--rm", "-i", "--network", "none", "-v", s.tempDir + ":" + s.tempDir, "-w", dir}
+	if runtime.GOOS != "windows" {
+		// the files written by the program are of the user
+		args = append(args, "--user", strconv.Itoa(os.Getuid())+":"+strconv.Itoa(os.Getgid()))
+	}
+	args = append(args, s.limitArgs()...)
+	// the variables set by :env are given to the program, not the others
+	var env []string
+	for key, o := range s.env {
+		if !o.unset {
+			env = append(env, key+"="+o.value)
+		}
+	}
+	sort.Strings(env)
+	if s.run.deterministic {
+		env = withDeterministicEnv(env)
+	}
+	for _, kv := range env {
+		args = append(args, "-e", kv)
+	}
+	return append(args, s.sandbox.image, path)
+}
@@ -0,0 +1,49 @@
+package gore
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_programCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the user is not given on Windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gore_program")
+	s := &Session{tempDir: dir, env: map[string]envOverride{
+		"B": {value: "2"},
+		"A": {value: "1"},
+		"C": {unset: true},
+	}}
+
+	cmd := s.programCommand(path, "x")
+	assert.Equal(t, []string{path, "x"}, cmd.Args)
+	assert.Equal(t, dir, cmd.Dir)
+
+	s.sandbox = sandboxConfig{kind: sandboxDocker, image: "img"}
+	s.workDir = os.TempDir()
+	user := strconv.Itoa(os.Getuid()) + ":" + strconv.Itoa(os.Getgid())
+	cmd = s.programCommand(path, "x")
+	assert.Equal(t, []string{
+		"docker", "run", "--rm", "-i", "--network", "none", "-v", dir + ":" + dir, "-w", dir,
+		"--user", user, "-e", "A=1", "-e", "B=2", "img", path, "x",
+	}, cmd.Args)
+
+	s.workDir = filepath.Join(dir, "sub")
+	s.run.deterministic = true
+	cmd = s.programCommand(path)
+	assert.Equal(t, []string{
+		"docker", "run", "--rm", "-i", "--network", "none", "-v", dir + ":" + dir, "-w", s.workDir,
+		"--user", user, "-e", "A=1", "-e", "B=2", "-e", "GOMAXPROCS=1", "-e", "GODEBUG=randseednop=0", "img", path,
+	}, cmd.Args)
+
+	assert.EqualError(t, s.setSandbox("podman", ""), "unknown sandbox: podman (docker)")
+	assert.NoError(t, s.setSandbox("", ""))
+	assert.Equal(t, sandboxConfig{}, s.sandbox)
+}
@@ -0,0 +1,163 @@
+package gore
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+type rpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type evalParams struct {
+	Code string `json:"code"`
+}
+
+type evalResult struct {
+	Output string `json:"output"`
+	Error  string `json:"error"`
+	Source string `json:"source"`
+}
+
+type completeParams struct {
+	Code string `json:"code"`
+	Pos  *int   `json:"pos"`
+}
+
+type completeResult struct {
+	Prefix     string   `json:"prefix"`
+	Candidates []string `json:"candidates"`
+	Suffix     string   `json:"suffix"`
+	Docs       []string `json:"docs,omitempty"` // the summaries of the documents of the candidates
+}
+
+// serve speaks JSON-RPC 2.0 over r and w, one message per line.
+// The methods are:
+//   - eval {code} -> {output, error, source}
+//   - complete {code, pos} -> {prefix, candidates, suffix}
+//   - reset -> {}
+func (s *Session) serve(r io.Reader, w io.Writer) error {
+	// the outputs are returned in the results, and w is for the responses
+	s.setOutput(io.Discard, io.Discard)
+
+	dec := json.NewDecoder(r)
+	enc := json.NewEncoder(w)
+	for {
+		var req rpcRequest
+		if err := dec.Decode(&req); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			// the stream cannot be recovered after a syntax error
+			return enc.Encode(rpcResponse{
+				Version: "2.0", ID: json.RawMessage("null"),
+				Error: &rpcError{Code: rpcParseError, Message: err.Error()},
+			})
+		}
+
+		result, err := s.serveRequest(&req)
+		if req.ID == nil {
+			// notification
+			if err == ErrQuit {
+				return nil
+			}
+			continue
+		}
+
+		resp := rpcResponse{Version: "2.0", ID: req.ID, Result: result}
+		if err != nil && err != ErrQuit {
+			rerr, ok := err.(*rpcError)
+			if !ok {
+				rerr = &rpcError{Code: rpcInternalError, Message: err.Error()}
+			}
+			resp.Result, resp.Error = nil, rerr
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+		if err == ErrQuit {
+			return nil
+		}
+	}
+}
+
+func (s *Session) serveRequest(req *rpcRequest) (any, error) {
+	if req.Version != "2.0" || req.Method == "" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: "invalid request"}
+	}
+
+	switch req.Method {
+	case "eval":
+		var params evalParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		r, err := s.Eval(params.Code)
+		switch err {
+		case nil, ErrQuit:
+		case ErrContinue:
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "incomplete input"}
+		default:
+			// the error is reported in r.Error
+			err = nil
+		}
+		return evalResult{Output: r.Output, Error: r.Error, Source: r.Source}, err
+
+	case "complete":
+		var params completeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		pos := len(params.Code)
+		if params.Pos != nil {
+			pos = *params.Pos
+		}
+		if pos < 0 || pos > len(params.Code) {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "pos out of range"}
+		}
+		prefix, cands, suffix := s.completeWord(params.Code, pos)
+		if cands == nil {
+			cands = []string{}
+		}
+		return completeResult{
+			Prefix: prefix, Candidates: cands, Suffix: suffix,
+			Docs: s.memberDocs(params.Code, len(prefix), cands),
+		}, nil
+
+	case "reset":
+		if err := s.init(); err != nil {
+			return nil, err
+		}
+		return struct{}{}, nil
+	}
+
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + req.Method}
+}
@@ -0,0 +1,43 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_serve(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eval","params":{"code":"x := 1"}}
+{"jsonrpc":"2.0","id":2,"method":"eval","params":{"code":"x + \"a\""}}
+{"jsonrpc":"2.0","id":3,"method":"eval","params":{"code":"func f() {"}}
+{"jsonrpc":"2.0","id":4,"method":"complete","params":{"code":":h"}}
+{"jsonrpc":"2.0","method":"reset"}
+{"jsonrpc":"2.0","id":"x","method":"eval","params":{"code":"x"}}
+{"jsonrpc":"2.0","id":5,"method":"foo"}
+{"jsonrpc":"2.0","id":6,"method":"eval","params":{"code":":quit"}}
+{"jsonrpc":"2.0","id":7,"method":"eval","params":{"code":"1"}}
+`)
+	var out strings.Builder
+	err = s.serve(in, &out)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, 7)
+	assert.Regexp(t, `^{"jsonrpc":"2.0","id":1,"result":{"output":"1\\n","error":"","source":"package main\\n.*x := 1.*"}}$`, lines[0])
+	assert.Regexp(t, `^{"jsonrpc":"2.0","id":2,"result":{"output":"","error":".*mismatched types.*","source":".*"}}$`, lines[1])
+	assert.Equal(t, `{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"incomplete input"}}`, lines[2])
+	assert.Equal(t, `{"jsonrpc":"2.0","id":4,"result":{"prefix":"","candidates":[":history ",":help "],"suffix":""}}`, lines[3])
+	assert.Regexp(t, `^{"jsonrpc":"2.0","id":"x","result":{"output":"","error":"undefined: x\\n","source":".*"}}$`, lines[4])
+	assert.Equal(t, `{"jsonrpc":"2.0","id":5,"error":{"code":-32601,"message":"method not found: foo"}}`, lines[5])
+	assert.Regexp(t, `^{"jsonrpc":"2.0","id":6,"result":{"output":"","error":"","source":".*"}}$`, lines[6])
+
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
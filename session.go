@@ -15,9 +15,14 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
+	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/imports"
 
@@ -34,17 +39,98 @@ type Session struct {
 	typeInfo        types.Info
 	extraFilePaths  []string
 	extraFiles      []*ast.File
+	declFile        *ast.File // the file of :file the declarations are added to, or nil for the main file
 	autoImport      bool
 	requiredModules []string
+	modules         []*goModule // the modules listed by go list -m all
+	printerPath     string
+	printerCode     string
+	format          formatSettings
+	run             runSettings
+	gistURL         string // the gist endpoint to share the session to, or "" for the Go Playground
+	transcript      *transcript
+	marks           map[string]int
+	checkpoints     []*checkpoint // the checkpoints of :checkpoint, the last one on the top
+	env             map[string]envOverride
+	goEnv           map[string]string // the variables of the go command set by the settings (e.g. goproxy)
+	args            []string
+	workDir         string
+	id              string
+	store           Store
+	history         []historyEntry
+	histControl     historyControl
+	cache           buildCache
+	inputNumber     int
+	results         resultState
+	numberedPrompt  bool
+	lang            string
+	a11y            bool
+	cgoPreamble     string
+	imports         importState
+	buildContext    build.Context
+	platforms       []string // GOOS/GOARCH supported by the go command, listed on demand
+	exit            exitState
+	once            runOnce
+	defers          deferState
 	mainBody        *ast.BlockStmt
-	lastStmts       []ast.Stmt
-	lastDecls       []ast.Decl
+	last            snapshot
+	stdin           io.Reader
+	stdinData       []byte                       // the input given by :stdin, or nil for stdin
+	terminal        func() func()                // hands the terminal over to the program, returning the function to take it back
+	hint            func(string)                 // shows the hint (e.g. the signature) on completion, if supported
+	confirm         func(prompt string) bool     // asks the user to answer yes or no, if supported
+	synopsisCache   map[string]map[string]string // the summaries of the documents by the package paths
+	autosaveEnabled bool                         // whether to save the session on quitting
+	summaryEnabled  bool                         // whether to print the summary on quitting
+	unsaved         bool                         // whether anything is input since the session was saved
+	savedBy         string                       // the command the session was saved by last (e.g. :write a.go)
+	capture         captureState
+	running         runningProgram
+	jobs            jobList
+	watch           watchState
+	sandbox         sandboxConfig
+	group           *sessionGroup // the sessions switched by :session, or nil if not supported
 	stdout          io.Writer
 	stderr          io.Writer
 }
 
+// formatSettings are the settings of the printer of the results.
+type formatSettings struct {
+	float          string
+	verb           string // the fmt verb of the printer, or "" for the default
+	stringer       bool   // whether to print the errors and the fmt.Stringer values by the methods
+	groupSeparator string
+	noColor        bool // whether to print without colors
+}
+
+// runSettings are the settings of running the program.
+type runSettings struct {
+	maxOutput     int64         // the limit of the outputs of a run, or 0 if not limited
+	spillOutput   bool          // whether to write the output omitted to a file
+	race          bool          // whether to run the code with the race detector
+	timeout       time.Duration // the timeout of a run, or 0 if not limited
+	wait          time.Duration // the duration to wait for the goroutines at the end, or 0 not to wait
+	deterministic bool          // whether to run reproducibly, with now() and rng
+	pager         bool          // whether to page the outputs longer than the terminal
+	memLimit      int64         // the limit of the memory of the program, or 0 if not limited
+	cpuLimit      time.Duration // the limit of the CPU time of the program, or 0 if not limited
+}
+
+// snapshot is the code stored before an input, restored if it fails.
+type snapshot struct {
+	stmts     []ast.Stmt
+	decls     []ast.Decl
+	results   []result
+	marks     map[string]int
+	fileDecls map[string][]ast.Decl // the declarations of the files of :file
+	undo      []func()              // the functions to undo the changes of the nodes (e.g. by redeclare)
+}
+
 const printerName = "__gore_p"
 
+// inputNumberName is the identifier replaced with the number of the input.
+const inputNumberName = "__n"
+
 const initialSourceTemplate = `
 package main
 
@@ -60,6 +146,18 @@ func main() {
 }
 `
 
+// printerSourceTemplate is used to regenerate the printer function
+// according to the formatting settings.
+const printerSourceTemplate = `
+package main
+
+func ` + printerName + `(xs ...any) {
+	for _, x := range xs {
+		%s
+	}
+}
+`
+
 // printerPkgs is a list of packages that provides pretty printing function
 // when changing this, read listModuleDirectives carefully
 var printerPkgs = []struct {
@@ -80,11 +178,19 @@ type pathVersion struct {
 
 // NewSession creates a new Session.
 func NewSession(stdout, stderr io.Writer) (*Session, error) {
+	return newSessionIn("", stdout, stderr)
+}
+
+// newSessionIn creates a new Session with the temporary directory under
+// root, or the default temporary directory if root is empty.
+func newSessionIn(root string, stdout, stderr io.Writer) (*Session, error) {
 	var err error
 
-	s := &Session{stdout: stdout, stderr: stderr}
+	s := &Session{id: newMessageID(), stdin: os.Stdin, env: map[string]envOverride{}, lang: localeLanguage(), buildContext: build.Default, autosaveEnabled: true, summaryEnabled: true, histControl: historyControl{size: historySize, dedupe: true}}
+	s.capture = captureState{stdout: &captureWriter{w: stdout}, stderr: &captureWriter{w: stderr}}
+	s.stdout, s.stderr = s.capture.stdout, s.capture.stderr
 
-	s.tempDir, err = os.MkdirTemp("", "gore-")
+	s.tempDir, err = makeTempDir(root)
 	if err != nil {
 		return s, err
 	}
@@ -98,14 +204,17 @@ func NewSession(stdout, stderr io.Writer) (*Session, error) {
 }
 
 type pkgsImporter struct {
-	dir string
+	dir   string
+	env   func() []string
+	flags func() []string
 }
 
 func (i *pkgsImporter) Import(path string) (*types.Package, error) {
 	pkgs, err := packages.Load(&packages.Config{
 		Mode:       packages.NeedTypes | packages.NeedDeps,
 		Dir:        i.dir,
-		BuildFlags: []string{"-mod=mod"},
+		Env:        i.env(),
+		BuildFlags: i.flags(),
 	}, path)
 	if err != nil {
 		return nil, err
@@ -120,35 +229,47 @@ func (i *pkgsImporter) Import(path string) (*types.Package, error) {
 
 func (s *Session) init() (err error) {
 	s.fset = token.NewFileSet()
-	s.types = &types.Config{Importer: &pkgsImporter{dir: s.tempDir}}
+	s.types = &types.Config{Importer: &pkgsImporter{dir: s.tempDir, env: s.loadEnviron, flags: s.buildFlags}, FakeImportC: true}
 	s.typeInfo = types.Info{}
 	s.extraFilePaths = nil
 	s.extraFiles = nil
+	s.declFile = nil
+	if s.run.deterministic {
+		if err := s.addDeterministicFile(); err != nil {
+			return err
+		}
+	}
 
 	if err = s.initGoMod(); err != nil { // this should be before printer load for printer package requirements
 		return err
 	}
 
-	var initialSource string
+	// the printer package is loaded only on the first initialization,
+	// as loading takes long and the result does not change by :clear
 	for _, pp := range printerPkgs {
+		if s.printerPath != "" {
+			break
+		}
 		_, err = packages.Load(
 			&packages.Config{
 				Dir:        s.tempDir,
-				BuildFlags: []string{"-mod=mod"},
+				Env:        s.loadEnviron(),
+				BuildFlags: s.buildFlags(),
 			},
 			pp.path,
 		)
 		if err == nil {
-			initialSource = fmt.Sprintf(initialSourceTemplate, pp.path, pp.code)
+			s.printerPath, s.printerCode = pp.path, pp.code
 			break
 		}
 		debugf("could not import %q: %s", pp.path, err)
 	}
 
-	if initialSource == "" {
+	if s.printerPath == "" {
 		return fmt.Errorf("could not load 'fmt' package: %w", err)
 	}
 
+	initialSource := fmt.Sprintf(initialSourceTemplate, s.printerPath, s.printerCode)
 	s.file, err = parser.ParseFile(s.fset, "gore_session.go", initialSource, parser.Mode(0))
 	if err != nil {
 		return err
@@ -156,8 +277,67 @@ func (s *Session) init() (err error) {
 
 	s.mainBody = s.mainFunc().Body
 
-	s.lastStmts = nil
-	s.lastDecls = nil
+	s.last.stmts = nil
+	s.last.decls = nil
+	s.last.fileDecls = nil
+	s.imports.names = nil
+	s.marks = nil
+	s.checkpoints = nil
+	s.once.reset(s.tempDir)
+	s.defers.ran = nil
+	s.cgoPreamble = ""
+	s.results.next, s.results.last, s.results.pending = 0, "", nil
+	return s.updatePrinter()
+}
+
+// updatePrinter rewrites the printer function according to the formatting settings.
+func (s *Session) updatePrinter() error {
+	var cases []string
+	if s.format.stringer && s.format.verb == "" {
+		// printed by fmt, which recovers the panic of the method (e.g. of nil)
+		cases = append(cases, "case error, fmt.Stringer:\n\tfmt.Println(x)")
+	}
+	if s.format.float != "" {
+		cases = append(cases, fmt.Sprintf("case float32, float64:\n\tfmt.Printf(%q, x)", s.format.float+"\n"))
+	}
+	if s.format.groupSeparator != "" {
+		cases = append(cases, fmt.Sprintf(`case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+	d := fmt.Sprint(x)
+	for i := len(d) - 3; i > 0 && d[i-1] != '-'; i -= 3 {
+		d = d[:i] + %q + d[i:]
+	}
+	fmt.Println(d)`, s.format.groupSeparator))
+	}
+
+	code := s.printerCode
+	if s.format.verb != "" {
+		code = fmt.Sprintf("fmt.Printf(%q, x)", s.format.verb+"\n")
+	} else if s.a11y || s.format.noColor || s.run.deterministic {
+		// print without colors, or with the keys of the maps sorted
+		code = printerPkgs[len(printerPkgs)-1].code
+	}
+	if len(cases) > 0 {
+		code = "switch x := x.(type) {\n" + strings.Join(cases, "\n") + "\ndefault:\n\t" + code + "\n}"
+	}
+	if s.a11y {
+		code = fmt.Sprintf("fmt.Print(%q)\n", a11yResultLabel) + code
+	}
+	if code != s.printerCode {
+		astutil.AddImport(s.fset, s.file, "fmt")
+	}
+
+	f, err := parser.ParseFile(s.fset, "printer.go", fmt.Sprintf(printerSourceTemplate, code), parser.Mode(0))
+	if err != nil {
+		return err
+	}
+
+	for i, d := range s.file.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok && isNamedIdent(d.Name, printerName) {
+			s.file.Decls[i] = f.Decls[0]
+			break
+		}
+	}
+
 	return nil
 }
 
@@ -167,31 +347,170 @@ func (s *Session) mainFunc() *ast.FuncDecl {
 
 // Run the session.
 func (s *Session) Run() error {
-	f, err := os.Create(s.tempFilePath)
-	if err != nil {
+	if err := s.writeSource(); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	err = printer.Fprint(f, s.fset, s.file)
+	err := s.goRun(s.runFiles())
+	s.commitRunOnce(err == nil && s.exit.status == 0)
+	s.commitDefers(err == nil && s.exit.status == 0)
+	return err
+}
+
+// writeSource writes the session source to the file to run.
+func (s *Session) writeSource() error {
+	return s.writeSourceTo(s.tempFilePath)
+}
+
+// writeSourceTo writes the session source to the file of the path.
+func (s *Session) writeSourceTo(path string) error {
+	defer s.applyRunOnce()()
+	if err := s.writeDeclFiles(); err != nil {
+		return err
+	}
+	defer s.skipRanDefers()()
+
+	restore, err := s.instrumentExits()
+	if err != nil {
+		return err
+	}
+	restoreWait := s.appendWait()
+	var buf bytes.Buffer
+	err = printer.Fprint(&buf, s.fset, s.file)
+	restoreWait()
+	restore()
 	if err != nil {
 		return err
 	}
 
-	return s.goRun(append(s.extraFilePaths, s.tempFilePath))
+	return os.WriteFile(path, s.withCgoPreamble(buf.Bytes()), 0o644)
 }
 
+// goRun builds the program of the files by go build, and runs it in the
+// working directory.
 func (s *Session) goRun(files []string) error {
-	args := append([]string{"run", "-mod=mod"}, files...)
+	s.exit.last, s.exit.status = nil, -1
+	args := append(append([]string{"build", "-o", s.programPath()}, s.runFlags()...), files...)
+	if err := s.goExec(args, s.stdout); err != nil {
+		return err
+	}
+
+	// the report of the exit may be left by :test
+	os.Remove(filepath.Join(s.tempDir, exitReportName))
+	cmd := s.programCommand(s.programPath(), s.args...)
+	w := s.stdout
+	var paged *bytes.Buffer
+	if _, _, ok := s.stdoutTerminal(); ok && s.run.pager {
+		// the output is written after the run, to page if long
+		paged = new(bytes.Buffer)
+		w = paged
+	}
+	err := s.execCmd(cmd, w, true)
+	if paged != nil {
+		if err := s.pageOutput(paged.Bytes()); err != nil {
+			debugf("pager: %s", err)
+		}
+	}
+	s.exit.last = s.readExit()
+	if err != nil && s.exit.last == nil {
+		// report the failure as go run does, e.g. exit status 2
+		fmt.Fprintln(s.stderr, err)
+	}
+	return err
+}
+
+// runningProgram is the process of the program running, if any.
+type runningProgram struct {
+	mu      sync.Mutex
+	process *os.Process
+}
+
+func (r *runningProgram) set(p *os.Process) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.process = p
+}
+
+// interrupt stops the program running as Ctrl-C does, if any.
+func (r *runningProgram) interrupt() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.process == nil {
+		return nil
+	}
+	// os.Interrupt is not supported on Windows
+	if err := r.process.Signal(os.Interrupt); err != nil {
+		return r.process.Kill()
+	}
+	return nil
+}
+
+// programPath returns the path of the program built to run.
+func (s *Session) programPath() string {
+	name := "gore_program"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(s.tempDir, name)
+}
+
+// goExec runs the go command (e.g. go test) in the temporary directory,
+// with the stdout written to w.
+func (s *Session) goExec(args []string, w io.Writer) error {
 	debugf("go %s", strings.Join(args, " "))
 	cmd := exec.Command("go", args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = s.stdout
 	cmd.Dir = s.tempDir
+	return s.execCmd(cmd, w, false)
+}
+
+// execCmd runs the command with the stdout written to w, keeping the exit
+// status of the program if run is true.
+func (s *Session) execCmd(cmd *exec.Cmd, w io.Writer, run bool) error {
+	cmd.Env = s.goEnviron()
+	if run {
+		cmd.Env = s.environ()
+	}
+	if run && s.run.deterministic {
+		cmd.Env = withDeterministicEnv(cmd.Env)
+	}
+	cmd.Stdin = s.stdin
+	limit := s.newOutputLimit()
+	defer s.closeOutputLimit(limit, s.stderr)
+	cmd.Stdout = limit.writer(w)
 	ef := newErrFilter(s.stderr)
-	cmd.Stderr = ef
 	defer ef.Close()
-	return cmd.Run()
+	cmd.Stderr = limit.writer(ef)
+	if !s.cache.checked {
+		s.cache.checked = true
+		s.checkGoCache()
+	}
+	restore := func() {}
+	if s.stdinData != nil {
+		cmd.Stdin = bytes.NewReader(s.stdinData)
+	} else if s.stdin != nil && s.terminal != nil && run {
+		restore = s.terminal()
+	}
+	start := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		if run {
+			s.running.set(cmd.Process)
+		}
+		err = cmd.Wait()
+		s.running.set(nil)
+	}
+	restore()
+	if !run {
+		// the time to build is what the build cache makes fast
+		s.recordRunTime(time.Since(start))
+	}
+	if s.transcript != nil && s.transcript.record != nil {
+		s.transcript.record.RunTime += time.Since(start)
+	}
+	if run {
+		s.exit.status = cmd.ProcessState.ExitCode()
+	}
+	return err
 }
 
 func (s *Session) evalExpr(in string) (ast.Expr, error) {
@@ -199,6 +518,7 @@ func (s *Session) evalExpr(in string) (ast.Expr, error) {
 	if err != nil {
 		return nil, err
 	}
+	expandLastResult(expr, s.results.last)
 
 	stmt := &ast.ExprStmt{
 		X: &ast.CallExpr{
@@ -230,6 +550,7 @@ func (s *Session) evalStmt(in string) error {
 	var stmts []ast.Stmt
 
 	for _, stmt := range enclosingFunc.Body.List {
+		expandLastResult(stmt, s.results.last)
 		switch stmt := stmt.(type) {
 		case *ast.AssignStmt:
 			if stmt := buildPrintStmt(stmt.Lhs); stmt != nil {
@@ -238,7 +559,8 @@ func (s *Session) evalStmt(in string) error {
 		case *ast.DeclStmt:
 			if decl, ok := stmt.Decl.(*ast.GenDecl); ok {
 				if decl.Tok == token.TYPE {
-					s.file.Decls = append(s.file.Decls, decl)
+					s.replaceTypes(decl)
+					s.addDecl(decl)
 					continue
 				} else if stmt := buildPrintStmtOfDecl(decl); stmt != nil {
 					stmts = append(stmts, stmt)
@@ -246,6 +568,7 @@ func (s *Session) evalStmt(in string) error {
 			}
 		}
 		s.appendStatements(stmt)
+		s.redeclare(stmt)
 	}
 	s.appendStatements(stmts...)
 
@@ -304,16 +627,136 @@ func (s *Session) evalFunc(in string) error {
 	if !ok {
 		return errors.New("eval func error")
 	}
-	for i, d := range s.file.Decls {
-		if d, ok := d.(*ast.FuncDecl); ok && d.Name.String() == newDecl.Name.String() {
-			s.file.Decls = append(s.file.Decls[:i], s.file.Decls[i+1:]...)
-			break
+	// a package may have several init functions
+	if !isInitFunc(newDecl) {
+		for i, d := range s.file.Decls {
+			if d, ok := d.(*ast.FuncDecl); ok && funcKey(d) == funcKey(newDecl) {
+				s.file.Decls = append(s.file.Decls[:i], s.file.Decls[i+1:]...)
+				break
+			}
 		}
+		s.removeFuncDecls(funcKey(newDecl))
 	}
-	s.file.Decls = append(s.file.Decls, newDecl)
+	s.addDecl(newDecl)
+	s.warnRerunDecls([]ast.Decl{newDecl})
 	return nil
 }
 
+// funcKey returns the name of the function, qualified by the receiver type
+// for a method, to tell the functions and the methods of the same name.
+func funcKey(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	typ := decl.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		typ = t.X
+	case *ast.IndexListExpr:
+		typ = t.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name + "." + decl.Name.Name
+	}
+	return decl.Name.Name
+}
+
+// evalInput adds the input as the kind told by inputKind, or tries the kinds
+// in turn if not told, returning the kind.
+func (s *Session) evalInput(in string) (string, error) {
+	switch kind := inputKind(in); kind {
+	case "expr":
+		_, err := s.evalExpr(in)
+		return kind, err
+	case "stmt":
+		return kind, s.evalStmt(in)
+	case "func":
+		return kind, s.evalFunc(in)
+	}
+
+	_, exprErr := s.evalExpr(in)
+	if exprErr == nil {
+		return "expr", nil
+	}
+	debugf("expr :: err = %s", exprErr)
+	err := s.evalStmt(in)
+	if err == nil {
+		return "stmt", nil
+	}
+	debugf("stmt :: err = %s", err)
+	// the error of the expression is reported unless the input starts with func
+	if err = s.evalFunc(in); err == nil || strings.HasPrefix(strings.TrimSpace(in), "func") {
+		return "func", err
+	}
+	return "expr", exprErr
+}
+
+// inputKind tells the kind of the input ("expr", "stmt" or "func") by the
+// first tokens and the tokens at the top level, or returns "" if they do not
+// tell (e.g. func (...) of a method or a function literal).
+func inputKind(in string) string {
+	var sc scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(in))
+	sc.Init(file, []byte(in), nil, 0)
+
+	var depth, n int
+	var first, prev token.Token
+	for ; ; n++ {
+		_, tok, _ := sc.Scan()
+		if tok == token.EOF {
+			break
+		}
+		switch {
+		case n == 0:
+			first = tok
+			switch tok {
+			case token.IF, token.FOR, token.SWITCH, token.SELECT, token.GO, token.DEFER,
+				token.RETURN, token.BREAK, token.CONTINUE, token.GOTO, token.FALLTHROUGH,
+				token.VAR, token.CONST, token.TYPE, token.LBRACE:
+				return "stmt"
+			case token.IMPORT, token.PACKAGE:
+				return ""
+			}
+		case n == 1 && first == token.FUNC:
+			if tok == token.IDENT {
+				return "func"
+			}
+			return ""
+		}
+
+		switch tok {
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			depth--
+		}
+		if depth > 0 {
+			prev = tok
+			continue
+		}
+		switch {
+		case tok == token.DEFINE, tok == token.ASSIGN, tok == token.INC, tok == token.DEC,
+			tok >= token.ADD_ASSIGN && tok <= token.AND_NOT_ASSIGN:
+			return "stmt"
+		case tok == token.ARROW && n > 0: // ch <- v
+			return "stmt"
+		case tok == token.COLON && n == 1 && prev == token.IDENT: // a label
+			return "stmt"
+		case prev == token.SEMICOLON: // the statements
+			return "stmt"
+		}
+		prev = tok
+	}
+	if n == 0 || first == token.FUNC {
+		return ""
+	}
+	return "expr"
+}
+
 func (*Session) parseTokens(in string) error {
 	var sc scanner.Scanner
 	fset := token.NewFileSet()
@@ -331,6 +774,64 @@ func (*Session) parseTokens(in string) error {
 	return nil
 }
 
+// inputContinues reports whether the input continues to the next line: the
+// brackets are not closed, a raw string or a comment is not terminated, or
+// the last token is an operator or a keyword followed by more (e.g. + or
+// else).
+func inputContinues(in string) bool {
+	if openDelimiters(in) != "" {
+		return true
+	}
+
+	last, unterminated := scanLast(in)
+	if unterminated {
+		return true
+	}
+
+	switch last {
+	case token.RPAREN, token.RBRACK, token.RBRACE, token.INC, token.DEC, token.SEMICOLON,
+		token.RETURN, token.BREAK, token.CONTINUE, token.FALLTHROUGH:
+		return false
+	}
+	return last.IsOperator() || last.IsKeyword()
+}
+
+// scanLast returns the last token of the input, ignoring the semicolons
+// inserted at the newlines, and reports whether a raw string or a comment
+// is not terminated.
+func scanLast(in string) (last token.Token, unterminated bool) {
+	var sc scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(in))
+	sc.Init(file, []byte(in), func(_ token.Position, msg string) {
+		switch msg {
+		case "raw string literal not terminated", "comment not terminated":
+			unterminated = true
+		}
+	}, 0)
+	last = token.ILLEGAL
+	for {
+		_, tok, lit := sc.Scan()
+		if tok == token.EOF {
+			return last, unterminated
+		}
+		// the semicolon inserted at the newline
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		last = tok
+	}
+}
+
+// syntaxError returns the error of parsing the input, without the position
+// in the source wrapping the input.
+func syntaxError(err error) error {
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		return errors.New(list[0].Msg)
+	}
+	return err
+}
+
 func (s *Session) appendStatements(stmts ...ast.Stmt) {
 	s.mainBody.List = append(s.mainBody.List, stmts...)
 }
@@ -383,17 +884,84 @@ func (s *Session) reset() error {
 	s.file = file
 	s.mainBody = s.mainFunc().Body
 
-	return nil
+	return s.resetDeclFiles()
+}
+
+// Result of an evaluation by Session.Eval.
+type Result struct {
+	// Output is what was written to stdout.
+	Output string
+	// Error is what was written to stderr, e.g. compile errors and panics.
+	Error string
+	// Source is the session source after the evaluation.
+	Source string
+}
+
+// captureState holds the writers beneath the transcript, which keep the
+// outputs of the evaluation for the Result.
+type captureState struct {
+	stdout, stderr *captureWriter
+}
+
+// captureWriter writes to w, keeping what is written while buf is set.
+type captureWriter struct {
+	w   io.Writer
+	buf *strings.Builder
 }
 
-// Eval the input.
-func (s *Session) Eval(in string) error {
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if w.buf != nil {
+		w.buf.Write(p)
+	}
+	return w.w.Write(p)
+}
+
+// setOutput replaces the writers the outputs are written to, keeping the
+// outputs captured and recorded by :record.
+func (s *Session) setOutput(stdout, stderr io.Writer) {
+	s.capture.stdout.w, s.capture.stderr.w = stdout, stderr
+}
+
+// Eval evaluates the input, writing the outputs to the writers of the session
+// and returning them as a Result.
+func (s *Session) Eval(in string) (Result, error) {
+	var stdout, stderr strings.Builder
+	s.capture.stdout.buf, s.capture.stderr.buf = &stdout, &stderr
+	err := s.eval(in)
+	s.capture.stdout.buf, s.capture.stderr.buf = nil, nil
+
+	source, serr := s.userSource(false)
+	if err == nil {
+		err = serr
+	}
+
+	return Result{Output: stdout.String(), Error: stderr.String(), Source: source}, err
+}
+
+// eval evaluates the input.
+func (s *Session) eval(in string) (err error) {
 	debugf("eval >>> %q", in)
 
+	n, start, orig := s.inputNumber+1, time.Now(), in
+	defer func() {
+		if err != ErrContinue {
+			s.inputNumber = n
+			s.recordHistory(orig, start, err)
+			s.recordResult(start, err)
+		}
+	}()
+
 	s.clearQuickFix()
 	s.storeCode()
+	if !isCommand(in, "quit") {
+		s.unsaved = true
+	}
 
 	if strings.HasPrefix(strings.TrimSpace(in), ":") {
+		if commandContinues(in) {
+			return ErrContinue
+		}
+		s.recordInput(in, "command")
 		err := s.invokeCommand(in)
 		if err != nil && err != ErrQuit {
 			fmt.Fprintf(s.stderr, "%s\n", err)
@@ -401,35 +969,56 @@ func (s *Session) Eval(in string) error {
 		return err
 	}
 
-	if _, err := s.evalExpr(in); err != nil {
-		debugf("expr :: err = %s", err)
-
-		err := s.evalStmt(in)
-		if err != nil {
-			debugf("stmt :: err = %s", err)
-
-			err := s.evalFunc(in)
-			if err != nil {
-				debugf("func :: err = %s", err)
-
-				if err := s.parseTokens(in); err != nil {
-					fmt.Fprintf(s.stderr, "%s\n", err)
-					return err
-				}
+	in = expandInputNumber(in, n)
+	in, silent := cutSilentSemicolon(in)
+	kind, err := s.evalInput(in)
+	if err != nil {
+		debugf("%s :: err = %s", kind, err)
 
-				return ErrContinue
-			}
+		if perr := s.parseTokens(in); perr != nil {
+			err = perr
+		} else if inputContinues(in) {
+			return ErrContinue
 		}
+		err = syntaxError(err)
+		s.recordInput(in, "invalid")
+		fmt.Fprintf(s.stderr, "%s\n", err)
+		return err
 	}
 
+	s.recordInput(in, kind)
+	s.addUsedResults()
+
+	var before string
+	if s.transcript != nil && s.transcript.jsonl {
+		before, _ = s.source(false)
+	}
 	if s.autoImport {
 		if err := s.fixImports(); err != nil {
 			debugf("fixImports :: err = %s", err)
 		}
 	}
 	s.doQuickFix()
+	if before != "" {
+		after, _ := s.source(false)
+		s.recordQuickFixes(before, after)
+	}
+	var result *result
+	if kind == "expr" && silent {
+		s.silenceLastStmt()
+		// the variables may be unused without printing
+		s.doQuickFix()
+	} else if kind == "expr" {
+		result = s.bindResult()
+	}
 
-	err := s.Run()
+	err = s.Run()
+	if exit := s.exit.last; exit != nil {
+		return s.handleExit(exit)
+	}
+	if err == nil && result != nil {
+		s.commitResult(result)
+	}
 	if err != nil {
 		if _, ok := err.(*exec.ExitError); ok {
 			debugf("got exit error, popping out last input")
@@ -442,16 +1031,104 @@ func (s *Session) Eval(in string) error {
 	return err
 }
 
+// handleExit reports the exit by os.Exit, and drops the input unless
+// configured to keep it. The input is kept if the exit is caused by
+// a statement before it, not to blame the input. The input exiting with
+// code 0 succeeds.
+func (s *Session) handleExit(exit *exitInfo) error {
+	before := exit.stmt > 0 && exit.stmt <= len(s.last.stmts)
+	if exit.code == 0 && !before {
+		return nil
+	}
+	if exit.stmt > 0 {
+		fmt.Fprintf(s.stderr, s.tr("program exited with code %d at statement #%d")+"\n", exit.code, exit.stmt)
+	} else {
+		fmt.Fprintf(s.stderr, s.tr("program exited with code %d")+"\n", exit.code)
+	}
+	if before {
+		fmt.Fprintf(s.stderr, s.tr("use :drop %d to drop the statement")+"\n", exit.stmt)
+	} else if !s.exit.keep {
+		debugf("exited by the input, popping out last input")
+		s.restoreCode()
+	}
+	return ErrCmdRun
+}
+
+// cutSilentSemicolon cuts the semicolon ending the expression input, which
+// is evaluated without printing the result (e.g. a large value), and reports
+// whether cut.
+func cutSilentSemicolon(in string) (string, bool) {
+	var sc scanner.Scanner
+	fset := token.NewFileSet()
+	sc.Init(fset.AddFile("", -1, len(in)), []byte(in), nil, 0)
+	offset := -1
+	for {
+		pos, tok, lit := sc.Scan()
+		if tok == token.EOF {
+			break
+		}
+		offset = -1
+		// the semicolons inserted at the newlines are not typed
+		if tok == token.SEMICOLON && lit == ";" {
+			offset = fset.Position(pos).Offset
+		}
+	}
+	if offset < 0 {
+		return in, false
+	}
+	if _, err := parser.ParseExpr(in[:offset]); err != nil {
+		return in, false
+	}
+	return in[:offset], true
+}
+
+// silenceLastStmt replaces the last statement printing the values with the
+// statements evaluating the impure ones without printing.
+func (s *Session) silenceLastStmt() {
+	i := len(s.mainBody.List) - 1
+	if i < 0 {
+		return
+	}
+	// a call of no value is not printed already
+	exprs := printedExprs(s.mainBody.List[i])
+	if exprs == nil {
+		return
+	}
+	s.mainBody.List = append(s.mainBody.List[:i:i], s.blankAssignStmts(exprs)...)
+}
+
+// expandInputNumber replaces the identifier __n in the input with the input
+// number, so that the value is kept while the program is run again and again.
+func expandInputNumber(in string, n int) string {
+	if !strings.Contains(in, inputNumberName) {
+		return in
+	}
+	var sc scanner.Scanner
+	fset := token.NewFileSet()
+	sc.Init(fset.AddFile("", -1, len(in)), []byte(in), nil, 0)
+	var sb strings.Builder
+	var last int
+	for {
+		pos, tok, lit := sc.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.IDENT && lit == inputNumberName {
+			offset := fset.Position(pos).Offset
+			sb.WriteString(in[last:offset])
+			sb.WriteString(strconv.Itoa(n))
+			last = offset + len(lit)
+		}
+	}
+	sb.WriteString(in[last:])
+	return sb.String()
+}
+
 func (s *Session) invokeCommand(in string) (err error) {
-	in = strings.TrimLeftFunc(in, func(c rune) bool {
-		return c == ':' || unicode.IsSpace(c)
-	})
-	tokens := strings.Fields(in)
-	if len(tokens) == 0 {
+	cmd, arg := splitCommand(in)
+	if cmd == "" {
 		return
 	}
-	cmd := tokens[0]
-	arg := strings.TrimSpace(strings.TrimPrefix(in, cmd))
 	for _, command := range commands {
 		if !command.name.matches(cmd) {
 			continue
@@ -468,34 +1145,119 @@ func (s *Session) invokeCommand(in string) (err error) {
 	return fmt.Errorf("command not found: %s", cmd)
 }
 
+// splitCommand splits the input into the command name and the argument.
+func splitCommand(in string) (cmd, arg string) {
+	in = strings.TrimLeftFunc(in, func(c rune) bool {
+		return c == ':' || unicode.IsSpace(c)
+	})
+	// the shell command line follows :! without a space (e.g. :!ls)
+	if strings.HasPrefix(in, "!") {
+		return "!", strings.TrimSpace(in[1:])
+	}
+	tokens := strings.Fields(in)
+	if len(tokens) == 0 {
+		return "", ""
+	}
+	cmd = tokens[0]
+	return cmd, strings.TrimSpace(strings.TrimPrefix(in, cmd))
+}
+
+// commandContinues reports whether the input invokes a command taking a
+// block whose braces are not closed yet, or a here document not closed yet,
+// to read the following lines.
+func commandContinues(in string) bool {
+	cmd, arg := splitCommand(in)
+	if heredocInput(in) {
+		_, closed := heredoc(arg)
+		return !closed
+	}
+	for _, command := range commands {
+		if command.name.matches(cmd) {
+			return command.block && braceDepth(arg) > 0
+		}
+	}
+	return false
+}
+
+// isCommand reports whether the input invokes the command of the name.
+func isCommand(in, name string) bool {
+	if !strings.HasPrefix(strings.TrimSpace(in), ":") {
+		return false
+	}
+	tokens := strings.Fields(strings.TrimLeftFunc(in, func(c rune) bool {
+		return c == ':' || unicode.IsSpace(c)
+	}))
+	if len(tokens) == 0 {
+		return false
+	}
+	for _, command := range commands {
+		if command.name.String() == name {
+			return command.name.matches(tokens[0])
+		}
+	}
+	return false
+}
+
 // storeCode stores current state of code so that it can be restored
 func (s *Session) storeCode() {
-	s.lastStmts = s.mainBody.List
-	if len(s.lastDecls) != len(s.file.Decls) {
-		s.lastDecls = make([]ast.Decl, len(s.file.Decls))
+	s.last.stmts = s.mainBody.List
+	if len(s.last.decls) != len(s.file.Decls) {
+		s.last.decls = make([]ast.Decl, len(s.file.Decls))
+	}
+	copy(s.last.decls, s.file.Decls)
+	s.last.results = append([]result(nil), s.results.pending...)
+	s.last.marks = make(map[string]int, len(s.marks))
+	for name, n := range s.marks {
+		s.last.marks[name] = n
 	}
-	copy(s.lastDecls, s.file.Decls)
+	s.last.fileDecls = s.storeDeclFiles()
+	s.last.undo = nil
 }
 
 // restoreCode restores the previous code
 func (s *Session) restoreCode() {
-	s.mainBody.List = s.lastStmts
+	for i := len(s.last.undo) - 1; i >= 0; i-- {
+		s.last.undo[i]()
+	}
+	s.last.undo = nil
+	s.mainBody.List = s.last.stmts
+	s.results.pending, s.marks = s.last.results, s.last.marks
+	s.restoreDeclFiles(s.last.fileDecls)
 	decls := make([]ast.Decl, 0, len(s.file.Decls))
 	for _, d := range s.file.Decls {
-		if d, ok := d.(*ast.FuncDecl); ok && d.Name.String() != "main" {
-			for _, ld := range s.lastDecls {
-				if ld, ok := ld.(*ast.FuncDecl); ok && ld.Name.String() == d.Name.String() {
+		if d, ok := d.(*ast.FuncDecl); ok && funcKey(d) != "main" {
+			for _, ld := range s.last.decls {
+				if ld, ok := ld.(*ast.FuncDecl); ok && funcKey(ld) == funcKey(d) && (!isInitFunc(d) || ld == d) {
 					decls = append(decls, ld)
 					break
 				}
 			}
 			continue
 		}
+		// the types declared by the input are dropped
+		if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.TYPE && !containsDecl(s.last.decls, d) {
+			continue
+		}
 		decls = append(decls, d)
 	}
+	// the types replaced by the input are restored
+	for _, ld := range s.last.decls {
+		if ld, ok := ld.(*ast.GenDecl); ok && ld.Tok == token.TYPE && !containsDecl(decls, ld) {
+			decls = append(decls, ld)
+		}
+	}
 	s.file.Decls = decls
 }
 
+func containsDecl(decls []ast.Decl, decl ast.Decl) bool {
+	for _, d := range decls {
+		if d == decl {
+			return true
+		}
+	}
+	return false
+}
+
 // includeFiles imports packages and funcsions from multiple golang source
 func (s *Session) includeFiles(files []string) {
 	for _, file := range files {
@@ -546,7 +1308,8 @@ func (s *Session) importFile(src []byte) error {
 		return err
 	}
 
-	f, err := parser.ParseFile(s.fset, tmp.Name(), src, parser.Mode(0))
+	// keep the comments for the build constraints
+	f, err := parser.ParseFile(s.fset, tmp.Name(), src, parser.ParseComments)
 	if err != nil {
 		return err
 	}
@@ -579,6 +1342,7 @@ func (s *Session) importFile(src []byte) error {
 	}
 
 	debugf("import file: %s", tmp.Name())
+	s.warnRerunDecls(f.Decls)
 	s.extraFilePaths = append(s.extraFilePaths, tmp.Name())
 	s.extraFiles = append(s.extraFiles, f)
 
@@ -591,6 +1355,7 @@ func (s *Session) fixImports() error {
 	for _, path := range s.requiredModules {
 		cmd := exec.Command("go", "get", "-d", path)
 		cmd.Dir = s.tempDir
+		cmd.Env = s.goEnviron()
 		if err := cmd.Run(); err != nil {
 			debugf("failed to go get -d %q: %s", path, err)
 		}
@@ -603,7 +1368,12 @@ func (s *Session) fixImports() error {
 		return err
 	}
 
-	formatted, err := imports.Process("", buf.Bytes(), nil)
+	src, err := s.chooseImports(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	formatted, err := imports.Process("", src, nil)
 	if err != nil {
 		return err
 	}
@@ -618,10 +1388,10 @@ func (s *Session) fixImports() error {
 }
 
 func (s *Session) includePackage(path string) error {
-	pkg, err := build.Import(path, ".", 0)
+	pkg, err := s.buildContext.Import(path, ".", 0)
 	if err != nil {
 		var err2 error
-		pkg, err2 = build.ImportDir(path, 0)
+		pkg, err2 = s.buildContext.ImportDir(path, 0)
 		if err2 != nil {
 			return err // return package path import error, not directory import error as build.Import can also import directories if "./foo" is specified
 		}
@@ -638,5 +1408,9 @@ func (s *Session) includePackage(path string) error {
 
 // Clear the temporary directory.
 func (s *Session) Clear() error {
+	s.jobs.killAll()
+	if s.transcript != nil {
+		s.stopRecording()
+	}
 	return os.RemoveAll(s.tempDir)
 }
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -80,7 +81,7 @@ func TestSessionEval_Gomod(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Equal(t, "10\n20\n10\n30\n", stdout.String())
@@ -108,7 +109,7 @@ func TestSessionEval_Gomod_AutoImport(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Equal(t, `10
@@ -146,7 +147,7 @@ func TestSessionEval_Gomod_DeepDir(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Equal(t, "10\n20\n10\n30\n", stdout.String())
@@ -166,7 +167,7 @@ func TestSessionEval_Gomod_Outside(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Equal(t, ``, stderr.String())
@@ -189,3 +190,79 @@ func TestSessionEval_Gomod_CompleteImport(t *testing.T) {
 	assert.Subset(t, cands, []string{"mod2/mod3", "mod2/mod4"})
 	assert.Equal(t, post, "")
 }
+
+func TestGoLangVersion(t *testing.T) {
+	testCases := []struct {
+		version, want string
+	}{
+		{"go1.21.3", "1.21"},
+		{"go1.22rc1", "1.22"},
+		{"go1.9", "1.9"},
+		{"devel go1.23-abcdef", ""},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, goLangVersion(tc.version), tc.version)
+	}
+	assert.True(t, langVersionLess("1.9", "1.21"))
+	assert.False(t, langVersionLess("1.21", "1.21"))
+	assert.False(t, langVersionLess("2.0", "1.21"))
+}
+
+func TestSessionEval_Generics(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`func Map[T, U any](xs []T, f func(T) U) []U { var r []U; for _, x := range xs { r = append(r, f(x)) }; return r }`,
+		`type List[T any] []T`,
+		`List[int]{1, 2}`,
+		`func (l List[T]) Len() int { return len(l) }`,
+		`Map(List[int]{1, 2}, func(x int) bool { return x > 1 })`,
+		`List[string]{"a"}.Len()`,
+	}
+	for _, code := range codes {
+		_, err = s.Eval(code)
+		require.NoError(t, err, code)
+	}
+	assert.Equal(t, "main.List[int]{1, 2}\n[]bool{false, true}\n1\n", stdout.String())
+	assert.Equal(t, "", stderr.String())
+
+	data, err := os.ReadFile(filepath.Join(s.tempDir, "go.mod"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "\ngo 1.")
+}
+
+func TestSession_checkWatch(t *testing.T) {
+	var stdout, stderr strings.Builder
+	gomodSetup(t)
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:i mod1 fmt`,
+		`fmt.Println(mod1.Value)`,
+		`:set watch on`,
+	}
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err, code)
+	}
+	s.checkWatch()
+
+	path := filepath.Join("..", "mod1", "mod1.go")
+	require.NoError(t, os.WriteFile(path, []byte(`package mod1
+
+const Value = 20
+`), 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	s.checkWatch()
+	s.checkWatch()
+
+	assert.Equal(t, "10\n3\n<nil>\n20\n", stdout.String())
+	assert.Equal(t, "changed: mod1, running again\n", stderr.String())
+}
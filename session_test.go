@@ -1,10 +1,14 @@
 package gore
 
 import (
+	"go/build"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -48,7 +52,7 @@ func TestSessionEval_import(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		err := s.Eval(code)
+		_, err := s.Eval(code)
 		require.NoError(t, err)
 	}
 
@@ -75,7 +79,7 @@ func TestSessionEval_QuickFix_evaluated_but_not_used(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		err := s.Eval(code)
+		_, err := s.Eval(code)
 		require.NoError(t, err)
 	}
 
@@ -90,6 +94,28 @@ func TestSessionEval_QuickFix_evaluated_but_not_used(t *testing.T) {
 	assert.Equal(t, "", stderr.String())
 }
 
+func TestSessionEval_QuickFix_unused_label(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`n := 0`,
+		`L: for i := 0; i < 3; i++ { n += i }`,
+		`func f() int { M: for { return 1 } }`,
+		`n + f()`,
+	}
+
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "0\n4\n", stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
 func TestSessionEval_QuickFix_used_as_value(t *testing.T) {
 	var stdout, stderr strings.Builder
 	s, err := NewSession(&stdout, &stderr)
@@ -103,7 +129,7 @@ func TestSessionEval_QuickFix_used_as_value(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		err := s.Eval(code)
+		_, err := s.Eval(code)
 		require.NoError(t, err)
 	}
 
@@ -129,7 +155,7 @@ func TestSessionEval_QuickFix_no_new_variables(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		err := s.Eval(code)
+		_, err := s.Eval(code)
 		require.NoError(t, err)
 	}
 
@@ -144,6 +170,108 @@ func TestSessionEval_QuickFix_no_new_variables(t *testing.T) {
 	assert.Equal(t, "", stderr.String())
 }
 
+func TestSessionEval_Redeclare(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`x := "hello"`,
+		`x := 5`,
+		`y := x + 1`,
+		`x := 6`,
+		`x := "again"`,
+		`x := undefined`,
+		`y`,
+		`var y = x + "!"`,
+		`y`,
+		`:print`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `"hello"
+5
+6
+6
+"again"
+6
+"again!"
+"again!"
+package main
+
+func main() {
+	x_1 := 5
+	y_1 := x_1 + 1
+	x_1 = 6
+	x := "again"
+	var y = x + "!"
+}
+
+`, strings.ReplaceAll(stdout.String(), "    ", "\t"))
+	assert.Equal(t, `x is declared again, the old one renamed to x_1
+undefined: undefined
+y is declared again, the old one renamed to y_1
+`, stderr.String())
+}
+
+func TestSessionEval_Redefine(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`func double(n int) int { return n * 2 }`,
+		`func double(n int) int { return n + n + 1 }`,
+		`double(2)`,
+		`type T struct{ A int }`,
+		`func (t T) Sum() int { return t.A }`,
+		`type T struct{ A, B int }`,
+		`func (t T) Sum() int { return t.A + t.B }`,
+		`T{1, 2}.Sum()`,
+		`type T struct{ C int }`,
+		`T{3, 4}`,
+		`type (U int; V int)`,
+		`type U string`,
+		`U("u")`,
+		`:print`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `5
+3
+main.T{A:3, B:4}
+"u"
+package main
+
+func double(n int) int { return n + n + 1 }
+
+func (t T) Sum() int { return t.A + t.B }
+
+type T struct{ A, B int }
+
+type (
+	V int
+)
+
+type U string
+
+func main() {
+	double(2)
+	T{1, 2}.Sum()
+}
+
+`, strings.ReplaceAll(stdout.String(), "    ", "\t"))
+	assert.Contains(t, stderr.String(), "t.A undefined (type T has no field or method A)")
+}
+
 func TestSessionEval_AutoImport(t *testing.T) {
 	var stdout, stderr strings.Builder
 	s, err := NewSession(&stdout, &stderr)
@@ -156,7 +284,7 @@ func TestSessionEval_AutoImport(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		err := s.Eval(code)
+		_, err := s.Eval(code)
 		require.NoError(t, err)
 	}
 
@@ -164,6 +292,40 @@ func TestSessionEval_AutoImport(t *testing.T) {
 	assert.Equal(t, "", stderr.String())
 }
 
+func TestSessionEval_AutoImport_Choose(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.autoImport = true
+
+	var prompts []string
+	s.imports.chooser = func(prompt string, options []string) int {
+		prompts = append(prompts, prompt)
+		for i, option := range options {
+			if option == "crypto/rand" {
+				return i
+			}
+		}
+		return -1
+	}
+
+	codes := []string{
+		`rand.Reader != nil`,
+		`len(template.HTMLEscapeString("<"))`,
+		`:clear`,
+		`rand.Reader != nil`,
+	}
+
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "true\n4\ntrue\n", stdout.String())
+	assert.Equal(t, []string{"choose the package of rand", "choose the package of template"}, prompts)
+}
+
 func TestSession_IncludePackage(t *testing.T) {
 	var stdout, stderr strings.Builder
 	s, err := NewSession(&stdout, &stderr)
@@ -173,8 +335,29 @@ func TestSession_IncludePackage(t *testing.T) {
 	err = s.includePackage("github.com/x-motemen/gore/gocode")
 	require.NoError(t, err)
 
-	err = s.Eval("Completer{}")
+	_, err = s.Eval("Completer{}")
+	require.NoError(t, err)
+}
+
+func TestSession_BuildContext(t *testing.T) {
+	gopath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(gopath, "src", "example.com", "greet"), 0o755))
+	goos := "windows"
+	if runtime.GOOS == goos {
+		goos = "linux"
+	}
+	ctxt := build.Default
+	ctxt.GOPATH, ctxt.GOOS = gopath, goos
+
+	var stdout, stderr strings.Builder
+	s, err := New(BuildContext(&ctxt)).newSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
 	require.NoError(t, err)
+
+	assert.Contains(t, completeImport(s, "example.com/gr"), "example.com/greet/")
+	assert.Contains(t, s.environ(), "GOPATH="+gopath)
+	assert.NotContains(t, s.environ(), "GOOS="+goos)
+	assert.Contains(t, s.loadEnviron(), "GOOS="+goos)
 }
 
 func TestSessionEval_Copy(t *testing.T) {
@@ -191,7 +374,7 @@ func TestSessionEval_Copy(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		err := s.Eval(code)
+		_, err := s.Eval(code)
 		require.NoError(t, err)
 	}
 
@@ -216,7 +399,7 @@ func TestSessionEval_Const(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		err := s.Eval(code)
+		_, err := s.Eval(code)
 		require.NoError(t, err)
 	}
 
@@ -236,7 +419,7 @@ func TestSessionEval_Declarations(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		err := s.Eval(code)
+		_, err := s.Eval(code)
 		require.NoError(t, err)
 	}
 
@@ -270,7 +453,7 @@ func TestSessionEval_NotUsed(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	r := regexp.MustCompile(`0x[0-9a-f]+`)
@@ -307,7 +490,7 @@ func TestSessionEval_MultipleValues(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Equal(t, `0
@@ -324,6 +507,62 @@ func TestSessionEval_MultipleValues(t *testing.T) {
 	assert.Equal(t, "", stderr.String())
 }
 
+func TestSessionEval_Silent(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import fmt strings`,
+		`x := 3`,
+		`x * 2;`,
+		`strings.Repeat("a", x);`,
+		`func() (int, int) { return x, 2 }();`,
+		`fmt.Print();`,
+		`"a;"`,
+		`x // ;`,
+	}
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, `3
+"a;"
+3
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestSessionEval_MultipleValueCall(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import strconv`,
+		`strconv.Atoi("42")`,
+		`strconv.ParseBool("maybe")`,
+		`func() (int, string, error) { return 1, "a", nil }()`,
+	}
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, `42
+<nil>
+false
+&strconv.NumError{Func:"ParseBool", Num:"maybe", Err:(*errors.errorString)(...)}
+1
+"a"
+<nil>
+`, regexp.MustCompile(`0x[0-9a-f]+`).ReplaceAllString(stdout.String(), "..."))
+	assert.Equal(t, "", stderr.String())
+}
+
 func TestSessionEval_Struct(t *testing.T) {
 	var stdout, stderr strings.Builder
 	s, err := NewSession(&stdout, &stderr)
@@ -347,7 +586,7 @@ func TestSessionEval_Struct(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Contains(t, stdout.String(), `main.X{v:0}
@@ -375,7 +614,7 @@ func TestSessionEval_Func(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Equal(t, "112\n2400\n204\n", stdout.String())
@@ -401,7 +640,7 @@ func TestSessionEval_TokenError(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Equal(t, "\"foo\\nbar\"\n", stdout.String())
@@ -411,6 +650,117 @@ invalid token: "$"
 `, stderr.String())
 }
 
+func TestSessionEval_InputNumber(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`__n`,
+		`x := __n * 10`,
+		"func f() int {\n\treturn __n\n}",
+		`:print`,
+		`"__n"`,
+		`x + f()`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Regexp(t, `^1
+20
+(?s:.*)return 3
+(?s:.*)"__n"
+23
+$`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+	assert.Equal(t, 6, s.inputNumber)
+}
+
+func TestSessionEval_Results(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`1 + 2`,
+		`res0 * 2`,
+		`_ + 1`,
+		`x := _ * 10`,
+		`for _, c := range "ab" { _ = c }`,
+		`func f() {}`,
+		`f()`,
+		`nil`,
+		`:type _`,
+		`:print`,
+		`res0 + res1 + res2 + x`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Regexp(t, `^3
+6
+7
+70
+<nil>
+int
+(?s:.*)res2 := res1 \+ 1
+(?s:.*)x := res2 \* 10
+(?s:.*)86
+`, stdout.String())
+	assert.NotContains(t, stdout.String(), "res3 :=")
+	assert.Equal(t, "", stderr.String())
+	assert.Equal(t, 4, s.results.next)
+	assert.Equal(t, "res3", s.results.last)
+}
+
+func TestSessionEval_Exit(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import os`,
+		`x := 1`,
+		`os.Exit(0)`,
+		`x`,
+		`:drop 2`,
+		`x`,
+		`func f(n int) { if n > 1 { os.Exit(3) } }`,
+		`x = 2`,
+		`f(x)`,
+		`:set onexit keep`,
+		`os.Exit(x)`,
+		`x`,
+		`:drop 3`,
+		`x`,
+		`println("gore: exit 5 1")`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, "1\n1\n2\n2\n", stdout.String())
+	assert.Equal(t, `program exited with code 0 at statement #2
+use :drop 2 to drop the statement
+program exited with code 3 at statement #3
+program exited with code 2 at statement #3
+program exited with code 2 at statement #3
+use :drop 3 to drop the statement
+gore: exit 5 1
+`, stderr.String())
+	source, err := s.source(false)
+	require.NoError(t, err)
+	assert.NotContains(t, source, exitFuncName)
+}
+
 func TestSessionEval_CompileError(t *testing.T) {
 	var stdout, stderr strings.Builder
 	s, err := NewSession(&stdout, &stderr)
@@ -428,7 +778,7 @@ func TestSessionEval_CompileError(t *testing.T) {
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Equal(t, "5\n105\n", stdout.String())
@@ -438,6 +788,29 @@ invalid operation: f\(\) \+ g\(\) \(mismatched types int and string\)
 `, stderr.String())
 }
 
+func TestSession_Lazy(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	assert.False(t, s.cache.checked)
+	modules := s.goModules()
+	printerPath := s.printerPath
+	require.NotEmpty(t, printerPath)
+
+	_, err = s.Eval(`:clear`)
+	require.NoError(t, err)
+	_, err = s.Eval(`1 + 2`)
+	require.NoError(t, err)
+
+	assert.True(t, s.cache.checked)
+	assert.Equal(t, printerPath, s.printerPath)
+	assert.Equal(t, modules, s.goModules())
+	assert.Equal(t, "3\n", stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
 func TestSession_ExtraFiles(t *testing.T) {
 	var stdout, stderr strings.Builder
 	_ = newTempDir(t)
@@ -458,7 +831,7 @@ var V = 42
 	}
 
 	for _, code := range codes {
-		_ = s.Eval(code)
+		_, _ = s.Eval(code)
 	}
 
 	assert.Contains(t, stdout.String(), `42
@@ -466,3 +839,205 @@ int
 package builtin`)
 	assert.Equal(t, ``, stderr.String())
 }
+
+func TestSession_ExtraFiles_rerunDecls(t *testing.T) {
+	var stdout, stderr strings.Builder
+	_ = newTempDir(t)
+	require.NoError(t, os.WriteFile("test.go", []byte(`package test
+
+import "fmt"
+
+var N = next()
+
+var F = newFunc()
+
+func next() int {
+	fmt.Println("next")
+	return 42
+}
+
+func newFunc() func() int {
+	fmt.Println("newFunc")
+	return func() int { return 1 }
+}
+`), 0o644))
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	_, _ = s.Eval(`:set rerun-decls off`)
+	s.includeFiles([]string{"test.go"})
+	codes := []string{
+		`N`,
+		`N + F()`,
+		`:set rerun-decls on`,
+		`N * 2`,
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `next
+newFunc
+42
+newFunc
+43
+next
+newFunc
+84
+`, stdout.String())
+	assert.Equal(t, `warning: the value of F cannot be kept; its initializer runs on every evaluation
+`, stderr.String())
+}
+
+func TestSession_Eval(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	r, err := s.Eval(`x := "hello"`)
+	require.NoError(t, err)
+	assert.Equal(t, "\"hello\"\n", r.Output)
+	assert.Equal(t, "", r.Error)
+	assert.Contains(t, r.Source, `x := "hello"`)
+
+	r, err = s.Eval(`x + 1`)
+	require.Equal(t, ErrCmdRun, err)
+	assert.Equal(t, "", r.Output)
+	assert.Contains(t, r.Error, "mismatched types")
+	assert.NotContains(t, r.Source, `x + 1`)
+
+	// the outputs are also written to the writers of the session
+	assert.Equal(t, "\"hello\"\n", stdout.String())
+	assert.Contains(t, stderr.String(), "mismatched types")
+}
+
+func TestSession_interrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("os.Interrupt is not supported on Windows")
+	}
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	_, err = s.Eval(`:import time`)
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			s.running.mu.Lock()
+			running := s.running.process != nil
+			s.running.mu.Unlock()
+			if running {
+				assert.NoError(t, s.running.interrupt())
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	r, err := s.Eval(`time.Sleep(time.Hour)`)
+	assert.Equal(t, ErrCmdRun, err)
+	assert.Contains(t, r.Error, "signal: interrupt")
+}
+
+func TestInputKind(t *testing.T) {
+	testCases := []struct {
+		in, kind string
+	}{
+		{`1 + 2`, "expr"},
+		{`<-ch`, "expr"},
+		{`map[string]int{"a": 1}`, "expr"},
+		{`sort.Slice(a, func(i, j int) bool { x := a[i]; return x < a[j] })`, "expr"},
+		{`a[1:2]`, "expr"},
+		{`x := 1`, "stmt"},
+		{`x, y = y, x`, "stmt"},
+		{`n += 2`, "stmt"},
+		{`i++`, "stmt"},
+		{`ch <- 1`, "stmt"},
+		{`L: for {}`, "stmt"},
+		{`if x { y() }`, "stmt"},
+		{`var x = 1`, "stmt"},
+		{`type T struct{}`, "stmt"},
+		{"f()\ng()", "stmt"},
+		{`f(); g()`, "stmt"},
+		{`func f() {}`, "func"},
+		{`func (t T) M() {}`, ""},
+		{`func() {}()`, ""},
+		{`import "fmt"`, ""},
+		{``, ""},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.kind, inputKind(tc.in), tc.in)
+	}
+}
+
+func TestSessionEval_Receive(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`ch := make(chan int, 2)`,
+		`ch <- 1; ch <- 2`,
+		`<-ch`,
+		`<-ch`,
+	}
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "(chan int)(...)\n1\n2\n", regexp.MustCompile(`0x[0-9a-f]+`).ReplaceAllString(stdout.String(), "..."))
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestInputContinues(t *testing.T) {
+	testCases := []struct {
+		in        string
+		continues bool
+	}{
+		{`f(1,`, true},
+		{`if x {`, true},
+		{`a[`, true},
+		{`1 +`, true},
+		{`x :=`, true},
+		{`s.`, true},
+		{"x := `raw", true},
+		{`/* comment`, true},
+		{`} else`, true},
+		{`func`, true},
+		{`f(1)`, false},
+		{`x++`, false},
+		{`return`, false},
+		{`x := 1 2`, false},
+		{`1 +* 2`, false},
+		{`"not terminated`, false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.continues, inputContinues(tc.in), tc.in)
+	}
+}
+
+func TestSessionEval_SyntaxError(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	_, err = s.Eval(`f(1,`)
+	assert.Equal(t, ErrContinue, err)
+	_, err = s.Eval(`x := 1 2`)
+	assert.Error(t, err)
+	_, err = s.Eval(`import "fmt"`)
+	assert.Error(t, err)
+	_, err = s.Eval(`1 + 2`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "3\n", stdout.String())
+	assert.Equal(t, "expected ';', found 2\nexpected operand, found 'import'\n", stderr.String())
+}
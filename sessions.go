@@ -0,0 +1,195 @@
+package gore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/tabwriter"
+)
+
+// The sessions are saved in the store as the bundles (see :write --bundle),
+// to be restored by the names after restarting gore.
+
+// sessionStoreName returns the name in the store of the session saved by
+// the name.
+func sessionStoreName(name string) string {
+	return path.Join(storeSessions, name+bundleExt)
+}
+
+func (s *Session) checkSessionName(name string) error {
+	if name == "" {
+		return s.errorf("argument is required")
+	}
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return s.errorf("invalid session name: %q", name)
+	}
+	if s.store == nil {
+		return s.errorf("no store to save the sessions")
+	}
+	return nil
+}
+
+func actionSaveSession(s *Session, name string) error {
+	if err := s.checkSessionName(name); err != nil {
+		return err
+	}
+	data, err := s.bundle()
+	if err != nil {
+		return err
+	}
+	if err := s.store.Save(sessionStoreName(name), data); err != nil {
+		return err
+	}
+	s.unsaved, s.savedBy = false, ":save-session "+name
+	infof("Session saved as %s", name)
+	return nil
+}
+
+// actionRestoreSession clears the session and restores the one saved.
+func actionRestoreSession(s *Session, name string) error {
+	if err := s.checkSessionName(name); err != nil {
+		return err
+	}
+	data, err := s.store.Load(sessionStoreName(name))
+	if err != nil {
+		if isNotExist(err) {
+			return s.errorf("no saved session: %s", name)
+		}
+		return err
+	}
+	if err := s.init(); err != nil {
+		return err
+	}
+	// the sessions saved in the store are of the user
+	return s.restoreBundle(data, false)
+}
+
+// The sessions of the REPL are kept by the names, to try several things side
+// by side by :session new and :session switch. Each session has the code,
+// the imports and the settings of its own, and the first one is named
+// "default". The sessions are switched after the input, by the loop reading
+// the inputs.
+const defaultSessionName = "default"
+
+// sessionGroup is the sessions of the REPL.
+type sessionGroup struct {
+	names    []string
+	sessions map[string]*Session
+	current  *Session
+	create   func() (*Session, error)
+}
+
+// newSessionGroup returns the sessions with the first session, in which
+// :session new creates the sessions by create.
+func newSessionGroup(s *Session, create func() (*Session, error)) *sessionGroup {
+	g := &sessionGroup{sessions: map[string]*Session{}, create: create}
+	g.add(defaultSessionName, s)
+	g.current = s
+	return g
+}
+
+func (g *sessionGroup) add(name string, s *Session) {
+	g.names = append(g.names, name)
+	g.sessions[name] = s
+	s.group = g
+}
+
+// name returns the name of the session.
+func (g *sessionGroup) name(s *Session) string {
+	for name, t := range g.sessions {
+		if t == s {
+			return name
+		}
+	}
+	return ""
+}
+
+// promptName returns the name of the current session to show in the prompt,
+// or "" while there is only the first one.
+func (g *sessionGroup) promptName() string {
+	if len(g.names) < 2 {
+		return ""
+	}
+	return g.name(g.current)
+}
+
+// clear clears all the sessions.
+func (g *sessionGroup) clear() {
+	for _, s := range g.sessions {
+		s.Clear()
+	}
+}
+
+func actionSession(s *Session, arg string) error {
+	if s.group == nil {
+		return s.errorf("sessions are not supported in this mode")
+	}
+	args, err := splitArgs(arg)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] == "list" && len(args) == 1 {
+		w := tabwriter.NewWriter(s.stdout, 0, 8, 4, ' ', 0)
+		for _, name := range s.group.names {
+			t, mark := s.group.sessions[name], " "
+			if t == s {
+				mark = "*"
+			}
+			fmt.Fprintf(w, "  %s %s\t%d statements\n", mark, name, len(t.mainBody.List))
+		}
+		return w.Flush()
+	}
+	if len(args) != 2 {
+		return s.errorf("invalid argument: %s", arg)
+	}
+
+	name := args[1]
+	switch args[0] {
+	case "new":
+		if strings.ContainsAny(name, " \t") {
+			return s.errorf("invalid session name: %q", name)
+		}
+		if _, ok := s.group.sessions[name]; ok {
+			return s.errorf("session already exists: %s", name)
+		}
+		t, err := s.group.create()
+		if err != nil {
+			return err
+		}
+		s.group.add(name, t)
+	case "switch":
+		t, ok := s.group.sessions[name]
+		if !ok {
+			return s.errorf("no such session: %s", name)
+		}
+		s.group.current = t
+	default:
+		return s.errorf("unknown subcommand: %s", args[0])
+	}
+	return nil
+}
+
+func completeSession(s *Session, prefix string) []string {
+	var result []string
+	if s.group == nil {
+		return result
+	}
+	sub, name, ok := strings.Cut(prefix, " ")
+	if !ok {
+		for _, sub := range []string{"list", "new", "switch"} {
+			if strings.HasPrefix(sub, prefix) {
+				result = append(result, sub)
+			}
+		}
+		return result
+	}
+	if sub != "switch" {
+		return result
+	}
+	for _, n := range s.group.names {
+		if strings.HasPrefix(n, name) {
+			result = append(result, sub+" "+n)
+		}
+	}
+	return result
+}
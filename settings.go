@@ -0,0 +1,367 @@
+package gore
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+type setting struct {
+	name     string
+	set      func(*Session, string) error
+	get      func(*Session) string
+	document string
+}
+
+var settings []setting
+
+func init() {
+	settings = []setting{
+		{
+			name:     "floatfmt",
+			set:      setFloatFormat,
+			get:      func(s *Session) string { return s.format.float },
+			document: `format of float results (e.g. %.4g), "" to reset`,
+		},
+		{
+			name:     "printverb",
+			set:      setPrintVerb,
+			get:      func(s *Session) string { return s.format.verb },
+			document: `fmt verb of results (%v, %+v or %#v), "" for the default printer`,
+		},
+		{
+			name:     "stringer",
+			set:      setStringer,
+			get:      func(s *Session) string { return formatBool(s.format.stringer) },
+			document: "print the errors and fmt.Stringers by Error and String, unless printverb is set (on/off)",
+		},
+		{
+			name:     "grouping",
+			set:      setGrouping,
+			get:      func(s *Session) string { return formatBool(s.format.groupSeparator != "") },
+			document: "group digits of integer results by the locale separator (on/off)",
+		},
+		{
+			name: "onexit",
+			set:  setOnExit,
+			get: func(s *Session) string {
+				if s.exit.keep {
+					return "keep"
+				}
+				return "drop"
+			},
+			document: "whether to keep or drop the input calling os.Exit (drop/keep)",
+		},
+		{
+			name:     "maxoutput",
+			set:      setMaxOutput,
+			get:      func(s *Session) string { return formatSize(s.run.maxOutput) },
+			document: `limit of the outputs of a run (e.g. 64KB), "" for no limit`,
+		},
+		{
+			name:     "outputfile",
+			set:      setOutputFile,
+			get:      func(s *Session) string { return formatBool(s.run.spillOutput) },
+			document: "write the outputs over maxoutput to a temporary file (on/off)",
+		},
+		{
+			name:     "pager",
+			set:      setPager,
+			get:      func(s *Session) string { return formatBool(s.run.pager) },
+			document: "page the outputs longer than the terminal by $PAGER (on/off)",
+		},
+		{
+			name:     "race",
+			set:      setRace,
+			get:      func(s *Session) string { return formatBool(s.run.race) },
+			document: "run the code with the race detector (on/off)",
+		},
+		{
+			name:     "deterministic",
+			set:      setDeterministic,
+			get:      func(s *Session) string { return formatBool(s.run.deterministic) },
+			document: "run reproducibly, with a fixed now(), seeded rng and math/rand, one processor (on/off)",
+		},
+		{
+			name:     "timeout",
+			set:      setTimeout,
+			get:      func(s *Session) string { return formatTimeout(s.run.timeout) },
+			document: `stop the program running over the duration (e.g. 10s), "" for no timeout`,
+		},
+		{
+			name:     "memlimit",
+			set:      setMemLimit,
+			get:      func(s *Session) string { return formatSize(s.run.memLimit) },
+			document: `limit of the memory allocated by the program (e.g. 1GB), "" for no limit`,
+		},
+		{
+			name:     "cpulimit",
+			set:      setCPULimit,
+			get:      func(s *Session) string { return formatTimeout(s.run.cpuLimit) },
+			document: `limit of the CPU time of the program (e.g. 30s), "" for no limit`,
+		},
+		{
+			name:     "wait",
+			set:      setWait,
+			get:      func(s *Session) string { return formatTimeout(s.run.wait) },
+			document: "wait for the goroutines to finish at the end, up to the duration (e.g. 1s)",
+		},
+		{
+			name:     "watch",
+			set:      setWatch,
+			get:      func(s *Session) string { return formatBool(s.watch.enabled) },
+			document: "run again when the local packages imported change, checked before the prompt (on/off)",
+		},
+		{
+			name:     "gocache",
+			set:      setGoCache,
+			get:      func(s *Session) string { return s.cache.dir },
+			document: `build cache directory for the session, "" to use GOCACHE`,
+		},
+		goEnvSetting("GOPROXY", `GOPROXY of the go command, not of the program (e.g. off), "" to inherit`),
+		goEnvSetting("GOSUMDB", `GOSUMDB of the go command, not of the program (e.g. off), "" to inherit`),
+		goEnvSetting("GOPRIVATE", `GOPRIVATE of the go command, not of the program (e.g. *.corp.example.com), "" to inherit`),
+		goEnvSetting("GOFLAGS", `GOFLAGS of the go command, not of the program (e.g. -modcacherw), "" to inherit`),
+		{
+			name:     "goos",
+			set:      setGoos,
+			get:      func(s *Session) string { return s.buildContext.GOOS },
+			document: `GOOS to type check and complete the code for, "" for this machine`,
+		},
+		{
+			name:     "goarch",
+			set:      setGoarch,
+			get:      func(s *Session) string { return s.buildContext.GOARCH },
+			document: `GOARCH to type check and complete the code for, "" for this machine`,
+		},
+		{
+			name:     "numbered",
+			set:      setNumbered,
+			get:      func(s *Session) string { return formatBool(s.numberedPrompt) },
+			document: "show the input number (__n) in the prompt (on/off)",
+		},
+		{
+			name:     "rerun-decls",
+			set:      setRerunDecls,
+			get:      func(s *Session) string { return formatBool(!s.once.enabled) },
+			document: "rerun the init functions and variable initializers on every evaluation (on/off)",
+		},
+		{
+			name:     "rerun-defers",
+			set:      setRerunDefers,
+			get:      func(s *Session) string { return formatBool(!s.defers.once) },
+			document: "rerun the defer statements of the earlier inputs on every evaluation (on/off)",
+		},
+		{
+			name:     "autosave",
+			set:      setAutosave,
+			get:      func(s *Session) string { return formatBool(s.autosaveEnabled) },
+			document: "save the session on quitting, to be restored by :restore-session autosave (on/off)",
+		},
+		{
+			name:     "summary",
+			set:      setSummary,
+			get:      func(s *Session) string { return formatBool(s.summaryEnabled) },
+			document: "print the summary of the session on quitting (on/off)",
+		},
+		{
+			name:     "histsize",
+			set:      setHistSize,
+			get:      func(s *Session) string { return strconv.Itoa(s.histControl.size) },
+			document: "maximum number of the entries of the history recalled and saved",
+		},
+		{
+			name:     "histdedupe",
+			set:      setHistDedupe,
+			get:      func(s *Session) string { return formatBool(s.histControl.dedupe) },
+			document: "skip the input same as the previous entry of the history (on/off)",
+		},
+		{
+			name: "histignore",
+			set:  setHistIgnore,
+			get: func(s *Session) string {
+				if s.histControl.ignore == nil {
+					return ""
+				}
+				return s.histControl.ignore.String()
+			},
+			document: `pattern of the inputs not kept in the history (e.g. "^ "), "" to keep all`,
+		},
+		{
+			name:     "share",
+			set:      setShare,
+			get:      func(s *Session) string { return s.gistURL },
+			document: `gist endpoint to :share to (e.g. https://api.github.com/gists), "" for the Go Playground`,
+		},
+		{
+			name:     "lang",
+			set:      setLang,
+			get:      func(s *Session) string { return s.lang },
+			document: `language of the messages (en, ja or pt), "" to follow the environment`,
+		},
+	}
+}
+
+func (s *Session) lookupSetting(name string) (*setting, error) {
+	for i := range settings {
+		if settings[i].name == name {
+			return &settings[i], nil
+		}
+	}
+	return nil, s.errorf("unknown setting: %s", name)
+}
+
+func actionSet(s *Session, arg string) error {
+	if arg == "" {
+		w := tabwriter.NewWriter(s.stdout, 0, 8, 4, ' ', 0)
+		for _, st := range settings {
+			fmt.Fprintf(w, "    %s\t%q\t%s\n", st.name, st.get(s), s.tr(st.document))
+		}
+		return w.Flush()
+	}
+
+	name, value, _ := strings.Cut(arg, " ")
+	st, err := s.lookupSetting(name)
+	if err != nil {
+		return err
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		fmt.Fprintf(s.stdout, "%s = %q\n", st.name, st.get(s))
+		return nil
+	}
+
+	if v, err := strconv.Unquote(value); err == nil {
+		value = v
+	}
+
+	return st.set(s, value)
+}
+
+func completeSet(_ *Session, prefix string) []string {
+	var result []string
+	for _, st := range settings {
+		if strings.HasPrefix(st.name, prefix) {
+			result = append(result, st.name+" ")
+		}
+	}
+	return result
+}
+
+func (s *Session) parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "true", "yes", "1":
+		return true, nil
+	case "off", "false", "no", "0":
+		return false, nil
+	}
+	return false, s.errorf("invalid boolean: %q", value)
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func setFloatFormat(s *Session, value string) error {
+	if value != "" && !strings.Contains(value, "%") {
+		return s.errorf("invalid format: %q", value)
+	}
+	s.format.float = value
+	return s.updatePrinter()
+}
+
+func setPrintVerb(s *Session, value string) error {
+	switch value {
+	case "", "%v", "%+v", "%#v":
+	default:
+		return s.errorf("invalid verb: %q (%%v, %%+v or %%#v)", value)
+	}
+	s.format.verb = value
+	return s.updatePrinter()
+}
+
+func setStringer(s *Session, value string) error {
+	on, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.format.stringer = on
+	return s.updatePrinter()
+}
+
+func setGrouping(s *Session, value string) error {
+	on, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.format.groupSeparator = ""
+	if on {
+		s.format.groupSeparator = localeGroupSeparator()
+	}
+	return s.updatePrinter()
+}
+
+// localeGroupSeparator returns the digit grouping separator of the locale
+// specified by the environment variables, defaulting to ",".
+func localeGroupSeparator() string {
+	tag := language.AmericanEnglish
+	for _, key := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if v, _, _ := strings.Cut(os.Getenv(key), "."); v != "" {
+			if t, err := language.Parse(v); err == nil {
+				tag = t
+				break
+			}
+		}
+	}
+	if sep := strings.Trim(message.NewPrinter(tag).Sprintf("%d", 1000), "0123456789"); sep != "" {
+		return sep
+	}
+	return ","
+}
+
+func setNumbered(s *Session, value string) error {
+	on, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.numberedPrompt = on
+	return nil
+}
+
+func setRace(s *Session, value string) error {
+	on, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	if on {
+		cmd := exec.Command("go", "env", "CGO_ENABLED")
+		cmd.Env = s.goEnviron()
+		if out, err := cmd.Output(); err == nil && strings.TrimSpace(string(out)) != "1" {
+			return s.errorf("the race detector requires cgo (CGO_ENABLED=1)")
+		}
+	}
+	s.run.race = on
+	return nil
+}
+
+func setOnExit(s *Session, value string) error {
+	switch value {
+	case "drop":
+		s.exit.keep = false
+	case "keep":
+		s.exit.keep = true
+	default:
+		return s.errorf("invalid value: %q (drop or keep)", value)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocaleGroupSeparator(t *testing.T) {
+	testCases := []struct {
+		lcAll, lang string
+		want        string
+	}{
+		{"", "", ","},
+		{"", "C", ","},
+		{"", "en_US.UTF-8", ","},
+		{"", "de_DE.UTF-8", "."},
+		{"de_DE", "en_US.UTF-8", "."},
+		{"", "fr_FR.UTF-8", "\u00a0"},
+	}
+	for _, tc := range testCases {
+		t.Setenv("LC_ALL", tc.lcAll)
+		t.Setenv("LC_NUMERIC", "")
+		t.Setenv("LANG", tc.lang)
+		assert.Equal(t, tc.want, localeGroupSeparator(), "LC_ALL=%q LANG=%q", tc.lcAll, tc.lang)
+	}
+}
+
+func TestSession_goEnviron(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:set goproxy https://proxy.example.com`,
+		`:set goflags "-modcacherw"`,
+		`:set goproxy`,
+		`:env GOSUMDB=off`,
+		`1 + 2`,
+	}
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err, code)
+	}
+	assert.Equal(t, "goproxy = \"https://proxy.example.com\"\n3\n", stdout.String())
+	assert.Equal(t, "", stderr.String())
+
+	assert.Subset(t, s.goEnviron(), []string{"GOPROXY=https://proxy.example.com", "GOFLAGS=-modcacherw", "GOSUMDB=off"})
+	assert.Subset(t, s.loadEnviron(), []string{"GOPROXY=https://proxy.example.com", "GOFLAGS=-modcacherw"})
+	assert.NotContains(t, s.environ(), "GOPROXY=https://proxy.example.com")
+	assert.Contains(t, s.environ(), "GOSUMDB=off")
+
+	require.NoError(t, setGoEnvSetting(s, "goproxy", ""))
+	assert.NotContains(t, s.goEnviron(), "GOPROXY=https://proxy.example.com")
+}
+
+func setGoEnvSetting(s *Session, name, value string) error {
+	st, err := s.lookupSetting(name)
+	if err != nil {
+		return err
+	}
+	return st.set(s, value)
+}
@@ -0,0 +1,148 @@
+package gore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// playgroundURL is the Go Playground to share the session to, unless the
+// gist endpoint is set by :set share.
+var playgroundURL = "https://play.golang.org"
+
+// playgroundShareURL is the URL of the snippet shared to the playground.
+var playgroundShareURL = "https://play.golang.org/p/"
+
+const (
+	shareTimeout   = 10 * time.Second
+	maxShareResult = 1 << 20
+	shareFileName  = "main.go"
+)
+
+// actionShare posts the source of the session, without the scaffolding,
+// to the Go Playground or to the gist endpoint, and prints the URL.
+func actionShare(s *Session, _ string) error {
+	src, err := s.formattedSource()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shareTimeout)
+	defer cancel()
+	var u string
+	if s.gistURL != "" {
+		u, err = shareGist(ctx, s.gistURL, src)
+	} else {
+		u, err = sharePlayground(ctx, src)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.stdout, u)
+	return nil
+}
+
+// formattedSource returns the gofmt-ed source of the session without the
+// scaffolding, to be shared or copied.
+func (s *Session) formattedSource() ([]byte, error) {
+	source, err := s.userSource(false)
+	if err != nil {
+		return nil, err
+	}
+	return format.Source([]byte(source))
+}
+
+// sharePlayground posts the source to the Go Playground.
+func sharePlayground(ctx context.Context, src []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, playgroundURL+"/share", bytes.NewReader(src))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	body, err := doShare(req)
+	if err != nil {
+		return "", err
+	}
+	id := strings.TrimSpace(string(body))
+	if id == "" || strings.ContainsAny(id, "/ \t\n") {
+		return "", fmt.Errorf("invalid response of %s: %q", req.URL, id)
+	}
+	return playgroundShareURL + id, nil
+}
+
+// shareGist posts the source to the gist endpoint as a secret gist, with the
+// token of GITHUB_TOKEN if set.
+func shareGist(ctx context.Context, endpoint string, src []byte) (string, error) {
+	type file struct {
+		Content string `json:"content"`
+	}
+	data, err := json.Marshal(struct {
+		Description string          `json:"description"`
+		Public      bool            `json:"public"`
+		Files       map[string]file `json:"files"`
+	}{
+		Description: "shared by gore",
+		Files:       map[string]file{shareFileName: {string(src)}},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	body, err := doShare(req)
+	if err != nil {
+		return "", err
+	}
+	var gist struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(body, &gist); err != nil || gist.HTMLURL == "" {
+		return "", fmt.Errorf("invalid response of %s: %q", req.URL, body)
+	}
+	return gist.HTMLURL, nil
+}
+
+func doShare(req *http.Request) ([]byte, error) {
+	req.Header.Set("User-Agent", "gore/"+Version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxShareResult))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("%s: %s", req.URL, resp.Status)
+	}
+	return body, nil
+}
+
+// setShare sets the gist endpoint to share the session to, or "" for the
+// Go Playground.
+func setShare(s *Session, value string) error {
+	if value != "" {
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return s.errorf("invalid URL: %q", value)
+		}
+	}
+	s.gistURL = value
+	return nil
+}
@@ -0,0 +1,79 @@
+package gore
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Share(t *testing.T) {
+	var shared []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/share":
+			shared = append(shared, string(body))
+			_, _ = io.WriteString(w, "abc123")
+		case "/gists":
+			var gist struct {
+				Files map[string]struct {
+					Content string `json:"content"`
+				} `json:"files"`
+			}
+			require.NoError(t, json.Unmarshal(body, &gist))
+			shared = append(shared, gist.Files["main.go"].Content)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"html_url": "https://gist.example.com/xyz"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	defer func(u string) { playgroundURL = u }(playgroundURL)
+	playgroundURL = ts.URL
+
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import strings`,
+		`x := strings.Repeat("a", 2)`,
+		`:share`,
+		`:set share ` + ts.URL + `/gists`,
+		`:share`,
+		`:set share ` + ts.URL + `/missing`,
+		`:share`,
+		`:set share foo`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, `"aa"
+https://play.golang.org/p/abc123
+https://gist.example.com/xyz
+`, stdout.String())
+	assert.Equal(t, `share: `+ts.URL+`/missing: 404 Not Found
+set: invalid URL: "foo"
+`, stderr.String())
+	require.Len(t, shared, 2)
+	assert.Equal(t, `package main
+
+import (
+	"strings"
+)
+
+func main() {
+	x := strings.Repeat("a", 2)
+}
+`, shared[0])
+	assert.Equal(t, shared[0], shared[1])
+}
@@ -0,0 +1,37 @@
+package gore
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// shellCommand returns the command to run the command line by the shell.
+func shellCommand(line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", line)
+	}
+	return exec.Command("sh", "-c", line)
+}
+
+// actionShell runs the command line by the shell in the working directory of
+// the program with the environment of the session, streaming the output.
+func actionShell(s *Session, arg string) error {
+	if arg == "" {
+		return s.errorf("argument is required")
+	}
+	cmd := shellCommand(arg)
+	cmd.Dir = s.currentWorkDir()
+	cmd.Env = s.environ()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = s.stdin, s.stdout, s.stderr
+	restore := func() {}
+	if s.stdin != nil && s.terminal != nil {
+		restore = s.terminal()
+	}
+	defer restore()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	s.running.set(cmd.Process)
+	defer s.running.set(nil)
+	return cmd.Wait()
+}
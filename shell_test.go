@@ -0,0 +1,40 @@
+package gore
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Shell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are of sh")
+	}
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0o644))
+
+	codes := []string{
+		`:cd ` + dir,
+		`:env GORE_SHELL_TEST=foo`,
+		`:! cat hello.txt`,
+		`:!echo $GORE_SHELL_TEST`,
+		`:! echo error >&2; exit 2`,
+		`:!`,
+	}
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	assert.Equal(t, dir+"\nhello\nfoo\n", stdout.String())
+	assert.Equal(t, "error\n!: exit status 2\n!: argument is required\n", stderr.String())
+}
@@ -0,0 +1,81 @@
+package gore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The program is run again on every input, so it reads the standard input
+// from the start on each run. The input given by :stdin <<EOF is fed to each
+// run, to read the same lines every time without typing them again. Without
+// it, the terminal is handed over to the program for the run.
+
+// actionStdin sets the input of the program by the here document
+// (:stdin <<EOF, the lines, and EOF), or shows it.
+func actionStdin(s *Session, arg string) error {
+	if arg == "" {
+		if s.stdinData != nil {
+			fmt.Fprint(s.stdout, string(s.stdinData))
+		}
+		return nil
+	}
+
+	if arg == "--" {
+		s.stdinData = nil
+		return nil
+	}
+
+	marker := heredocMarker(arg)
+	if marker == "" {
+		return s.errorf("invalid argument: %s (<<MARKER or --)", arg)
+	}
+	body, closed := heredoc(arg)
+	if !closed {
+		return s.errorf("here document not closed by %s", marker)
+	}
+	s.stdinData = []byte(body)
+	return nil
+}
+
+// heredocMarker returns the marker of the here document started by the
+// argument (EOF of <<EOF or <<'EOF'), or "" if not a here document.
+func heredocMarker(arg string) string {
+	line, _, _ := strings.Cut(arg, "\n")
+	if !strings.HasPrefix(line, "<<") {
+		return ""
+	}
+	marker := strings.TrimSpace(strings.TrimPrefix(line, "<<"))
+	if len(marker) >= 2 && (marker[0] == '\'' || marker[0] == '"') && marker[len(marker)-1] == marker[0] {
+		marker = marker[1 : len(marker)-1]
+	}
+	if strings.ContainsAny(marker, " \t'\"") {
+		return ""
+	}
+	return marker
+}
+
+// heredoc returns the lines of the here document before the marker, each
+// terminated by a newline, and reports whether the marker is read.
+func heredoc(arg string) (string, bool) {
+	marker := heredocMarker(arg)
+	lines := strings.Split(arg, "\n")[1:]
+	for i, line := range lines {
+		if strings.TrimSpace(line) == marker {
+			if i == 0 {
+				return "", true
+			}
+			return strings.Join(lines[:i], "\n") + "\n", true
+		}
+	}
+	return "", false
+}
+
+// heredocInput reports whether the input is :stdin with a here document,
+// whose lines are read until the marker.
+func heredocInput(in string) bool {
+	if !isCommand(in, "stdin") {
+		return false
+	}
+	_, arg := splitCommand(in)
+	return heredocMarker(arg) != ""
+}
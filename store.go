@@ -0,0 +1,136 @@
+package gore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Store persists the data of gore (e.g. the history) by names.
+// Load returns an error satisfying errors.Is(err, fs.ErrNotExist)
+// if nothing is saved by the name.
+type Store interface {
+	Load(name string) ([]byte, error)
+	Save(name string, data []byte) error
+}
+
+// Names of the data saved in the store.
+const (
+	storeHistory  = "history"
+	storeConfig   = "config"
+	storeSessions = "sessions" // the directory of the sessions saved by :save-session
+)
+
+// NewStore returns the store of the kind, which is one of
+//   - "home": files in $GORE_HOME or ~/.gore (the default)
+//   - "xdg": files in the XDG base directories
+//   - "memory": nothing is persisted, for ephemeral sessions
+//
+// The sessions saved by :save-session are in $XDG_CONFIG_HOME/gore/sessions
+// (e.g. ~/.config/gore/sessions) for the file stores.
+func NewStore(kind string) (Store, error) {
+	switch kind {
+	case "", "home":
+		dir, err := homeDir()
+		if err != nil {
+			return nil, err
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		configDir := xdgDir(home, "XDG_CONFIG_HOME", ".config")
+		return &fileStore{dir: func(name string) string {
+			if isSessionName(name) {
+				return configDir
+			}
+			return dir
+		}}, nil
+	case "xdg":
+		return newXDGStore()
+	case "memory":
+		return &memoryStore{data: map[string][]byte{}}, nil
+	}
+	return nil, fmt.Errorf("unknown store: %s", kind)
+}
+
+// fileStore saves the data to the files of the names in the directories.
+type fileStore struct {
+	dir func(name string) string
+}
+
+func (st *fileStore) Load(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(st.dir(name), name))
+}
+
+func (st *fileStore) Save(name string, data []byte) error {
+	path := filepath.Join(st.dir(name), name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// newXDGStore returns the store following the XDG Base Directory
+// Specification. The config and the sessions are saved in
+// $XDG_CONFIG_HOME/gore and the others are saved in $XDG_STATE_HOME/gore.
+func newXDGStore() (Store, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	configDir := xdgDir(home, "XDG_CONFIG_HOME", ".config")
+	stateDir := xdgDir(home, "XDG_STATE_HOME", filepath.Join(".local", "state"))
+	return &fileStore{dir: func(name string) string {
+		if name == storeConfig || isSessionName(name) {
+			return configDir
+		}
+		return stateDir
+	}}, nil
+}
+
+// xdgDir returns the directory of gore in the XDG base directory of the
+// environment variable, or of the default relative to home.
+func xdgDir(home, env, def string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "gore")
+	}
+	return filepath.Join(home, def, "gore")
+}
+
+// isSessionName reports whether the name is of a session saved.
+func isSessionName(name string) bool {
+	return strings.HasPrefix(name, storeSessions+"/")
+}
+
+// memoryStore keeps the data in memory.
+type memoryStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (st *memoryStore) Load(name string) ([]byte, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	data, ok := st.data[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "load", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (st *memoryStore) Save(name string, data []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.data[name] = append([]byte(nil), data...)
+	return nil
+}
+
+// isNotExist reports whether the error tells that nothing is saved.
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
@@ -0,0 +1,102 @@
+package gore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GORE_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_STATE_HOME", "")
+
+	for kind, files := range map[string][]string{
+		"home": {".gore/history", ".gore/config", "config/gore/sessions/a.gorebundle"},
+		"xdg":  {".local/state/gore/history", "config/gore/config", "config/gore/sessions/a.gorebundle"},
+	} {
+		st, err := NewStore(kind)
+		require.NoError(t, err)
+
+		_, err = st.Load(storeHistory)
+		assert.True(t, isNotExist(err), kind)
+
+		require.NoError(t, st.Save(storeHistory, []byte("1 + 2\n")))
+		require.NoError(t, st.Save(storeConfig, []byte("config\n")))
+		require.NoError(t, st.Save(sessionStoreName("a"), []byte("session")))
+		data, err := st.Load(storeHistory)
+		require.NoError(t, err)
+		assert.Equal(t, "1 + 2\n", string(data))
+
+		for _, file := range files {
+			assert.FileExists(t, filepath.Join(home, file), kind)
+		}
+	}
+
+	st, err := NewStore("memory")
+	require.NoError(t, err)
+	_, err = st.Load(storeHistory)
+	assert.True(t, isNotExist(err))
+	require.NoError(t, st.Save(storeHistory, []byte("x")))
+	data, err := st.Load(storeHistory)
+	require.NoError(t, err)
+	assert.Equal(t, "x", string(data))
+	entries, err := os.ReadDir(home)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3) // .gore, .local and config
+
+	_, err = NewStore("foo")
+	assert.EqualError(t, err, "unknown store: foo")
+}
+
+func TestSession_loadConfig(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.store, _ = NewStore("memory")
+
+	require.NoError(t, s.store.Save(storeConfig, []byte(`# the settings
+floatfmt "%.2f"
+
+autosave off
+foo bar
+timeout 1x
+`)))
+	s.loadConfig()
+	assert.Equal(t, "%.2f", s.format.float)
+	assert.False(t, s.autosaveEnabled)
+	assert.Equal(t, `config:5: unknown setting: foo
+config:6: invalid duration: "1x"
+`, stderr.String())
+}
+
+func TestSession_autosave(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.store, _ = NewStore("memory")
+
+	require.NoError(t, s.autosave())
+	_, err = s.store.Load(sessionStoreName(autosaveName))
+	assert.True(t, isNotExist(err))
+
+	for _, code := range []string{`x := 42`, `:clear`, `:restore-session autosave`} {
+		if code == `:clear` {
+			require.NoError(t, s.autosave())
+		}
+		_, err = s.Eval(code)
+		require.NoError(t, err)
+	}
+	_, err = s.Eval(`x`)
+	require.NoError(t, err)
+	assert.Equal(t, "42\n42\n", stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
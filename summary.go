@@ -0,0 +1,63 @@
+package gore
+
+import (
+	"fmt"
+	"time"
+)
+
+// summary returns the summary of the session printed on quitting, of the
+// inputs, the failed ones, and the time since the first input and spent on
+// evaluating.
+func (s *Session) summary(now time.Time) string {
+	if len(s.history) == 0 {
+		return ""
+	}
+	var failed int
+	var spent time.Duration
+	for _, e := range s.history {
+		if e.Failed {
+			failed++
+		}
+		spent += e.Duration
+	}
+	elapsed := now.Sub(s.history[0].Time).Round(time.Second)
+	return fmt.Sprintf(s.tr("inputs: %d, failed: %d, time: %s, running: %s"),
+		len(s.history), failed, elapsed, spent.Round(time.Millisecond))
+}
+
+// exitSummary prints the summary of the session and saves the session on
+// quitting. The session not saved since the last input is offered to be
+// saved, if the autosave is off.
+func (s *Session) exitSummary() error {
+	if s.summaryEnabled {
+		if summary := s.summary(time.Now()); summary != "" {
+			fmt.Fprintln(s.stderr, summary)
+		}
+		if s.savedBy != "" {
+			fmt.Fprintf(s.stderr, s.tr("last saved by %s")+"\n", s.savedBy)
+		}
+	}
+
+	if !s.autosaveEnabled && s.unsaved && s.store != nil && s.confirm != nil {
+		s.autosaveEnabled = s.confirm(s.tr("save the session not saved?"))
+	}
+	if !s.autosaveEnabled || s.store == nil || s.inputNumber == 0 {
+		return nil
+	}
+	if err := s.autosave(); err != nil {
+		return err
+	}
+	if s.summaryEnabled {
+		fmt.Fprintf(s.stderr, s.tr("saved, to be restored by :restore-session %s")+"\n", autosaveName)
+	}
+	return nil
+}
+
+func setSummary(s *Session, value string) error {
+	b, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.summaryEnabled = b
+	return nil
+}
@@ -0,0 +1,58 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_summary(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &Session{history: []historyEntry{
+		{Time: start, Duration: 1200 * time.Millisecond, Input: "x := 1"},
+		{Time: start.Add(time.Minute), Duration: 300 * time.Millisecond, Input: "y", Failed: true},
+		{Time: start.Add(2 * time.Minute), Duration: time.Millisecond, Input: ":q"},
+	}}
+	assert.Equal(t, "inputs: 3, failed: 1, time: 2m5s, running: 1.501s", s.summary(start.Add(125*time.Second)))
+	assert.Equal(t, "", (&Session{}).summary(start))
+}
+
+func TestSession_exitSummary(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.store, _ = NewStore("memory")
+	var prompts []string
+	s.confirm = func(prompt string) bool {
+		prompts = append(prompts, prompt)
+		return true
+	}
+
+	for _, code := range []string{`:set autosave off`, `x := 42`, `:save-session foo`} {
+		_, err = s.Eval(code)
+		require.NoError(t, err)
+	}
+	stderr.Reset()
+	require.NoError(t, s.exitSummary())
+	assert.Empty(t, prompts)
+	_, err = s.store.Load(sessionStoreName(autosaveName))
+	assert.True(t, isNotExist(err))
+	assert.Regexp(t, `^inputs: 3, failed: 0, time: \S+, running: \S+
+last saved by :save-session foo
+$`, stderr.String())
+
+	_, _ = s.Eval(`y`)
+	stderr.Reset()
+	require.NoError(t, s.exitSummary())
+	assert.Equal(t, []string{"save the session not saved?"}, prompts)
+	_, err = s.store.Load(sessionStoreName(autosaveName))
+	assert.NoError(t, err)
+	assert.Regexp(t, `^inputs: 4, failed: 1, time: \S+, running: \S+
+last saved by :save-session foo
+saved, to be restored by :restore-session autosave
+$`, stderr.String())
+}
@@ -0,0 +1,147 @@
+package gore
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// maxCandidateDocs is the maximum number of the candidates of the completion
+// shown with the summaries of the documents, not to fill the terminal.
+const maxCandidateDocs = 20
+
+// memberDocs returns the summaries of the documents of the candidates, if
+// they are the members of the package before the dot at keep (e.g.
+// strings.<TAB>), or nil if none is found.
+func (s *Session) memberDocs(in string, keep int, candidates []string) []string {
+	if keep == 0 || keep > len(in) || in[keep-1] != '.' {
+		return nil
+	}
+	expr, info, _, ok := s.typeOfExpr(in[selectorStart(in[:keep-1]) : keep-1])
+	if !ok {
+		return nil
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	pkgName, ok := info.Uses[ident].(*types.PkgName)
+	if !ok {
+		return nil
+	}
+
+	synopses := s.synopses(pkgName.Imported().Path())
+	docs := make([]string, len(candidates))
+	var found bool
+	for i, cand := range candidates {
+		docs[i] = synopses[strings.TrimSuffix(cand, "(")]
+		found = found || docs[i] != ""
+	}
+	if !found {
+		return nil
+	}
+	return docs
+}
+
+// formatMemberDocs formats the candidates with the summaries of the documents
+// to show as the hint (e.g. "Contains — reports whether substr is within s").
+func formatMemberDocs(candidates, docs []string) string {
+	var width int
+	for _, cand := range candidates {
+		if len(cand) > width {
+			width = len(cand)
+		}
+	}
+	var sb strings.Builder
+	for i, cand := range candidates {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(cand)
+		if docs[i] != "" {
+			sb.WriteString(strings.Repeat(" ", width-len(cand)) + " — " + docs[i])
+		}
+	}
+	return sb.String()
+}
+
+// synopses returns the summaries of the documents of the exported members of
+// the package by the names, loaded from the source files and cached.
+func (s *Session) synopses(path string) map[string]string {
+	if m, ok := s.synopsisCache[path]; ok {
+		return m
+	}
+	m := make(map[string]string)
+	if s.synopsisCache == nil {
+		s.synopsisCache = make(map[string]map[string]string)
+	}
+	s.synopsisCache[path] = m
+
+	pkgs, err := packages.Load(
+		&packages.Config{
+			Mode:       packages.NeedName | packages.NeedFiles,
+			Dir:        s.tempDir,
+			Env:        s.loadEnviron(),
+			BuildFlags: s.buildFlags(),
+		},
+		path,
+	)
+	if err != nil || len(pkgs) != 1 {
+		debugf("synopses :: %s: %v", path, err)
+		return m
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, file := range pkgs[0].GoFiles {
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			debugf("synopses :: %s", err)
+			continue
+		}
+		files = append(files, f)
+	}
+	p, err := doc.NewFromFiles(fset, files, path)
+	if err != nil {
+		debugf("synopses :: %s", err)
+		return m
+	}
+
+	add := func(name, text string) {
+		if _, ok := m[name]; !ok && text != "" {
+			m[name] = synopsis(p, name, text)
+		}
+	}
+	addValues := func(values []*doc.Value) {
+		for _, v := range values {
+			for _, name := range v.Names {
+				add(name, v.Doc)
+			}
+		}
+	}
+	for _, f := range p.Funcs {
+		add(f.Name, f.Doc)
+	}
+	addValues(p.Consts)
+	addValues(p.Vars)
+	for _, t := range p.Types {
+		add(t.Name, t.Doc)
+		for _, f := range t.Funcs {
+			add(f.Name, f.Doc)
+		}
+		addValues(t.Consts)
+		addValues(t.Vars)
+	}
+	return m
+}
+
+// synopsis returns the first sentence of the document of the name, without
+// the name at the beginning and the period at the end.
+func synopsis(p *doc.Package, name, text string) string {
+	text = strings.TrimSuffix(p.Synopsis(text), ".")
+	return strings.TrimPrefix(text, name+" ")
+}
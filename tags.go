@@ -0,0 +1,62 @@
+package gore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The build tags are kept in the build context, and passed to the go
+// command by -tags for running the code and loading the packages.
+func actionTags(s *Session, arg string) error {
+	if arg == "" {
+		fmt.Fprintln(s.stdout, strings.Join(s.buildContext.BuildTags, " "))
+		return nil
+	}
+
+	// the tags are separated by spaces or commas as in go build -tags,
+	// and :tags -- clears them
+	tags := strings.FieldsFunc(arg, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(tags) == 1 && tags[0] == "--" {
+		tags = nil
+	}
+	for _, tag := range tags {
+		if !isBuildTag(tag) {
+			return s.errorf("invalid build tag: %q", tag)
+		}
+	}
+	s.buildContext.BuildTags = tags
+	return nil
+}
+
+func isBuildTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, c := range tag {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// buildFlags returns the flags of the go command to build the code.
+func (s *Session) buildFlags() []string {
+	flags := []string{"-mod=mod"}
+	if len(s.buildContext.BuildTags) > 0 {
+		flags = append(flags, "-tags="+strings.Join(s.buildContext.BuildTags, ","))
+	}
+	return flags
+}
+
+// runFlags returns the flags of the go command to run the code, which are
+// not used for loading the packages to type check it.
+func (s *Session) runFlags() []string {
+	flags := s.buildFlags()
+	if s.run.race {
+		flags = append(flags, "-race")
+	}
+	return flags
+}
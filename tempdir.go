@@ -0,0 +1,93 @@
+package gore
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// The temporary directory of a session is created under the root (the
+// default temporary directory if not given by -tempdir), with the file of
+// the process id to remove the ones left by the processes killed or crashed.
+const (
+	tempDirPattern = "gore-"
+	tempDirPIDFile = "gore.pid"
+)
+
+// makeTempDir creates the temporary directory of a session under root,
+// removing the stale ones first.
+func makeTempDir(root string) (string, error) {
+	if root != "" {
+		if err := os.MkdirAll(root, 0o755); err != nil {
+			return "", err
+		}
+	}
+	removeStaleTempDirs(root)
+
+	dir, err := os.MkdirTemp(root, tempDirPattern)
+	if err != nil {
+		return "", err
+	}
+	pid := []byte(strconv.Itoa(os.Getpid()))
+	if err := os.WriteFile(filepath.Join(dir, tempDirPIDFile), pid, 0o644); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// removeStaleTempDirs removes the temporary directories of the processes not
+// running. The ones without the file of the process id are not of gore, or
+// of the older versions, and are left.
+func removeStaleTempDirs(root string) {
+	if root == "" {
+		root = os.TempDir()
+	}
+	dirs, _ := filepath.Glob(filepath.Join(root, tempDirPattern+"*"))
+	for _, dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(dir, tempDirPIDFile))
+		if err != nil {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || pid == os.Getpid() || processAlive(pid) {
+			continue
+		}
+		debugf("removing the stale temporary directory: %s", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			debugf("%s", err)
+		}
+	}
+}
+
+// clearOnSignal calls clear and exits when gore is terminated by the
+// signals, not to leave the temporary directory. It returns the function to
+// stop it.
+func clearOnSignal(clear func(), sigs ...os.Signal) func() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-ch:
+			clear()
+			code := 1
+			if sig, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(sig)
+			}
+			os.Exit(code)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+// terminateSignals are the signals gore is terminated by. The interrupt is
+// not included, which the line editor and the program running take.
+var terminateSignals = []os.Signal{syscall.SIGTERM, syscall.SIGHUP}
@@ -0,0 +1,42 @@
+package gore
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeTempDir(t *testing.T) {
+	root := t.TempDir()
+
+	// the process exited to leave the stale directory
+	cmd := exec.Command("go", "version")
+	require.NoError(t, cmd.Run())
+	dirs := map[string]string{
+		"gore-stale":   strconv.Itoa(cmd.Process.Pid),
+		"gore-running": strconv.Itoa(os.Getpid()),
+		"gore-other":   "",
+	}
+	for name, pid := range dirs {
+		require.NoError(t, os.Mkdir(filepath.Join(root, name), 0o755))
+		if pid != "" {
+			require.NoError(t, os.WriteFile(filepath.Join(root, name, tempDirPIDFile), []byte(pid), 0o644))
+		}
+	}
+
+	dir, err := makeTempDir(root)
+	require.NoError(t, err)
+	assert.Equal(t, root, filepath.Dir(dir))
+	pid, err := os.ReadFile(filepath.Join(dir, tempDirPIDFile))
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), string(pid))
+
+	assert.NoDirExists(t, filepath.Join(root, "gore-stale"))
+	assert.DirExists(t, filepath.Join(root, "gore-running"))
+	assert.DirExists(t, filepath.Join(root, "gore-other"))
+}
@@ -5,6 +5,9 @@ package gore
 
 import (
 	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
 )
 
 func cursorUp() {
@@ -14,3 +17,9 @@ func cursorUp() {
 func eraseInLine() {
 	fmt.Print("\x1b[0K")
 }
+
+func terminalSize(f *os.File) (width, height int, ok bool) {
+	var ws struct{ row, col, xpixel, ypixel uint16 }
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	return int(ws.col), int(ws.row), errno == 0 && ws.row > 0
+}
@@ -1,6 +1,7 @@
 package gore
 
 import (
+	"os"
 	"syscall"
 	"unsafe"
 )
@@ -67,3 +68,12 @@ func eraseInLine() {
 	var w uint32
 	procFillConsoleOutputCharacter.Call(stdoutHandle, uintptr(' '), uintptr(csbi.size.x), uintptr(*(*int32)(unsafe.Pointer(&csbi.cursorPosition))), uintptr(unsafe.Pointer(&w)))
 }
+
+func terminalSize(f *os.File) (width, height int, ok bool) {
+	var csbi consoleScreenBufferInfo
+	r, _, _ := procGetConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&csbi)))
+	if r == 0 {
+		return 0, 0, false
+	}
+	return int(csbi.window.right - csbi.window.left + 1), int(csbi.window.bottom - csbi.window.top + 1), true
+}
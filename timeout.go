@@ -0,0 +1,63 @@
+package gore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The program is stopped after the timeout by an extra file, which starts
+// the timer on initialization, so that the time to build is not counted.
+// The input is dropped as calling os.Exit, not to time out again.
+const timeoutFileName = "gore_timeout.go"
+
+const timeoutSourceTemplate = `package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+func init() {
+	time.AfterFunc(%d, func() {
+		fmt.Fprintln(os.Stderr, %q)
+		os.Exit(124)
+	})
+}
+`
+
+// setTimeout sets the timeout of each run, or removes it by "" or 0.
+func setTimeout(s *Session, value string) error {
+	var d time.Duration
+	if value != "" && value != "0" {
+		var err error
+		if d, err = time.ParseDuration(value); err != nil || d <= 0 {
+			return s.errorf("invalid duration: %q", value)
+		}
+	}
+	path := filepath.Join(s.tempDir, timeoutFileName)
+	if d == 0 {
+		s.run.timeout = 0
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	msg := fmt.Sprintf(s.tr("timed out after %s"), d)
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(timeoutSourceTemplate, int64(d), msg)), 0o644); err != nil {
+		return err
+	}
+	s.run.timeout = d
+	return nil
+}
+
+// formatTimeout returns the timeout of each run, or "" if not set.
+func formatTimeout(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.String()
+}
@@ -0,0 +1,244 @@
+package gore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// transcript records inputs and outputs of a session to a file.
+// Inputs are written with the prompts so that the file can be fed back
+// to a session by :replay; other lines are regarded as outputs.
+//
+// In the JSONL format, each input is written as a record of a line with the
+// outputs, which can also be fed back to a session by :replay.
+type transcript struct {
+	file           *os.File
+	stdout, stderr io.Writer // the writers before the recording started
+	jsonl          bool
+	record         *transcriptRecord // the record of the input being evaluated
+	out, err       bytes.Buffer      // the outputs of the input
+}
+
+// Log formats of the transcript.
+const (
+	logFormatText  = "text"
+	logFormatJSONL = "jsonl"
+)
+
+// transcriptRecord is a record of an input in the JSONL transcript.
+type transcriptRecord struct {
+	Time       time.Time     `json:"time"`
+	Input      string        `json:"input"`
+	Kind       string        `json:"kind"` // command, expr, stmt, func or invalid
+	QuickFixes []string      `json:"quickfixes,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	RunTime    time.Duration `json:"runTime,omitempty"` // the time to build and run
+	Stdout     string        `json:"stdout"`
+	Stderr     string        `json:"stderr"`
+	Failed     bool          `json:"failed,omitempty"`
+}
+
+func (s *Session) startRecording(filename, format string) error {
+	if s.transcript != nil {
+		if err := s.stopRecording(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	t := &transcript{file: f, stdout: s.stdout, stderr: s.stderr, jsonl: format == logFormatJSONL}
+	s.transcript = t
+	if t.jsonl {
+		s.stdout = io.MultiWriter(s.stdout, &t.out)
+		s.stderr = io.MultiWriter(s.stderr, &t.err)
+	} else {
+		s.stdout = io.MultiWriter(s.stdout, f)
+		s.stderr = io.MultiWriter(s.stderr, f)
+	}
+	return nil
+}
+
+func (s *Session) stopRecording() error {
+	if s.transcript == nil {
+		return fmt.Errorf("not recording")
+	}
+
+	s.stdout, s.stderr = s.transcript.stdout, s.transcript.stderr
+	err := s.transcript.file.Close()
+	s.transcript = nil
+	return err
+}
+
+// recordInput writes the input to the transcript if recording,
+// or starts the record of the input in the JSONL format.
+func (s *Session) recordInput(in, kind string) {
+	if s.transcript == nil {
+		return
+	}
+
+	if s.transcript.jsonl {
+		s.transcript.out.Reset()
+		s.transcript.err.Reset()
+		s.transcript.record = &transcriptRecord{Input: in, Kind: kind}
+		return
+	}
+
+	for i, line := range strings.Split(in, "\n") {
+		prompt := promptDefault
+		if i > 0 {
+			prompt = promptContinue
+		}
+		fmt.Fprintln(s.transcript.file, prompt+line)
+	}
+}
+
+// recordQuickFixes adds the changes of the source by the quickfix to the
+// record of the input, as the lines removed (-) and added (+).
+func (s *Session) recordQuickFixes(before, after string) {
+	if s.transcript == nil || s.transcript.record == nil {
+		return
+	}
+	removed := map[string]int{}
+	for _, line := range strings.Split(before, "\n") {
+		removed[strings.TrimSpace(line)]++
+	}
+	var added []string
+	for _, line := range strings.Split(after, "\n") {
+		line = strings.TrimSpace(line)
+		if removed[line] > 0 {
+			removed[line]--
+		} else {
+			added = append(added, "+ "+line)
+		}
+	}
+	var fixes []string
+	for _, line := range strings.Split(before, "\n") {
+		line = strings.TrimSpace(line)
+		if removed[line] > 0 {
+			removed[line]--
+			fixes = append(fixes, "- "+line)
+		}
+	}
+	s.transcript.record.QuickFixes = append(s.transcript.record.QuickFixes, append(fixes, added...)...)
+}
+
+// recordResult writes the record of the input to the JSONL transcript.
+func (s *Session) recordResult(start time.Time, err error) {
+	if s.transcript == nil || s.transcript.record == nil {
+		return
+	}
+	t := s.transcript
+	r := t.record
+	t.record = nil
+	r.Time, r.Duration = start, time.Since(start)
+	r.Stdout, r.Stderr = t.out.String(), t.err.String()
+	r.Failed = err != nil && err != ErrQuit
+	data, jerr := json.Marshal(r)
+	if jerr != nil {
+		debugf("transcript: %s", jerr)
+		return
+	}
+	if _, err := t.file.Write(append(data, '\n')); err != nil {
+		debugf("transcript: %s", err)
+	}
+}
+
+// readTranscript returns the inputs recorded in the transcript,
+// in the plain format or the JSONL format.
+func readTranscript(r io.Reader) ([]string, error) {
+	var inputs []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "{") {
+			var r transcriptRecord
+			if err := json.Unmarshal([]byte(line), &r); err == nil {
+				inputs = append(inputs, r.Input)
+				continue
+			}
+		}
+		if strings.HasPrefix(line, promptDefault) {
+			inputs = append(inputs, strings.TrimPrefix(line, promptDefault))
+		} else if strings.HasPrefix(line, promptContinue) && len(inputs) > 0 {
+			inputs[len(inputs)-1] += "\n" + strings.TrimPrefix(line, promptContinue)
+		}
+	}
+	return inputs, sc.Err()
+}
+
+func actionRecord(s *Session, arg string) error {
+	if arg == "" {
+		return s.stopRecording()
+	}
+
+	format, filename := logFormatText, arg
+	if fields := strings.Fields(arg); fields[0] == "--log-format" || fields[0] == "-log-format" {
+		if len(fields) < 2 {
+			return s.errorf("log format is required")
+		}
+		format = fields[1]
+		filename = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(arg), fields[0]))
+		filename = strings.TrimSpace(strings.TrimPrefix(filename, format))
+	}
+	if format != logFormatText && format != logFormatJSONL {
+		return s.errorf("unknown log format: %s", format)
+	}
+	if filename == "" {
+		return s.errorf("argument is required")
+	}
+
+	filename = expandHome(filename)
+	if err := s.startRecording(filename, format); err != nil {
+		return err
+	}
+
+	infof("Recording to %s", filename)
+	return nil
+}
+
+func actionReplay(s *Session, filename string) error {
+	if filename == "" {
+		return fmt.Errorf("argument is required")
+	}
+
+	f, err := os.Open(expandHome(filename))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	inputs, err := readTranscript(f)
+	if err != nil {
+		return err
+	}
+
+	for _, in := range inputs {
+		// do not let a transcript control recording or replay itself
+		if isCommand(in, "record") || isCommand(in, "replay") {
+			continue
+		}
+
+		// echo the input, which is recorded separately when recording
+		out := s.stdout
+		if s.transcript != nil {
+			out = s.transcript.stdout
+		}
+		fmt.Fprintln(out, promptDefault+strings.ReplaceAll(in, "\n", "\n"+promptContinue))
+		if err := s.eval(in); err == ErrQuit {
+			return nil
+		}
+	}
+
+	return nil
+}
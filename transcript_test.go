@@ -0,0 +1,128 @@
+package gore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_RecordReplay(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "transcript.txt")
+	codes := []string{
+		":record " + file,
+		"x := 10",
+		"func f(n int) int {\n\treturn n * 2\n}",
+		"f(x)",
+		"y",
+		":record",
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, `:= x := 10
+10
+:= func f(n int) int {
+.. 	return n * 2
+.. }
+:= f(x)
+20
+:= y
+undefined: y
+:= :record
+`, string(content))
+
+	_, err = s.Eval(":clear")
+	require.NoError(t, err)
+	stdout.Reset()
+	stderr.Reset()
+
+	_, err = s.Eval(":replay " + file)
+	require.NoError(t, err)
+
+	assert.Equal(t, `:= x := 10
+10
+:= func f(n int) int {
+.. 	return n * 2
+.. }
+:= f(x)
+20
+:= y
+`, stdout.String())
+	assert.Equal(t, "undefined: y\n", stderr.String())
+
+	_, err = s.Eval(":record")
+	require.Error(t, err)
+	_, err = s.Eval(":replay")
+	require.Error(t, err)
+}
+
+func TestAction_Record_JSONL(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "transcript.jsonl")
+	codes := []string{
+		":record --log-format jsonl " + file,
+		"x := 10",
+		":import os",
+		"func f(n int) int { return n * 2 }",
+		"f(x)",
+		"y",
+		":record",
+	}
+
+	for _, code := range codes {
+		_, _ = s.Eval(code)
+	}
+
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+	var records []transcriptRecord
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var r transcriptRecord
+		require.NoError(t, dec.Decode(&r))
+		records = append(records, r)
+	}
+
+	require.Len(t, records, 5)
+	assert.Equal(t, []string{"x := 10", ":import os", "func f(n int) int { return n * 2 }", "f(x)", "y"},
+		[]string{records[0].Input, records[1].Input, records[2].Input, records[3].Input, records[4].Input})
+	assert.Equal(t, []string{"stmt", "command", "func", "expr", "expr"},
+		[]string{records[0].Kind, records[1].Kind, records[2].Kind, records[3].Kind, records[4].Kind})
+	assert.Equal(t, "10\n", records[0].Stdout)
+	assert.Contains(t, records[2].QuickFixes, `+ _ "os"`)
+	assert.Equal(t, "20\n", records[3].Stdout)
+	assert.NotZero(t, records[3].RunTime)
+	assert.True(t, records[4].Failed)
+	assert.Equal(t, "undefined: y\n", records[4].Stderr)
+
+	_, err = s.Eval(":clear")
+	require.NoError(t, err)
+	stdout.Reset()
+	stderr.Reset()
+	_, err = s.Eval(":replay " + file)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "20\n")
+	assert.Equal(t, "undefined: y\n", stderr.String())
+
+	_, err = s.Eval(":record --log-format yaml " + file)
+	assert.Error(t, err)
+}
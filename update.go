@@ -0,0 +1,329 @@
+package gore
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// releaseURL is the API to get the latest release of gore.
+var releaseURL = "https://api.github.com/repos/x-motemen/gore/releases/latest"
+
+// The latest release is checked at most once in updateCheckInterval,
+// and the result is saved to the store to notify on the next sessions.
+const (
+	storeUpdate         = "update.json"
+	updateCheckInterval = 24 * time.Hour
+	updateCheckTimeout  = 10 * time.Second
+	maxReleaseAssetSize = 100 << 20
+	releaseChecksums    = "SHA256SUMS"
+)
+
+// Release is a release of gore.
+type Release struct {
+	Version string
+	assets  map[string]string // name to the download URL
+}
+
+// LatestRelease returns the latest release of gore.
+func LatestRelease(ctx context.Context) (*Release, error) {
+	resp, err := httpGet(ctx, releaseURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("invalid release: %s", err)
+	}
+	rel := &Release{Version: strings.TrimPrefix(r.TagName, "v"), assets: map[string]string{}}
+	for _, a := range r.Assets {
+		rel.assets[a.Name] = a.URL
+	}
+	return rel, nil
+}
+
+func httpGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "gore/"+Version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// IsNewer reports whether the release is newer than the running gore.
+func (r *Release) IsNewer() bool {
+	return newerVersion(r.Version, Version)
+}
+
+// newerVersion reports whether the version x.y.z is newer than the other.
+func newerVersion(version, than string) bool {
+	vs, ts := strings.Split(version, "."), strings.Split(than, ".")
+	for i := 0; i < len(vs) || i < len(ts); i++ {
+		var v, t int
+		if i < len(vs) {
+			v, _ = strconv.Atoi(vs[i])
+		}
+		if i < len(ts) {
+			t, _ = strconv.Atoi(ts[i])
+		}
+		if v != t {
+			return v > t
+		}
+	}
+	return false
+}
+
+// Install downloads the binary of the release for the platform, verifies
+// the checksum, and replaces the executable file of the path with it.
+func (r *Release) Install(ctx context.Context, exe string) error {
+	name, url := r.archive()
+	if url == "" {
+		return fmt.Errorf("no binary for %s/%s in gore %s", runtime.GOOS, runtime.GOARCH, r.Version)
+	}
+	sum, err := r.checksum(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	data, err := download(ctx, url)
+	if err != nil {
+		return err
+	}
+	if actual := sha256.Sum256(data); hex.EncodeToString(actual[:]) != sum {
+		return fmt.Errorf("checksum mismatch of %s", name)
+	}
+	bin, err := extractBinary(name, data)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0o755)
+	if fi, err := os.Stat(exe); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp := exe + ".new"
+	if err := os.WriteFile(tmp, bin, mode); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		// the running executable cannot be replaced but can be renamed
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// archive returns the name and the URL of the archive for the platform,
+// which are built by goxz (see Makefile).
+func (r *Release) archive() (string, string) {
+	prefix := fmt.Sprintf("gore_v%s_%s_%s.", r.Version, runtime.GOOS, runtime.GOARCH)
+	for _, ext := range []string{"tar.gz", "zip"} {
+		if url, ok := r.assets[prefix+ext]; ok {
+			return prefix + ext, url
+		}
+	}
+	return "", ""
+}
+
+// checksum returns the SHA-256 checksum of the asset in the release.
+func (r *Release) checksum(ctx context.Context, name string) (string, error) {
+	url, ok := r.assets[releaseChecksums]
+	if !ok {
+		return "", fmt.Errorf("no checksums in gore %s", r.Version)
+	}
+	data, err := download(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum of %s", name)
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxReleaseAssetSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxReleaseAssetSize {
+		return nil, fmt.Errorf("too large: %s", url)
+	}
+	return data, nil
+}
+
+// extractBinary extracts the gore binary from the archive.
+func extractBinary(name string, data []byte) ([]byte, error) {
+	bin := "gore"
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	if strings.HasSuffix(name, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if path.Base(f.Name) == bin && !f.FileInfo().IsDir() {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+	} else {
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gr)
+		for {
+			h, err := tr.Next()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, err
+			}
+			if path.Base(h.Name) == bin && h.Typeflag == tar.TypeReg {
+				return io.ReadAll(tr)
+			}
+		}
+	}
+	return nil, fmt.Errorf("no %s in %s", bin, name)
+}
+
+// updateState is the result of the last check of the latest release.
+type updateState struct {
+	Checked time.Time `json:"checked"`
+	Latest  string    `json:"latest"`
+}
+
+// checkUpdate notifies the newer release found by the last check, and
+// checks the latest release in background if the last check is old.
+// The returned channel is closed when the check is done.
+func checkUpdate(st Store, w io.Writer, now time.Time) <-chan struct{} {
+	done := make(chan struct{})
+	var state updateState
+	if data, err := st.Load(storeUpdate); err == nil {
+		if err := json.Unmarshal(data, &state); err != nil {
+			debugf("update: %s", err)
+		}
+	} else if !isNotExist(err) {
+		debugf("update: %s", err)
+	}
+
+	if newerVersion(state.Latest, Version) {
+		fmt.Fprintf(w, "gore %s is available (run gore self-update to upgrade)\n", state.Latest)
+	}
+	if now.Sub(state.Checked) < updateCheckInterval {
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+		defer cancel()
+		rel, err := LatestRelease(ctx)
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				debugf("update: %s", err)
+			}
+			return
+		}
+		data, _ := json.Marshal(updateState{Checked: now, Latest: rel.Version})
+		if err := st.Save(storeUpdate, data); err != nil {
+			debugf("update: %s", err)
+		}
+	}()
+	return done
+}
+
+// executablePath returns the path of the running gore.
+func executablePath() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// SelfUpdate replaces the running gore with the latest release,
+// and writes the progress to w. Only checks the release if check is true.
+func SelfUpdate(ctx context.Context, w io.Writer, check bool) error {
+	rel, err := LatestRelease(ctx)
+	if err != nil {
+		return err
+	}
+	if !rel.IsNewer() {
+		fmt.Fprintf(w, "gore %s is up to date\n", Version)
+		return nil
+	}
+	if check {
+		fmt.Fprintf(w, "gore %s is available (current: %s)\n", rel.Version, Version)
+		return nil
+	}
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+	if err := rel.Install(ctx, exe); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "updated gore %s to %s (%s)\n", Version, rel.Version, exe)
+	return nil
+}
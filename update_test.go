@@ -0,0 +1,141 @@
+package gore
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewerVersion(t *testing.T) {
+	testCases := []struct {
+		version, than string
+		newer         bool
+	}{
+		{"0.5.8", "0.5.7", true},
+		{"0.10.0", "0.9.9", true},
+		{"1.0", "0.5.7", true},
+		{"0.5.7", "0.5.7", false},
+		{"0.5.6", "0.5.7", false},
+		{"", "0.5.7", false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.newer, newerVersion(tc.version, tc.than), tc.version+" > "+tc.than)
+	}
+}
+
+// serveRelease serves the latest release with the binary of the content,
+// and the checksum of the archive unless it is broken.
+func serveRelease(t *testing.T, version, content string, brokenSum bool) {
+	bin := "gore"
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	dir := fmt.Sprintf("gore_v%s_%s_%s", version, runtime.GOOS, runtime.GOARCH)
+	var archive bytes.Buffer
+	gw := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: dir + "/README.md", Mode: 0o644, Size: 1}))
+	_, _ = tw.Write([]byte("#"))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: dir + "/" + bin, Mode: 0o755, Size: int64(len(content))}))
+	_, _ = tw.Write([]byte(content))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	sum := sha256.Sum256(archive.Bytes())
+	if brokenSum {
+		sum[0]++
+	}
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"tag_name": "v" + version,
+				"assets": []map[string]string{
+					{"name": dir + ".tar.gz", "browser_download_url": ts.URL + "/archive"},
+					{"name": releaseChecksums, "browser_download_url": ts.URL + "/sums"},
+				},
+			})
+		case "/archive":
+			_, _ = w.Write(archive.Bytes())
+		case "/sums":
+			fmt.Fprintf(w, "%x  %s.tar.gz\n", sum, dir)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	orig := releaseURL
+	releaseURL = ts.URL + "/latest"
+	t.Cleanup(func() { releaseURL = orig })
+}
+
+func TestRelease_Install(t *testing.T) {
+	serveRelease(t, "9.0.0", "new gore", false)
+	exe := filepath.Join(t.TempDir(), "gore")
+	require.NoError(t, os.WriteFile(exe, []byte("old gore"), 0o755))
+
+	rel, err := LatestRelease(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "9.0.0", rel.Version)
+	assert.True(t, rel.IsNewer())
+
+	require.NoError(t, rel.Install(context.Background(), exe))
+	data, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	assert.Equal(t, "new gore", string(data))
+}
+
+func TestRelease_Install_ChecksumMismatch(t *testing.T) {
+	serveRelease(t, "9.0.0", "new gore", true)
+	exe := filepath.Join(t.TempDir(), "gore")
+	require.NoError(t, os.WriteFile(exe, []byte("old gore"), 0o755))
+
+	rel, err := LatestRelease(context.Background())
+	require.NoError(t, err)
+
+	err = rel.Install(context.Background(), exe)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+	data, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	assert.Equal(t, "old gore", string(data))
+}
+
+func TestCheckUpdate(t *testing.T) {
+	serveRelease(t, "9.0.0", "new gore", false)
+	st, err := NewStore("memory")
+	require.NoError(t, err)
+	now := time.Now()
+
+	var out strings.Builder
+	<-checkUpdate(st, &out, now)
+	assert.Equal(t, "", out.String())
+
+	<-checkUpdate(st, &out, now.Add(time.Hour))
+	assert.Equal(t, "gore 9.0.0 is available (run gore self-update to upgrade)\n", out.String())
+
+	// the release is not checked until the interval passes
+	releaseURL = "http://127.0.0.1:0/"
+	require.NoError(t, st.Save(storeUpdate, []byte(`{"checked":"`+now.Format(time.RFC3339)+`","latest":"`+Version+`"}`)))
+	out.Reset()
+	<-checkUpdate(st, &out, now.Add(time.Hour))
+	assert.Equal(t, "", out.String())
+}
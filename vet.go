@@ -0,0 +1,135 @@
+package gore
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// vetLinePattern matches the finding of go vet, which is followed by the
+// position in the file.
+var vetLinePattern = regexp.MustCompile(`^(.+\.go):(\d+):(?:(\d+):)? (.*)$`)
+
+// actionVet runs go vet for the session, and reports the findings at the
+// statements of main by the numbers (as :drop takes), or at the declarations
+// by the names. The findings in the code written by gore are dropped.
+func actionVet(s *Session, _ string) error {
+	if err := s.writeSource(); err != nil {
+		return err
+	}
+	args := append(append([]string{"vet"}, s.buildFlags()...), s.runFiles()...)
+	debugf("go %s", strings.Join(args, " "))
+	cmd := exec.Command("go", args...)
+	cmd.Dir = s.tempDir
+	cmd.Env = s.goEnviron()
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return err
+	}
+
+	var found bool
+	lines := s.vetLines()
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		m := vetLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			if !strings.HasPrefix(line, "# ") && err != nil {
+				fmt.Fprintln(s.stderr, line)
+			}
+			continue
+		}
+		found = true
+		path, msg := m[1], m[4]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.tempDir, path)
+		}
+		n, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		switch {
+		case path == s.tempFilePath:
+			if at := lines(n, col); at != "" {
+				fmt.Fprintf(s.stdout, "    %s: %s\n", at, msg)
+			}
+		case filepath.Dir(path) == s.tempDir && strings.HasPrefix(filepath.Base(path), "gore_"):
+		default:
+			fmt.Fprintf(s.stdout, "    %s:%d: %s\n", filepath.Base(path), n, msg)
+		}
+	}
+	if err != nil && !found {
+		return err
+	}
+	return nil
+}
+
+// vetLines returns the function to find the statement of main or the
+// declaration at the position of the source written to the session file, or
+// the empty string if it is written by gore. The column is required, since
+// main may be written in a line.
+func (s *Session) vetLines() func(line, col int) string {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, s.tempFilePath, nil, 0)
+	if err != nil {
+		return func(int, int) string { return "" }
+	}
+	file := fset.File(f.Pos())
+	return func(line, col int) string {
+		if line < 1 || line > file.LineCount() || col < 1 {
+			return ""
+		}
+		pos := file.LineStart(line) + token.Pos(col-1)
+		contains := func(n ast.Node) bool {
+			return n.Pos() <= pos && pos < n.End()
+		}
+		for _, decl := range f.Decls {
+			if !contains(decl) {
+				continue
+			}
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil && decl.Name.Name == "main" {
+					// the statements appended by gore follow the ones of main
+					for i, stmt := range decl.Body.List {
+						if i < len(s.mainBody.List) && contains(stmt) {
+							return "#" + strconv.Itoa(i+1)
+						}
+					}
+					return ""
+				}
+				if isGoreName(decl.Name.Name) {
+					return ""
+				}
+				if decl.Recv != nil && len(decl.Recv.List) > 0 {
+					return showNode(fset, decl.Recv.List[0].Type) + "." + decl.Name.Name
+				}
+				return decl.Name.Name
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if !contains(spec) {
+						continue
+					}
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						return spec.Name.Name
+					case *ast.ValueSpec:
+						if !isGoreName(spec.Names[0].Name) {
+							return spec.Names[0].Name
+						}
+					}
+				}
+			}
+			return ""
+		}
+		return ""
+	}
+}
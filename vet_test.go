@@ -0,0 +1,37 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Vet(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`:import fmt sync`,
+		`x := 1`,
+		`fmt.Printf("%s\n", x)`,
+		`type T struct{ mu sync.Mutex }`,
+		`func (t T) Lock() { t.mu.Lock() }`,
+		`fmt.Println(x)`,
+	}
+	for _, code := range codes {
+		_, err := s.Eval(code)
+		require.NoError(t, err, code)
+	}
+	stdout.Reset()
+
+	_, err = s.Eval(`:vet`)
+	require.NoError(t, err)
+	assert.Equal(t, `    T.Lock: Lock passes lock by value: command-line-arguments.T contains sync.Mutex
+    #2: fmt.Printf format %s has arg x of wrong type int
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
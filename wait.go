@@ -0,0 +1,71 @@
+package gore
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The goroutines started by the inputs are waited for at the end of main up
+// to the duration, as the program exits on returning from main, by the
+// function declared in an extra file.
+const (
+	waitFileName = "gore_wait.go"
+	waitFuncName = "__gore_wait"
+)
+
+const waitSourceTemplate = `package main
+
+import (
+	"runtime"
+	"time"
+)
+
+func ` + waitFuncName + `() {
+	deadline := time.Now().Add(%d)
+	for runtime.NumGoroutine() > 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+}
+`
+
+// setWait sets the duration to wait for the goroutines, or removes it by ""
+// or 0.
+func setWait(s *Session, value string) error {
+	var d time.Duration
+	if value != "" && value != "0" {
+		var err error
+		if d, err = time.ParseDuration(value); err != nil || d <= 0 {
+			return s.errorf("invalid duration: %q", value)
+		}
+	}
+	path := filepath.Join(s.tempDir, waitFileName)
+	if d == 0 {
+		s.run.wait = 0
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(waitSourceTemplate, int64(d))), 0o644); err != nil {
+		return err
+	}
+	s.run.wait = d
+	return nil
+}
+
+// appendWait calls the function waiting for the goroutines at the end of
+// main, returning the function to restore the code.
+func (s *Session) appendWait() func() {
+	if s.run.wait <= 0 {
+		return func() {}
+	}
+	stmts := s.mainBody.List
+	s.mainBody.List = append(stmts[:len(stmts):len(stmts)], &ast.ExprStmt{
+		X: &ast.CallExpr{Fun: ast.NewIdent(waitFuncName)},
+	})
+	return func() { s.mainBody.List = stmts }
+}
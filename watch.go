@@ -0,0 +1,126 @@
+package gore
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// The local packages imported (of the main module of -context, or of the
+// modules replaced by the local directories) are watched by :set watch on.
+// The files of the packages are checked before the prompt (e.g. on Enter),
+// and the program runs again if any of them changed, as the inputs after
+// the change would run it.
+
+// watchState is the state of watching the local packages imported.
+type watchState struct {
+	enabled bool
+	imports string            // the import paths the directories are listed for
+	dirs    map[string]string // the directories of the local packages by the paths
+	stamps  map[string]string // the stamps of the files of the packages by the paths
+}
+
+func setWatch(s *Session, value string) error {
+	on, err := s.parseBool(value)
+	if err != nil {
+		return err
+	}
+	s.watch = watchState{enabled: on}
+	if on {
+		s.watch.stamps = s.watchStamps()
+	}
+	return nil
+}
+
+// checkWatch runs the program again if any of the local packages imported
+// changed since the last check.
+func (s *Session) checkWatch() {
+	if !s.watch.enabled {
+		return
+	}
+	stamps := s.watchStamps()
+	var changed []string
+	for path, stamp := range stamps {
+		if prev, ok := s.watch.stamps[path]; ok && prev != stamp {
+			changed = append(changed, path)
+		}
+	}
+	s.watch.stamps = stamps
+	if len(changed) == 0 || len(s.mainBody.List) == 0 {
+		return
+	}
+	sort.Strings(changed)
+	fmt.Fprintf(s.stderr, s.tr("changed: %s, running again")+"\n", strings.Join(changed, ", "))
+	if err := s.Run(); err != nil {
+		debugf("watch :: err = %s", err)
+	}
+}
+
+// watchStamps returns the stamps of the files of the local packages
+// imported, which differ if any file is changed, added or removed.
+func (s *Session) watchStamps() map[string]string {
+	stamps := map[string]string{}
+	for path, dir := range s.watchDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		var b strings.Builder
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+				continue
+			}
+			fi, err := e.Info()
+			if err != nil {
+				continue
+			}
+			b.WriteString(e.Name() + ":" + strconv.FormatInt(fi.ModTime().UnixNano(), 10) + ":" + strconv.FormatInt(fi.Size(), 10) + "\n")
+		}
+		stamps[path] = b.String()
+	}
+	return stamps
+}
+
+// watchDirs returns the directories of the local packages imported, loaded
+// again only if the imports are changed.
+func (s *Session) watchDirs() map[string]string {
+	var paths []string
+	for _, f := range append([]*ast.File{s.file}, s.extraFiles...) {
+		for _, spec := range f.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil && path != "C" {
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	if imports := strings.Join(paths, " "); imports != s.watch.imports || s.watch.dirs == nil {
+		s.watch.imports, s.watch.dirs = imports, map[string]string{}
+		if len(paths) == 0 {
+			return s.watch.dirs
+		}
+		pkgs, err := packages.Load(&packages.Config{
+			Mode:       packages.NeedName | packages.NeedFiles | packages.NeedModule,
+			Dir:        s.tempDir,
+			Env:        s.loadEnviron(),
+			BuildFlags: s.buildFlags(),
+		}, paths...)
+		if err != nil {
+			debugf("watch :: err = %s", err)
+			return s.watch.dirs
+		}
+		for _, pkg := range pkgs {
+			m := pkg.Module
+			if m == nil || len(pkg.GoFiles) == 0 || !m.Main && (m.Replace == nil || m.Replace.Version != "") {
+				continue
+			}
+			s.watch.dirs[pkg.PkgPath] = filepath.Dir(pkg.GoFiles[0])
+		}
+	}
+	return s.watch.dirs
+}
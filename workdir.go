@@ -0,0 +1,83 @@
+package gore
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+)
+
+// currentWorkDir returns the working directory of the program.
+func (s *Session) currentWorkDir() string {
+	if s.workDir != "" {
+		return s.workDir
+	}
+	return s.tempDir
+}
+
+// setWorkDir changes the working directory of the program,
+// or resets it to the temporary directory if dir is empty.
+func (s *Session) setWorkDir(dir string) error {
+	if dir == "" {
+		s.workDir = ""
+		return nil
+	}
+
+	dir = expandHome(dir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.currentWorkDir(), dir)
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return s.errorf("not a directory: %s", dir)
+	}
+
+	s.workDir = dir
+	return nil
+}
+
+// runFiles returns the files to build the program of. The build constraints
+// of the files are checked here, as go build ignores them of the files given.
+func (s *Session) runFiles() []string {
+	ctxt := s.buildContext
+	ctxt.GOOS, ctxt.GOARCH = build.Default.GOOS, build.Default.GOARCH
+	var files []string
+	for _, path := range s.extraFilePaths {
+		if ok, err := ctxt.MatchFile(filepath.Split(path)); ok || err != nil {
+			files = append(files, path)
+		}
+	}
+	files = append(files, s.tempFilePath)
+	if s.run.timeout > 0 {
+		files = append(files, filepath.Join(s.tempDir, timeoutFileName))
+	}
+	if s.run.wait > 0 {
+		files = append(files, filepath.Join(s.tempDir, waitFileName))
+	}
+	if s.exit.file {
+		files = append(files, filepath.Join(s.tempDir, exitFileName))
+	}
+	if s.once.file {
+		files = append(files, filepath.Join(s.tempDir, onceFileName))
+	}
+	return files
+}
+
+func actionCd(s *Session, arg string) error {
+	if err := s.setWorkDir(arg); err != nil {
+		return err
+	}
+	return actionPwd(s, "")
+}
+
+func actionPwd(s *Session, _ string) error {
+	fmt.Fprintln(s.stdout, s.currentWorkDir())
+	return nil
+}
+
+func completeCd(s *Session, prefix string) []string {
+	return completePath(prefix, s.currentWorkDir(), true)
+}
@@ -0,0 +1,249 @@
+// Package zmtp implements a minimal subset of ZMTP 3.0, the ZeroMQ message
+// transport protocol, with the NULL security mechanism. It provides just
+// enough to talk to ZeroMQ peers over TCP without depending on libzmq.
+// Socket semantics (routing, subscriptions) are left to the users.
+package zmtp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+const (
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+)
+
+// maxFrameSize is the size of the largest frame to read, not to allocate
+// the size the peer claims.
+const maxFrameSize = 64 << 20
+
+// Conn is a ZMTP connection with the handshake completed.
+type Conn struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	mu       sync.Mutex // guards writes
+	PeerType string     // the socket type of the peer (e.g. "DEALER")
+}
+
+// Listener accepts ZMTP connections.
+type Listener struct {
+	net.Listener
+	socketType string
+}
+
+// Listen announces on the address and accepts the connections
+// as a socket of the socket type (e.g. "ROUTER").
+func Listen(network, address, socketType string) (*Listener, error) {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{Listener: l, socketType: socketType}, nil
+}
+
+// Accept waits for the next connection and performs the handshake.
+func (l *Listener) Accept() (*Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := handshake(c, l.socketType, true)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Dial connects to the address as a socket of the socket type.
+func Dial(network, address, socketType string) (*Conn, error) {
+	c, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := handshake(c, socketType, false)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func greeting(asServer bool) []byte {
+	g := make([]byte, 64)
+	g[0], g[9] = 0xff, 0x7f
+	g[10], g[11] = 3, 0
+	copy(g[12:32], "NULL")
+	if asServer {
+		g[32] = 1
+	}
+	return g
+}
+
+func handshake(c net.Conn, socketType string, asServer bool) (*Conn, error) {
+	conn := &Conn{conn: c, r: bufio.NewReader(c)}
+
+	if _, err := c.Write(greeting(asServer)); err != nil {
+		return nil, err
+	}
+
+	var peer [64]byte
+	if _, err := io.ReadFull(conn.r, peer[:]); err != nil {
+		return nil, err
+	}
+	if peer[0] != 0xff || peer[9] != 0x7f {
+		return nil, errors.New("zmtp: invalid greeting")
+	}
+	if peer[10] < 3 {
+		return nil, fmt.Errorf("zmtp: unsupported version %d.%d", peer[10], peer[11])
+	}
+	if mechanism := string(bytes.TrimRight(peer[12:32], "\x00")); mechanism != "NULL" {
+		return nil, fmt.Errorf("zmtp: unsupported mechanism %q", mechanism)
+	}
+
+	if err := conn.writeFrame(readyCommand(socketType), flagCommand); err != nil {
+		return nil, err
+	}
+
+	body, flags, err := conn.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	if flags&flagCommand == 0 {
+		return nil, errors.New("zmtp: expected READY command")
+	}
+	name, props, err := parseCommand(body)
+	if err != nil {
+		return nil, err
+	}
+	if name != "READY" {
+		return nil, fmt.Errorf("zmtp: expected READY command, got %s", name)
+	}
+	conn.PeerType = props["Socket-Type"]
+
+	return conn, nil
+}
+
+func readyCommand(socketType string) []byte {
+	var b bytes.Buffer
+	b.WriteByte(5)
+	b.WriteString("READY")
+	b.WriteByte(byte(len("Socket-Type")))
+	b.WriteString("Socket-Type")
+	binary.Write(&b, binary.BigEndian, uint32(len(socketType)))
+	b.WriteString(socketType)
+	return b.Bytes()
+}
+
+func parseCommand(body []byte) (name string, props map[string]string, err error) {
+	if len(body) < 1 || len(body) < 1+int(body[0]) {
+		return "", nil, errors.New("zmtp: malformed command")
+	}
+	name, body = string(body[1:1+body[0]]), body[1+body[0]:]
+	props = map[string]string{}
+	for len(body) > 0 {
+		n := int(body[0])
+		if len(body) < 1+n+4 {
+			return "", nil, errors.New("zmtp: malformed property")
+		}
+		key := string(body[1 : 1+n])
+		body = body[1+n:]
+		m := int(binary.BigEndian.Uint32(body))
+		if len(body) < 4+m {
+			return "", nil, errors.New("zmtp: malformed property")
+		}
+		props[key] = string(body[4 : 4+m])
+		body = body[4+m:]
+	}
+	return name, props, nil
+}
+
+func (c *Conn) readFrame() ([]byte, byte, error) {
+	flags, err := c.r.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+	var size uint64
+	if flags&flagLong != 0 {
+		if err := binary.Read(c.r, binary.BigEndian, &size); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return nil, 0, err
+		}
+		size = uint64(b)
+	}
+	if size > maxFrameSize {
+		return nil, 0, fmt.Errorf("zmtp: frame too large: %d bytes", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, 0, err
+	}
+	return body, flags, nil
+}
+
+func (c *Conn) writeFrame(body []byte, flags byte) error {
+	var header []byte
+	if len(body) > 255 {
+		header = make([]byte, 9)
+		header[0] = flags | flagLong
+		binary.BigEndian.PutUint64(header[1:], uint64(len(body)))
+	} else {
+		header = []byte{flags, byte(len(body))}
+	}
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(body)
+	return err
+}
+
+// ReadMessage reads a multipart message. Commands are skipped.
+func (c *Conn) ReadMessage() ([][]byte, error) {
+	var frames [][]byte
+	for {
+		body, flags, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if flags&flagCommand != 0 {
+			continue
+		}
+		frames = append(frames, body)
+		if flags&flagMore == 0 {
+			return frames, nil
+		}
+	}
+}
+
+// WriteMessage writes a multipart message. It is safe for concurrent use.
+func (c *Conn) WriteMessage(frames [][]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, frame := range frames {
+		var flags byte
+		if i < len(frames)-1 {
+			flags = flagMore
+		}
+		if err := c.writeFrame(frame, flags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,68 @@
+package zmtp
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConn(t *testing.T) {
+	l, err := Listen("tcp", "127.0.0.1:0", "ROUTER")
+	require.NoError(t, err)
+	defer l.Close()
+
+	done := make(chan error)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+		if conn.PeerType != "DEALER" {
+			t.Errorf("unexpected peer type: %s", conn.PeerType)
+		}
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			done <- err
+			return
+		}
+		done <- conn.WriteMessage(append(msg, []byte("pong")))
+	}()
+
+	conn, err := Dial("tcp", l.Addr().String(), "DEALER")
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "ROUTER", conn.PeerType)
+
+	long := []byte(strings.Repeat("x", 1000))
+	require.NoError(t, conn.WriteMessage([][]byte{[]byte("ping"), {}, long}))
+	msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+	assert.Equal(t, [][]byte{[]byte("ping"), {}, long, []byte("pong")}, msg)
+}
+
+func TestParseCommand(t *testing.T) {
+	name, props, err := parseCommand(readyCommand("PUB"))
+	require.NoError(t, err)
+	assert.Equal(t, "READY", name)
+	assert.Equal(t, map[string]string{"Socket-Type": "PUB"}, props)
+
+	_, _, err = parseCommand([]byte{5, 'R', 'E'})
+	assert.Error(t, err)
+
+	_, _, err = parseCommand(append(readyCommand("PUB"), bytes.Repeat([]byte{3}, 3)...))
+	assert.Error(t, err)
+}
+
+func TestReadFrame_tooLarge(t *testing.T) {
+	header := []byte{flagLong, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	c := &Conn{r: bufio.NewReader(bytes.NewReader(header))}
+	_, _, err := c.readFrame()
+	assert.EqualError(t, err, "zmtp: frame too large: 18446744073709551615 bytes")
+}